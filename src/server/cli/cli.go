@@ -10,15 +10,30 @@ import (
 	"github.com/chzyer/readline"
 	"io"
 	"log"
+	"path/filepath"
 	"server/cli/help"
+	"server/codebase"
 	"server/config"
 	footlecmd "server/core/cmd"
+	"server/core/current-state"
+	"server/core/tracker"
 	"server/dbgp/command"
 	"server/dbgp/message"
+	"strings"
 )
 
 const READLINE_PROMPT = "> "
 
+/**
+ * Maximum number of files listed by the "find" command.
+ */
+const findLimit = 15
+
+/**
+ * Identifies commands issued from the command line.
+ */
+const Origin = "cli"
+
 /**
  * Execute the command line interface.
  *
@@ -30,13 +45,17 @@ const READLINE_PROMPT = "> "
  *   - The "bye" command exits the debugger.
  *   - The empty string or "refresh" command lists any DBGp message that has
  *     arrived after the previous command has been issued.
+ *   - The "state" command shows Footle's current state.  This is also shown
+ *     at start so that a newly started CLI does not miss an ongoing break.
+ *   - The "find" command lists files whose path fuzzy matches its argument.
+ *   - The "def" command lists the declarations of a function, class, etc.
  *
- * @param chan<- string out
+ * @param chan<- tracker.Request out
  *   DBGp commands are written to this channel.
  * @param chan struct{} bye
  *   Event channel.  It is closed to broadcast the global exit event.
  */
-func RunUI(out chan<- string, bye chan struct{}) {
+func RunUI(out chan<- tracker.Request, bye chan struct{}) {
 
 	rl, err := readline.New(READLINE_PROMPT)
 	if err != nil {
@@ -45,6 +64,8 @@ func RunUI(out chan<- string, bye chan struct{}) {
 
 	config := config.Get()
 
+	showCurrentState()
+
 	for {
 		cmd, err := rl.Readline()
 		if err != nil && err != io.EOF {
@@ -66,19 +87,28 @@ func RunUI(out chan<- string, bye chan struct{}) {
 			return
 		} else if "refresh" == cmd || "" == cmd {
 			continue
+		} else if "state" == cmd {
+			showCurrentState()
+			continue
 		} else if cmd == "verbose" {
 			config.GoVerbose()
 			continue
 		} else if cmd == "no-verbose" {
 			config.GoSilent()
 			continue
+		} else if cmdAlias == "find" {
+			showMatches(strings.Join(cmdArgs, " "))
+			continue
+		} else if cmdAlias == "def" {
+			showDefinitions(strings.Join(cmdArgs, ""))
+			continue
 		} else if cmdAlias == "help" {
 			helpObj := help.Get()
 			fmt.Print(helpObj.Me(cmdArgs))
 			continue
 		} else if footlecmd.Is(cmdAlias) {
 			// Commands for controlling Footle.
-			out <- cmd
+			out <- tracker.New(cmd, Origin)
 			continue
 		}
 
@@ -88,7 +118,53 @@ func RunUI(out chan<- string, bye chan struct{}) {
 			continue
 		}
 
-		out <- cmd
+		out <- tracker.New(cmd, Origin)
+	}
+}
+
+/**
+ * List files from the codebase whose path fuzzy matches the given text.
+ *
+ * @see codebase.Index.Find()
+ */
+func showMatches(query string) {
+
+	if strings.TrimSpace(query) == "" {
+		fmt.Println("Usage: find TEXT")
+		return
+	}
+
+	matches := codebase.GetIndex().Find(query, findLimit)
+	if len(matches) == 0 {
+		fmt.Println("No matching file.")
+		return
+	}
+
+	for _, match := range matches {
+		fmt.Println(match.Path)
+	}
+}
+
+/**
+ * List the declarations of a PHP function, method, class, constant, etc.
+ *
+ * @see codebase.SymbolIndex.Lookup()
+ */
+func showDefinitions(name string) {
+
+	if name == "" {
+		fmt.Println("Usage: def NAME")
+		return
+	}
+
+	definitions := codebase.GetSymbolIndex().Lookup(name)
+	if len(definitions) == 0 {
+		fmt.Println("No such declaration.")
+		return
+	}
+
+	for _, definition := range definitions {
+		fmt.Printf("%s:%d\t%s %s\n", definition.Path, definition.Line, definition.Kind, definition.FullName)
 	}
 }
 
@@ -98,13 +174,60 @@ func RunUI(out chan<- string, bye chan struct{}) {
 func UpdateUIStatus(in <-chan message.Message) {
 
 	for msg := range in {
-		fmt.Printf("%s\n\r%s", msg, READLINE_PROMPT)
+		displayMsg(msg)
+	}
+}
+
+/**
+ * Display Footle's current state.
+ *
+ * @see currentstate.Get()
+ */
+func showCurrentState() {
+
+	for _, msg := range currentstate.Get() {
+		displayMsg(msg)
+	}
+}
+
+/**
+ * Display a DBGp message.
+ *
+ * The engine's copy of a file arrives in base64 with the "source" response.
+ * It is transcoded to UTF-8 according to the file's encoding.
+ */
+func displayMsg(msg message.Message) {
 
-		// Some commands such as "source" send XML character data
-		// as inner XML content.
-		decoded, err := base64.StdEncoding.DecodeString(msg.Content)
-		if nil == err && 0 < len(decoded) {
-			fmt.Printf("%s\n\r%s", string(decoded), READLINE_PROMPT)
+	fmt.Printf("%v\n\r%s", msg, READLINE_PROMPT)
+
+	if msg.Properties.Command != "source" {
+		return
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(msg.Content)
+	if nil == err && 0 < len(decoded) {
+		if transcoded, err := codebase.GetEncodings().Decode(toRelativePath(msg.Properties.Filename), decoded); err == nil {
+			decoded = transcoded
 		}
+
+		fmt.Printf("%s\n\r%s", string(decoded), READLINE_PROMPT)
+	}
+}
+
+/**
+ * Turn a file URI from the DBGp engine into a path relative to the codebase.
+ *
+ * Files outside the codebase keep their local path.
+ */
+func toRelativePath(fileUri string) (relativePath string) {
+
+	config := config.Get()
+	localPath := config.ToLocalPath(fileUri)
+
+	relativePath, err := filepath.Rel(config.GetCodebase(), localPath)
+	if err != nil || strings.HasPrefix(relativePath, "..") {
+		return localPath
 	}
+
+	return relativePath
 }
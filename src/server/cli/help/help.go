@@ -8,25 +8,33 @@ package help
 var cliCmdList []helptext = []helptext{
 	helptext{[]string{"bye", "quit", "q"}, "Quits Footle."},
 	helptext{[]string{"refresh"}, "Updates cli with any pending DBGp messages."},
+	helptext{[]string{"state"}, "Shows the current state: session details, last break, stack trace, variables, and breakpoints."},
 	helptext{[]string{"verbose"}, "Dumps all traffic between Footle and the debugger engine."},
 	helptext{[]string{"no-verbose"}, "Opposite of *verbose*."},
+	helptext{[]string{"find"}, "Lists files whose path fuzzy matches the given text.\nExample: find usrctl"},
+	helptext{[]string{"def"}, "Lists where a PHP function, method, class, interface, trait, or constant is declared.\nExample: def save; def User::save; def App\\Model\\User"},
 }
 
 var footleCmdList []helptext = []helptext{
 	helptext{[]string{"on"}, "Awake Footle.  It will then start responding to the debugger engine."},
 	helptext{[]string{"off"}, "Put Footle to sleep.  It won't then respond to the debugger engine."},
-	helptext{[]string{"continue"}, "End execution.  Ignore all breakpoints if needed."},
+	helptext{[]string{"continue"}, "Detach from the debugger engine and let the script finish.  Ignores all breakpoints."},
 	helptext{[]string{"update_source"}, "Refresh source code of a displayed file.\nExample: update_source foo.php"},
+	helptext{[]string{"run_to"}, "Carry on with execution until the given line.\nUsage: run_to FILEPATH LINE-NUMBER\nExample: run_to foo.php 18"},
+	helptext{[]string{"record"}, "Record all DBGp traffic to a session file.  Useful for bug reports.\nExample: record /tmp/session.jsonl"},
+	helptext{[]string{"stop_recording"}, "Stop recording DBGp traffic."},
 }
 
 var DBGpCmdList []helptext = []helptext{
+	helptext{[]string{"break", "pause"}, "Interrupt a running script.  Execution stops at the current line."},
 	helptext{[]string{"breakpoint_set", "b"}, "Usage: breakpoint_set FILEPATH LINE-NUMBER"},
 	helptext{[]string{"breakpoint_get", "bg"}, "Usage: breakpoint_get BREAKPOINT-ID"},
 	helptext{[]string{"breakpoint_remove", "br"}, "Usage: breakpoint_remove BREAKPOINT-ID"},
 	helptext{[]string{"breakpoint_list", "bl"}, "Fetches all breakpoints, including the pending ones."},
 	helptext{[]string{"context_get", "vl"}, "Fetches all variables.\nUsage: context_get [local|global [stack-depth-number]]\nExample: context_get; context_get local; context_get global 3"},
+	helptext{[]string{"detach"}, "Stop debugging and let the script finish on its own."},
 	helptext{[]string{"dbgp"}, "Useful for executing raw DBGp commands.  Do *not* provide the transaction ID.\nUsage: dbgp DBGP-COMMAND [DBGP-COMMAND-ARGS]\nExample: dbgp breakpoint_list"},
-	helptext{[]string{"eval", "ev"}, "Broken, don't use."},
+	helptext{[]string{"eval", "ev"}, "Evaluate a PHP expression.\nExample: eval $user->getName()"},
 	helptext{[]string{"property_get", "var"}, "Fetch the value of a variable.  Usage: property_get [local|global] VARIABLE-NAME\nExample: property_get $foo; property_get global $bar.  When neither *local* nor *global* context is mentioned, local is assumed."},
 	helptext{[]string{"run", "r"}, "Carry on with execution."},
 	helptext{[]string{"stk", "stack_get"}, "Fetch current stack trace."},
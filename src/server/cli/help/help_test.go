@@ -64,7 +64,7 @@ func TestForAll(t *testing.T) {
 	helpObj.prepare(texts, []helptext{}, []helptext{})
 	result := helpObj.forAll()
 
-	expected := "foo, bar\nbaz, qux\ntar\n"
+	expected := "help [cmd]\nExample: help bye\n\nAvailable commands and their aliases:\nfoo, bar\nbaz, qux\ntar\n"
 	if result != expected {
 		t.Errorf("Command list does not meet expectation. %s given.", result)
 	}
@@ -0,0 +1,217 @@
+/**
+ * @file
+ * Character encodings of source files.
+ *
+ * Footle works in UTF-8.  Legacy PHP files may be in some other encoding such
+ * as ISO-8859-1 or Windows-1252.  These are transcoded to UTF-8 for display.
+ *
+ * The encoding of a file is decided by, in order:
+ *   - The configured encoding for its path.  Paths are given as .gitignore
+ *     style patterns, e.g. legacy/=iso-8859-1 or *.inc=windows-1252.  The
+ *     last matching pattern wins.
+ *   - A byte order mark.
+ *   - Valid UTF-8 is left alone.
+ *   - Anything else is taken to be in the fallback encoding.  This is
+ *     Windows-1252 unless configured otherwise, e.g. -encoding iso-8859-15.
+ *
+ * Encoding names are those understood by browsers.
+ *
+ * @see https://encoding.spec.whatwg.org/#names-and-labels
+ */
+
+package codebase
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path"
+	"server/config"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+)
+
+const defaultFallbackEncoding = "windows-1252"
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+/**
+ * Byte order marks of encodings other than UTF-8.
+ */
+var byteOrderMarks = []struct {
+	bom      []byte
+	encoding string
+}{
+	{[]byte{0xFF, 0xFE}, "utf-16le"},
+	{[]byte{0xFE, 0xFF}, "utf-16be"},
+}
+
+type encodingRule struct {
+	rule
+	encoding string
+}
+
+/**
+ * Decides the encoding of each source file.
+ */
+type Encodings struct {
+	rules            []encodingRule
+	fallbackEncoding string
+}
+
+var sharedEncodings *Encodings
+var sharedEncodingsOnce sync.Once
+
+/**
+ * Encodings as configured through the -encoding option.
+ *
+ * Broken settings are logged and skipped.
+ */
+func GetEncodings() *Encodings {
+
+	sharedEncodingsOnce.Do(func() {
+		var err error
+
+		if sharedEncodings, err = NewEncodings(config.Get().GetEncodings()); err != nil {
+			log.Println(err)
+		}
+	})
+
+	return sharedEncodings
+}
+
+/**
+ * Prepare encodings from their settings.
+ *
+ * Each setting is either PATTERN=ENCODING or just ENCODING.  The latter
+ * replaces the fallback encoding.  Unusable settings are skipped and reported
+ * through the returned error.
+ */
+func NewEncodings(settings []string) (encodings *Encodings, err error) {
+
+	encodings = &Encodings{fallbackEncoding: defaultFallbackEncoding}
+	brokenSettings := []string{}
+
+	for _, setting := range settings {
+		pattern, encoding := "", setting
+		if separatorPos := strings.LastIndex(setting, "="); separatorPos >= 0 {
+			pattern, encoding = setting[:separatorPos], setting[separatorPos+1:]
+		}
+
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if knownEncoding, _ := charset.Lookup(encoding); knownEncoding == nil {
+			brokenSettings = append(brokenSettings, setting)
+			continue
+		}
+
+		if pattern == "" {
+			encodings.fallbackEncoding = encoding
+		} else if r, ok := parseRule(strings.TrimSpace(pattern), ""); ok {
+			encodings.rules = append(encodings.rules, encodingRule{rule: r, encoding: encoding})
+		} else {
+			brokenSettings = append(brokenSettings, setting)
+		}
+	}
+
+	if len(brokenSettings) > 0 {
+		err = fmt.Errorf("Unknown encoding settings: %s", strings.Join(brokenSettings, ", "))
+	}
+
+	return encodings, err
+}
+
+/**
+ * Has an encoding been configured for this file?
+ *
+ * The path is relative to the codebase and slash separated.
+ */
+func (e *Encodings) IsConfigured(relativePath string) bool {
+
+	return e.configuredEncoding(relativePath) != ""
+}
+
+/**
+ * Encoding of a file with the given content.
+ *
+ * "utf-8" means no transcoding is needed.
+ */
+func (e *Encodings) Detect(relativePath string, src []byte) (encoding string) {
+
+	if encoding = e.configuredEncoding(relativePath); encoding != "" {
+		return encoding
+	}
+
+	if bytes.HasPrefix(src, utf8BOM) || utf8.Valid(src) {
+		return "utf-8"
+	}
+
+	for _, mark := range byteOrderMarks {
+		if bytes.HasPrefix(src, mark.bom) {
+			return mark.encoding
+		}
+	}
+
+	if e == nil {
+		return defaultFallbackEncoding
+	}
+
+	return e.fallbackEncoding
+}
+
+/**
+ * Transcode the content of a file to UTF-8.
+ *
+ * Byte order marks are dropped.  Works on a nil Encodings too.  Then only
+ * byte order marks and the default fallback encoding are considered.
+ */
+func (e *Encodings) Decode(relativePath string, src []byte) (decoded []byte, err error) {
+
+	encoding := e.Detect(relativePath, src)
+
+	src = bytes.TrimPrefix(src, utf8BOM)
+	for _, mark := range byteOrderMarks {
+		if mark.encoding == encoding {
+			src = bytes.TrimPrefix(src, mark.bom)
+		}
+	}
+
+	if encoding == "utf-8" || encoding == "utf8" {
+		return src, nil
+	}
+
+	reader, err := charset.NewReaderLabel(encoding, bytes.NewReader(src))
+	if err != nil {
+		return src, err
+	}
+
+	return ioutil.ReadAll(reader)
+}
+
+/**
+ * Configured encoding for a file, if any.
+ *
+ * Patterns for a directory apply to everything inside.
+ */
+func (e *Encodings) configuredEncoding(relativePath string) (encoding string) {
+
+	if e == nil {
+		return ""
+	}
+
+	relativePath = strings.Trim(path.Clean("/"+relativePath), "/")
+
+	for _, r := range e.rules {
+		for candidate, isDir := relativePath, false; candidate != "." && candidate != ""; candidate, isDir = path.Dir(candidate), true {
+			if r.matches(candidate, isDir) {
+				encoding = r.encoding
+				break
+			}
+		}
+	}
+
+	return encoding
+}
@@ -0,0 +1,67 @@
+package codebase
+
+import "testing"
+
+/**
+ * Tests for Encodings.Detect().
+ */
+func TestDetectEncoding(t *testing.T) {
+
+	encodings, err := NewEncodings([]string{"iso-8859-15", "legacy/=iso-8859-1", "*.inc=koi8-r", "legacy/new/=utf-8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path     string
+		src      string
+		encoding string
+	}{
+		{"index.php", "<?php echo 'café';", "utf-8"},
+		{"index.php", "<?php echo 'caf\xe9';", "iso-8859-15"},
+		{"index.php", "\xef\xbb\xbf<?php", "utf-8"},
+		{"index.php", "\xff\xfe<\x00?\x00", "utf-16le"},
+		{"legacy/User.php", "<?php", "iso-8859-1"},
+		{"/legacy/lib/db.php", "<?php", "iso-8859-1"},
+		{"legacy/new/User.php", "<?php echo 'caf\xe9';", "utf-8"},
+		{"lib/header.inc", "<?php", "koi8-r"},
+	}
+
+	for _, c := range cases {
+		if encoding := encodings.Detect(c.path, []byte(c.src)); encoding != c.encoding {
+			t.Errorf("Expected %s for %s, got %s", c.encoding, c.path, encoding)
+		}
+	}
+
+	if _, err := NewEncodings([]string{"no-such-encoding", "*.php=klingon"}); err == nil {
+		t.Error("Expected an error for unknown encodings.")
+	}
+}
+
+/**
+ * Tests for Encodings.Decode().
+ */
+func TestDecode(t *testing.T) {
+
+	encodings, _ := NewEncodings([]string{"legacy/=iso-8859-1"})
+
+	cases := []struct {
+		encodings *Encodings
+		path      string
+		src       string
+		decoded   string
+	}{
+		{encodings, "index.php", "caf\xe9 \x80", "café €"},
+		{encodings, "legacy/index.php", "caf\xe9", "café"},
+		{encodings, "index.php", "\xef\xbb\xbfcafé", "café"},
+		{encodings, "index.php", "\xfe\xff\x00h\x00i", "hi"},
+		{nil, "index.php", "caf\xe9", "café"},
+		{nil, "index.php", "café", "café"},
+	}
+
+	for _, c := range cases {
+		if decoded, err := c.encodings.Decode(c.path, []byte(c.src)); err != nil || string(decoded) != c.decoded {
+			t.Errorf("Expected %q for %q, got %q, %v", c.decoded, c.src, decoded, err)
+		}
+	}
+}
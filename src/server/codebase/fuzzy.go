@@ -0,0 +1,165 @@
+/**
+ * @file
+ * Fuzzy matching of file paths, Ctrl-P style.
+ *
+ * A path matches when all the characters of the query appear in it in the same
+ * order, ignoring case.  Example: "uctrl" matches "src/User/Controller.php".
+ * Only ASCII letters are compared without case.  Others must match exactly.
+ *
+ * Among all the ways a query can match a path, the best scoring one counts.
+ * Characters score more when they:
+ *   - start a path segment, e.g. the "C" of "/Controller.php"
+ *   - start a word, e.g. the "c" of "user_controller" or "UserController"
+ *   - follow the previous matched character
+ *   - are part of the filename rather than a directory name
+ * Long paths score a little less so that "index.php" beats
+ * "vendor/foo/bar/index.php".
+ */
+
+package codebase
+
+import "strings"
+
+/**
+ * A path matching a query.
+ */
+type Match struct {
+	Path  string `json:"path"`
+	Score int    `json:"score"`
+}
+
+const segmentStartBonus = 8
+const wordStartBonus = 6
+const consecutiveBonus = 4
+const filenameBonus = 2
+
+/**
+ * Characters that separate words in filenames.
+ */
+const wordSeparators = "_-. "
+
+/**
+ * Score the best way a query matches a path.
+ *
+ * The query must be in lowercase as per toLowerASCII().  The second return
+ * value is false when the path does not match at all.
+ */
+func fuzzyScore(query, filepath string) (score int, isMatch bool) {
+
+	lowercasePath := toLowerASCII(filepath)
+	if !isSubsequence(query, lowercasePath) {
+		return 0, false
+	}
+
+	filenameStart := strings.LastIndexByte(filepath, '/') + 1
+	charScores := make([]int, len(filepath))
+	for j := range filepath {
+		charScores[j] = scoreChar(filepath, j, filenameStart)
+	}
+
+	// best[j]: Best score for the query so far with its last character matched
+	// at position j of the path.  noMatch where impossible.
+	const noMatch = -1 << 30
+	best := make([]int, len(filepath))
+	for j := range best {
+		best[j] = noMatch
+		if lowercasePath[j] == query[0] {
+			best[j] = charScores[j]
+		}
+	}
+
+	for i := 1; i < len(query); i++ {
+		next := make([]int, len(filepath))
+		bestBefore := noMatch // Best of best[0..j-2].
+
+		for j := range filepath {
+			next[j] = noMatch
+
+			if j >= 2 && best[j-2] > bestBefore {
+				bestBefore = best[j-2]
+			}
+
+			if lowercasePath[j] != query[i] || j == 0 {
+				continue
+			}
+
+			candidate := bestBefore
+			if best[j-1] != noMatch && best[j-1]+consecutiveBonus > candidate {
+				candidate = best[j-1] + consecutiveBonus
+			}
+
+			if candidate != noMatch {
+				next[j] = candidate + charScores[j]
+			}
+		}
+
+		best = next
+	}
+
+	score = noMatch
+	for _, s := range best {
+		if s > score {
+			score = s
+		}
+	}
+
+	return score - len(filepath)/8, true
+}
+
+/**
+ * Score of a single matching character.
+ */
+func scoreChar(filepath string, position, filenameStart int) (score int) {
+
+	score = 1
+
+	if position >= filenameStart {
+		score += filenameBonus
+	}
+
+	if position == 0 || filepath[position-1] == '/' {
+		return score + segmentStartBonus
+	}
+
+	previous, current := filepath[position-1], filepath[position]
+	isCamelHump := 'a' <= previous && previous <= 'z' && 'A' <= current && current <= 'Z'
+
+	if strings.IndexByte(wordSeparators, previous) >= 0 || isCamelHump {
+		score += wordStartBonus
+	}
+
+	return score
+}
+
+/**
+ * Do all the characters of the query appear in the text in the same order?
+ */
+func isSubsequence(query, text string) bool {
+
+	i := 0
+	for j := 0; i < len(query) && j < len(text); j++ {
+		if query[i] == text[j] {
+			i++
+		}
+	}
+
+	return i == len(query)
+}
+
+/**
+ * Lowercase the ASCII letters of a text.
+ *
+ * Unlike strings.ToLower(), the text keeps its length in bytes.  So positions
+ * in the result are positions in the original text as well.
+ */
+func toLowerASCII(text string) string {
+
+	lowercase := []byte(text)
+	for i, c := range lowercase {
+		if 'A' <= c && c <= 'Z' {
+			lowercase[i] = c + 'a' - 'A'
+		}
+	}
+
+	return string(lowercase)
+}
@@ -0,0 +1,43 @@
+package codebase
+
+import "testing"
+
+/**
+ * Tests for fuzzyScore().
+ */
+func TestFuzzyScore(t *testing.T) {
+
+	if _, isMatch := fuzzyScore("ctrlusr", "src/UserController.php"); isMatch {
+		t.Error("Characters out of order should not match.")
+	}
+
+	if _, isMatch := fuzzyScore("usrctl", "src/UserController.php"); !isMatch {
+		t.Error("Expected a case insensitive match.")
+	}
+
+	// The Kelvin sign is longer in bytes than its lowercase form.
+	if _, isMatch := fuzzyScore("ab", "\u212Aab.php"); !isMatch {
+		t.Error("Expected a match after a non-ASCII character.")
+	}
+
+	if _, isMatch := fuzzyScore("é", "\u00C9t\u00E9.php"); !isMatch {
+		t.Error("Expected an exact match of a non-ASCII character.")
+	}
+
+	// Better match first.
+	orderedPairs := [][3]string{
+		{"uc", "src/UserController.php", "src/Utils/misc.php"},
+		{"app", "src/App.php", "src/Mapper.php"},
+		{"index", "index.php", "vendor/foo/bar/index.php"},
+		{"fb", "foo_bar.php", "fooba.php"},
+	}
+
+	for _, pair := range orderedPairs {
+		better, _ := fuzzyScore(pair[0], pair[1])
+		worse, _ := fuzzyScore(pair[0], pair[2])
+
+		if better <= worse {
+			t.Errorf("%q: Expected %s (%d) to beat %s (%d).", pair[0], pair[1], better, pair[2], worse)
+		}
+	}
+}
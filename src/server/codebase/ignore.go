@@ -0,0 +1,266 @@
+/**
+ * @file
+ * Ignore rules for the codebase.
+ *
+ * Files can be ignored through .gitignore files anywhere in the codebase and
+ * through Footle's own exclude list.  Both use the .gitignore syntax:
+ *   - Blank lines and lines starting with # are skipped.
+ *   - A leading ! re-includes whatever an earlier pattern has ignored.
+ *   - A trailing / only matches directories.
+ *   - A pattern without any other / matches at any depth.  Otherwise it is
+ *     relative to the directory of the .gitignore file.
+ *   - * and ? do not match /.  ** matches across directories.
+ *
+ * The last matching rule wins.  The exclude list comes after all .gitignore
+ * files.  The .git directory is always ignored.
+ *
+ * @see https://git-scm.com/docs/gitignore
+ */
+
+package codebase
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type rule struct {
+	pattern    *regexp.Regexp
+	isNegated  bool
+	isDirOnly  bool
+	isAnchored bool   // Relative to baseDir rather than matching at any depth.
+	baseDir    string // Relative, slash separated path of the .gitignore file's directory.
+}
+
+/**
+ * Decides which files in a codebase are ignored.
+ *
+ * .gitignore files are read once per Filter.
+ */
+type Filter struct {
+	root         string
+	excludeRules []rule
+	dirRules     map[string][]rule // Rules from each directory's .gitignore.
+	ignoredDirs  map[string]bool   // Directories we have already checked.
+}
+
+/**
+ * Prepare a filter for the given codebase.
+ *
+ * Excludes are .gitignore style patterns relative to the codebase.
+ */
+func NewFilter(root string, excludes []string) (filter *Filter) {
+
+	filter = &Filter{root: root, dirRules: make(map[string][]rule), ignoredDirs: make(map[string]bool)}
+
+	for _, exclude := range append([]string{".git/"}, excludes...) {
+		if r, ok := parseRule(exclude, ""); ok {
+			filter.excludeRules = append(filter.excludeRules, r)
+		}
+	}
+
+	return filter
+}
+
+/**
+ * Is this file or directory ignored?
+ *
+ * The path is relative to the codebase and slash separated.  A file inside an
+ * ignored directory is ignored too.
+ */
+func (f *Filter) IsIgnored(relativePath string, isDir bool) bool {
+
+	relativePath = strings.Trim(path.Clean("/"+relativePath), "/")
+	if relativePath == "" {
+		return false
+	}
+
+	if isIgnored, isKnown := f.ignoredDirs[relativePath]; isKnown && isDir {
+		return isIgnored
+	}
+
+	parentDir := path.Dir(relativePath)
+	if parentDir == "." {
+		parentDir = ""
+	} else if f.IsIgnored(parentDir, true) {
+		return true
+	}
+
+	isIgnored := false
+	for _, r := range f.rulesFor(parentDir) {
+		if r.matches(relativePath, isDir) {
+			isIgnored = !r.isNegated
+		}
+	}
+
+	if isDir {
+		f.ignoredDirs[relativePath] = isIgnored
+	}
+
+	return isIgnored
+}
+
+/**
+ * All the rules that apply to the content of a directory.
+ *
+ * Rules from .gitignore files higher up come first.
+ */
+func (f *Filter) rulesFor(dir string) (rules []rule) {
+
+	ancestors := []string{""}
+	if dir != "" {
+		parts := strings.Split(dir, "/")
+		for i := range parts {
+			ancestors = append(ancestors, strings.Join(parts[:i+1], "/"))
+		}
+	}
+
+	for _, ancestor := range ancestors {
+		rules = append(rules, f.loadGitignore(ancestor)...)
+	}
+
+	return append(rules, f.excludeRules...)
+}
+
+/**
+ * Rules from a directory's .gitignore file.
+ */
+func (f *Filter) loadGitignore(dir string) (rules []rule) {
+
+	if rules, isLoaded := f.dirRules[dir]; isLoaded {
+		return rules
+	}
+
+	file, err := os.Open(filepath.Join(f.root, filepath.FromSlash(dir), ".gitignore"))
+	if err == nil {
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if r, ok := parseRule(scanner.Text(), dir); ok {
+				rules = append(rules, r)
+			}
+		}
+	}
+
+	f.dirRules[dir] = rules
+
+	return rules
+}
+
+/**
+ * Parse a line of a .gitignore file.
+ */
+func parseRule(line, baseDir string) (r rule, ok bool) {
+
+	line = strings.TrimRight(line, " \r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return r, false
+	}
+
+	r.baseDir = baseDir
+
+	if strings.HasPrefix(line, "!") {
+		r.isNegated = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		r.isDirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	r.isAnchored = strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	if line == "" {
+		return r, false
+	}
+
+	pattern, err := regexp.Compile("^" + globToRegexp(line) + "$")
+	if err != nil {
+		return r, false
+	}
+
+	r.pattern = pattern
+
+	return r, true
+}
+
+/**
+ * Does this rule match the given path?
+ */
+func (r rule) matches(relativePath string, isDir bool) bool {
+
+	if r.isDirOnly && !isDir {
+		return false
+	}
+
+	if !r.isAnchored {
+		return r.pattern.MatchString(path.Base(relativePath))
+	}
+
+	if r.baseDir != "" {
+		if !strings.HasPrefix(relativePath, r.baseDir+"/") {
+			return false
+		}
+
+		relativePath = relativePath[len(r.baseDir)+1:]
+	}
+
+	return r.pattern.MatchString(relativePath)
+}
+
+/**
+ * Turn a .gitignore glob into a regular expression.
+ */
+func globToRegexp(glob string) string {
+
+	var expr bytes.Buffer
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			expr.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '[':
+			classEnd := strings.IndexByte(glob[i+1:], ']')
+			if classEnd < 0 {
+				expr.WriteString(`\[`)
+				continue
+			}
+
+			class := glob[i+1 : i+1+classEnd]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			expr.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i += classEnd + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return expr.String()
+}
@@ -0,0 +1,74 @@
+package codebase
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/**
+ * Tests for Filter.IsIgnored().
+ */
+func TestIsIgnored(t *testing.T) {
+
+	root, err := ioutil.TempDir("", "footle-codebase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	os.MkdirAll(filepath.Join(root, "web", "sites"), 0755)
+	ioutil.WriteFile(filepath.Join(root, ".gitignore"), []byte("# Dependencies.\nvendor/\n*.log\n!keep.log\n/build\ndocs/**/*.pdf\n"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "web", ".gitignore"), []byte("sites/*.php\n!sites/default.php\n"), 0644)
+
+	filter := NewFilter(root, []string{"*.min.js"})
+
+	cases := []struct {
+		path      string
+		isDir     bool
+		isIgnored bool
+	}{
+		{"index.php", false, false},
+		{"vendor", true, true},
+		{"vendor/autoload.php", false, true},
+		{"lib/vendor", true, true},
+		{"lib/vendor", false, false},
+		{"error.log", false, true},
+		{"logs/keep.log", false, false},
+		{"build", true, true},
+		{"src/build", true, false},
+		{"docs/a/b/manual.pdf", false, true},
+		{"web/sites/settings.php", false, true},
+		{"web/sites/default.php", false, false},
+		{"sites/settings.php", false, false},
+		{"web/js/app.min.js", false, true},
+		{".git", true, true},
+		{".git/HEAD", false, true},
+	}
+
+	for _, c := range cases {
+		if c.isIgnored != filter.IsIgnored(c.path, c.isDir) {
+			t.Errorf("Expected %v for %s", c.isIgnored, c.path)
+		}
+	}
+}
+
+/**
+ * Tests for globToRegexp().
+ */
+func TestGlobToRegexp(t *testing.T) {
+
+	cases := map[string]string{
+		"*.php":      `[^/]*\.php`,
+		"a/**/b":     `a/(.*/)?b`,
+		"a/**":       `a/.*`,
+		"file?.[!a]": `file[^/]\.[^a]`,
+	}
+
+	for glob, expected := range cases {
+		if expr := globToRegexp(glob); expected != expr {
+			t.Errorf("Expected %s for %s, got %s", expected, glob, expr)
+		}
+	}
+}
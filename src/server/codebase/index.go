@@ -0,0 +1,220 @@
+/**
+ * @file
+ * Index of all files in the codebase.
+ *
+ * Powers the fuzzy file finder.  The index is built on first use and then kept
+ * up to date as files change.  Ignored files are not indexed.
+ *
+ * @see fuzzy.go
+ */
+
+package codebase
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"server/config"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type Index struct {
+	root     string
+	excludes []string
+	lock     sync.RWMutex
+	paths    map[string]bool // Relative, slash separated file paths.
+	changes  []string        // Paths updated during a build.  Nil otherwise.
+}
+
+var sharedIndex *Index
+var sharedIndexOnce sync.Once
+
+/**
+ * Guards sharedIndex and sharedSymbolIndex.
+ *
+ * The watcher refreshes the indexes from its own goroutine.
+ */
+var sharedIndexLock sync.Mutex
+
+/**
+ * The index of the configured codebase.
+ *
+ * Built on the first call.
+ */
+func GetIndex() *Index {
+
+	sharedIndexOnce.Do(func() {
+		config := config.Get()
+		index := NewIndex(config.GetCodebase(), config.GetExcludes())
+
+		// Shared before it is built so that no change is missed meanwhile.
+		sharedIndexLock.Lock()
+		sharedIndex = index
+		sharedIndexLock.Unlock()
+
+		index.Build()
+	})
+
+	sharedIndexLock.Lock()
+	defer sharedIndexLock.Unlock()
+
+	return sharedIndex
+}
+
+/**
+ * Refresh the shared indexes for a changed, new, or deleted path.
+ *
+ * Indexes nobody has used yet are left alone.
+ *
+ * @see GetSymbolIndex()
+ */
+func RefreshIndex(relativePath string) {
+
+	sharedIndexLock.Lock()
+	index, symbolIndex := sharedIndex, sharedSymbolIndex
+	sharedIndexLock.Unlock()
+
+	if index != nil {
+		index.Update(relativePath)
+	}
+
+	if symbolIndex != nil {
+		symbolIndex.Update(relativePath)
+	}
+}
+
+/**
+ * Prepare an empty index.
+ */
+func NewIndex(root string, excludes []string) *Index {
+
+	return &Index{root: root, excludes: excludes, paths: make(map[string]bool)}
+}
+
+/**
+ * Index every file in the codebase from scratch.
+ *
+ * Paths updated during the build are updated again afterwards.  The walk may
+ * have seen them before they changed.
+ */
+func (i *Index) Build() (err error) {
+
+	i.lock.Lock()
+	i.changes = []string{}
+	i.lock.Unlock()
+
+	paths := make(map[string]bool)
+
+	err = Walk(i.root, NewFilter(i.root, i.excludes), func(relativePath string, fileInfo os.FileInfo) error {
+
+		paths[relativePath] = true
+		return nil
+	})
+
+	i.lock.Lock()
+	i.paths = paths
+	changes := i.changes
+	i.changes = nil
+	i.lock.Unlock()
+
+	for _, relativePath := range changes {
+		i.Update(relativePath)
+	}
+
+	return err
+}
+
+/**
+ * Bring the index up to date for a single file or directory.
+ *
+ * Deleted paths are dropped.  New directories are indexed in full.
+ */
+func (i *Index) Update(relativePath string) {
+
+	relativePath = strings.Trim(path.Clean("/"+filepath.ToSlash(relativePath)), "/")
+	absolutePath, _, err := resolve(i.root, relativePath)
+	if err != nil || relativePath == "" {
+		return
+	}
+
+	fileInfo, err := os.Stat(absolutePath)
+	filter := NewFilter(i.root, i.excludes)
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.changes != nil {
+		i.changes = append(i.changes, relativePath)
+	}
+
+	if err != nil || filter.IsIgnored(relativePath, fileInfo.IsDir()) {
+		delete(i.paths, relativePath)
+
+		for indexedPath := range i.paths {
+			if strings.HasPrefix(indexedPath, relativePath+"/") {
+				delete(i.paths, indexedPath)
+			}
+		}
+	} else if fileInfo.IsDir() {
+		walkFrom(i.root, absolutePath, filter, func(walkedPath string, fileInfo os.FileInfo) error {
+
+			i.paths[walkedPath] = true
+			return nil
+		})
+	} else {
+		i.paths[relativePath] = true
+	}
+}
+
+/**
+ * Number of indexed files.
+ */
+func (i *Index) Size() int {
+
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	return len(i.paths)
+}
+
+/**
+ * Best fuzzy matches for the query, best first.
+ *
+ * At most "limit" matches are returned.
+ */
+func (i *Index) Find(query string, limit int) (matches []Match) {
+
+	query = toLowerASCII(strings.Join(strings.Fields(query), ""))
+	if query == "" {
+		return []Match{}
+	}
+
+	i.lock.RLock()
+	for indexedPath := range i.paths {
+		if score, isMatch := fuzzyScore(query, indexedPath); isMatch {
+			matches = append(matches, Match{Path: indexedPath, Score: score})
+		}
+	}
+	i.lock.RUnlock()
+
+	sort.Slice(matches, func(a, b int) bool {
+
+		if matches[a].Score != matches[b].Score {
+			return matches[a].Score > matches[b].Score
+		}
+
+		return matches[a].Path < matches[b].Path
+	})
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	if matches == nil {
+		matches = []Match{}
+	}
+
+	return matches
+}
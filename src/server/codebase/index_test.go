@@ -0,0 +1,116 @@
+package codebase
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/**
+ * Tests for Index.Find().
+ */
+func TestFind(t *testing.T) {
+
+	index := NewIndex("", nil)
+	for _, path := range []string{"src/UserController.php", "src/User.php", "src/Utils/Cache.php", "tests/UserControllerTest.php", "README.md"} {
+		index.paths[path] = true
+	}
+
+	matches := index.Find("usr ctl", 10)
+	if len(matches) != 2 || matches[0].Path != "src/UserController.php" || matches[1].Path != "tests/UserControllerTest.php" {
+		t.Errorf("Unexpected matches: %v", matches)
+	}
+
+	if matches = index.Find("u", 2); len(matches) != 2 {
+		t.Errorf("Expected two matches at most, got: %v", matches)
+	}
+
+	if matches = index.Find("xyz", 10); matches == nil || len(matches) != 0 {
+		t.Errorf("Expected an empty list, got: %#v", matches)
+	}
+
+	// Lowercasing the Kelvin sign would shorten the path.
+	index.paths["\u212Aab.php"] = true
+	if matches = index.Find("ab", 10); len(matches) != 1 {
+		t.Errorf("Expected one match, got: %v", matches)
+	}
+}
+
+/**
+ * Tests for Index.Build() and Index.Update().
+ */
+func TestUpdate(t *testing.T) {
+
+	root := prepareCodebase(t)
+	defer os.RemoveAll(root)
+
+	index := NewIndex(root, nil)
+	if err := index.Build(); err != nil || index.Size() != 3 {
+		t.Fatalf("Expected three files, got %v, %v", index.paths, err)
+	}
+
+	os.MkdirAll(filepath.Join(root, "src", "Model"), 0755)
+	ioutil.WriteFile(filepath.Join(root, "src", "Model", "User.php"), []byte("<?php"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "vendor", "lib", "More.php"), []byte("<?php"), 0644)
+	index.Update("src/Model")
+	index.Update("vendor/lib/More.php")
+
+	if !index.paths["src/Model/User.php"] || index.paths["vendor/lib/More.php"] {
+		t.Errorf("Unexpected index after additions: %v", index.paths)
+	}
+
+	os.RemoveAll(filepath.Join(root, "src"))
+	index.Update("src")
+
+	if index.Size() != 2 || index.paths["src/App.php"] {
+		t.Errorf("Unexpected index after deletion: %v", index.paths)
+	}
+}
+
+/**
+ * Tests for Index.Build() and Index.Update().
+ *
+ * Files added while the index is being built should not be lost.
+ */
+func TestUpdateDuringBuild(t *testing.T) {
+
+	root := prepareCodebase(t)
+	defer os.RemoveAll(root)
+
+	// Plenty of files so that the build takes a while.
+	for d := 0; d < 100; d++ {
+		dir := filepath.Join(root, fmt.Sprintf("old%d", d))
+		os.Mkdir(dir, 0755)
+
+		for n := 0; n < 50; n++ {
+			ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.php", n)), []byte("<?php"), 0644)
+		}
+	}
+
+	index := NewIndex(root, nil)
+	built := make(chan struct{})
+
+	go func() {
+		index.Build()
+		close(built)
+	}()
+
+	added := 0
+	for isBuilding := true; isBuilding; added++ {
+		select {
+		case <-built:
+			isBuilding = false
+		default:
+		}
+
+		relativePath := fmt.Sprintf("new%d.php", added)
+		ioutil.WriteFile(filepath.Join(root, relativePath), []byte("<?php"), 0644)
+		index.Update(relativePath)
+	}
+
+	if expected := 5003 + added; index.Size() != expected {
+		t.Errorf("Expected %d files, got %d", expected, index.Size())
+	}
+}
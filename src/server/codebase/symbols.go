@@ -0,0 +1,268 @@
+/**
+ * @file
+ * Index of PHP declarations in the codebase.
+ *
+ * Powers "go to definition".  Like the file index, it is built on first use
+ * and then kept up to date as files change.
+ *
+ * @see php/symbols.go
+ */
+
+package codebase
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"server/config"
+	"server/php"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/**
+ * A declaration and the file it is in.
+ */
+type Definition struct {
+	Path string `json:"path"`
+	php.Symbol
+}
+
+type SymbolIndex struct {
+	root     string
+	excludes []string
+	lock     sync.RWMutex
+	files    map[string][]php.Symbol // Declarations of each PHP file.
+	changes  []string                // Paths updated during a build.  Nil otherwise.
+}
+
+var sharedSymbolIndex *SymbolIndex
+var sharedSymbolIndexOnce sync.Once
+
+/**
+ * The symbol index of the configured codebase.
+ *
+ * Built on the first call.
+ */
+func GetSymbolIndex() *SymbolIndex {
+
+	sharedSymbolIndexOnce.Do(func() {
+		config := config.Get()
+		symbolIndex := NewSymbolIndex(config.GetCodebase(), config.GetExcludes())
+
+		// Shared before it is built so that no change is missed meanwhile.
+		sharedIndexLock.Lock()
+		sharedSymbolIndex = symbolIndex
+		sharedIndexLock.Unlock()
+
+		symbolIndex.Build()
+	})
+
+	sharedIndexLock.Lock()
+	defer sharedIndexLock.Unlock()
+
+	return sharedSymbolIndex
+}
+
+/**
+ * Prepare an empty symbol index.
+ */
+func NewSymbolIndex(root string, excludes []string) *SymbolIndex {
+
+	return &SymbolIndex{root: root, excludes: excludes, files: make(map[string][]php.Symbol)}
+}
+
+/**
+ * Index every PHP file in the codebase from scratch.
+ *
+ * @see Index.Build()
+ */
+func (s *SymbolIndex) Build() (err error) {
+
+	s.lock.Lock()
+	s.changes = []string{}
+	s.lock.Unlock()
+
+	files := make(map[string][]php.Symbol)
+
+	err = Walk(s.root, NewFilter(s.root, s.excludes), func(relativePath string, fileInfo os.FileInfo) error {
+
+		if php.IsPHPFile(relativePath) {
+			files[relativePath] = s.parse(relativePath)
+		}
+
+		return nil
+	})
+
+	s.lock.Lock()
+	s.files = files
+	changes := s.changes
+	s.changes = nil
+	s.lock.Unlock()
+
+	for _, relativePath := range changes {
+		s.Update(relativePath)
+	}
+
+	return err
+}
+
+/**
+ * Bring the index up to date for a single file or directory.
+ *
+ * @see Index.Update()
+ */
+func (s *SymbolIndex) Update(relativePath string) {
+
+	relativePath = strings.Trim(path.Clean("/"+filepath.ToSlash(relativePath)), "/")
+	absolutePath, _, err := resolve(s.root, relativePath)
+	if err != nil || relativePath == "" {
+		return
+	}
+
+	fileInfo, err := os.Stat(absolutePath)
+	filter := NewFilter(s.root, s.excludes)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.changes != nil {
+		s.changes = append(s.changes, relativePath)
+	}
+
+	if err != nil || filter.IsIgnored(relativePath, fileInfo.IsDir()) {
+		delete(s.files, relativePath)
+
+		for indexedPath := range s.files {
+			if strings.HasPrefix(indexedPath, relativePath+"/") {
+				delete(s.files, indexedPath)
+			}
+		}
+	} else if fileInfo.IsDir() {
+		walkFrom(s.root, absolutePath, filter, func(walkedPath string, fileInfo os.FileInfo) error {
+
+			if php.IsPHPFile(walkedPath) {
+				s.files[walkedPath] = s.parse(walkedPath)
+			}
+
+			return nil
+		})
+	} else if php.IsPHPFile(relativePath) {
+		s.files[relativePath] = s.parse(relativePath)
+	}
+}
+
+/**
+ * Declarations of the given name.
+ *
+ * Case is ignored.  The name may be:
+ *   - Short, e.g. "save" or "User".
+ *   - Qualified with a class, e.g. "User::save".
+ *   - Qualified with a namespace, e.g. "App\Model\User" or "\App\helper".
+ * A trailing "()" is ignored.
+ *
+ * Sorted by path and line.
+ */
+func (s *SymbolIndex) Lookup(name string) (definitions []Definition) {
+
+	name = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(name), `\`), "()"))
+	isQualified := strings.ContainsAny(name, `\:`)
+
+	s.lock.RLock()
+	for relativePath, symbols := range s.files {
+		for _, symbol := range symbols {
+			var isMatch bool
+
+			if isQualified {
+				fullName := strings.ToLower(symbol.FullName)
+				isMatch = fullName == name || strings.HasSuffix(fullName, `\`+name)
+			} else {
+				isMatch = symbol.Kind != php.NamespaceKind && strings.ToLower(symbol.Name) == name
+			}
+
+			if isMatch {
+				definitions = append(definitions, Definition{Path: relativePath, Symbol: symbol})
+			}
+		}
+	}
+	s.lock.RUnlock()
+
+	sortDefinitions(definitions)
+
+	if definitions == nil {
+		definitions = []Definition{}
+	}
+
+	return definitions
+}
+
+/**
+ * Declarations whose short name starts with the given prefix.
+ *
+ * Useful for suggesting names as they are typed.  Case is ignored.  At most
+ * "limit" declarations are returned, shortest names first.
+ */
+func (s *SymbolIndex) Complete(prefix string, limit int) (definitions []Definition) {
+
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+
+	s.lock.RLock()
+	for relativePath, symbols := range s.files {
+		for _, symbol := range symbols {
+			if symbol.Kind != php.NamespaceKind && strings.HasPrefix(strings.ToLower(symbol.Name), prefix) {
+				definitions = append(definitions, Definition{Path: relativePath, Symbol: symbol})
+			}
+		}
+	}
+	s.lock.RUnlock()
+
+	sortDefinitions(definitions)
+	sort.SliceStable(definitions, func(a, b int) bool {
+
+		return len(definitions[a].Name) < len(definitions[b].Name)
+	})
+
+	if len(definitions) > limit {
+		definitions = definitions[:limit]
+	}
+
+	if definitions == nil {
+		definitions = []Definition{}
+	}
+
+	return definitions
+}
+
+/**
+ * Declarations in a PHP file.
+ *
+ * Unreadable files have none.  Files that are not in UTF-8 are transcoded
+ * first.
+ */
+func (s *SymbolIndex) parse(relativePath string) []php.Symbol {
+
+	src, err := ioutil.ReadFile(filepath.Join(s.root, filepath.FromSlash(relativePath)))
+	if err != nil {
+		return nil
+	}
+
+	if src, err = GetEncodings().Decode(relativePath, src); err != nil {
+		return nil
+	}
+
+	return php.Symbols(php.Tokenize(string(src)))
+}
+
+func sortDefinitions(definitions []Definition) {
+
+	sort.Slice(definitions, func(a, b int) bool {
+
+		if definitions[a].Path != definitions[b].Path {
+			return definitions[a].Path < definitions[b].Path
+		}
+
+		return definitions[a].Line < definitions[b].Line
+	})
+}
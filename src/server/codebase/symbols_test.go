@@ -0,0 +1,49 @@
+package codebase
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/**
+ * Tests for SymbolIndex.Lookup() and SymbolIndex.Complete().
+ */
+func TestLookup(t *testing.T) {
+
+	root := prepareCodebase(t)
+	defer os.RemoveAll(root)
+
+	ioutil.WriteFile(filepath.Join(root, "src", "User.php"), []byte("<?php\nnamespace App\\Model;\n\nclass User {\n  function save() {}\n}\n"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "index.php"), []byte("<?php\nfunction save() {}\n"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "vendor", "lib", "Lib.php"), []byte("<?php\nfunction save() {}\n"), 0644)
+
+	index := NewSymbolIndex(root, nil)
+	index.Build()
+
+	if definitions := index.Lookup("SAVE()"); len(definitions) != 2 || definitions[0].Path != "index.php" || definitions[1].Path != "src/User.php" || definitions[1].Line != 5 {
+		t.Errorf("Unexpected definitions: %v", definitions)
+	}
+
+	for _, name := range []string{"User::save", `\App\Model\User::save`, `Model\User::save`} {
+		if definitions := index.Lookup(name); len(definitions) != 1 || definitions[0].FullName != `App\Model\User::save` {
+			t.Errorf("Unexpected definitions for %s: %v", name, definitions)
+		}
+	}
+
+	if definitions := index.Lookup("Model"); len(definitions) != 0 {
+		t.Errorf("Unexpected definitions: %v", definitions)
+	}
+
+	if definitions := index.Complete("s", 10); len(definitions) != 2 || definitions[0].Name != "save" {
+		t.Errorf("Unexpected suggestions: %v", definitions)
+	}
+
+	ioutil.WriteFile(filepath.Join(root, "index.php"), []byte("<?php\nfunction load() {}\n"), 0644)
+	index.Update("index.php")
+
+	if len(index.Lookup("save")) != 1 || len(index.Lookup("load")) != 1 {
+		t.Errorf("Index has not been updated: %v", index.files)
+	}
+}
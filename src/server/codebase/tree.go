@@ -0,0 +1,171 @@
+/**
+ * @file
+ * List and walk the codebase.
+ *
+ * Paths are always relative to the codebase and slash separated.  The codebase
+ * itself is "".  Paths leading out of the codebase are refused.
+ */
+
+package codebase
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const FileType = "file"
+const DirType = "dir"
+
+/**
+ * A file or directory.
+ *
+ * Children are only present for directories whose content has been listed.
+ */
+type Entry struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Type      string    `json:"type"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mtime"`
+	IsIgnored bool      `json:"ignored,omitempty"`
+	Children  []Entry   `json:"children,omitempty"`
+}
+
+var ErrOutsideCodebase = errors.New("Path is outside the codebase.")
+
+/**
+ * List a directory.
+ *
+ * Only the directory's own content is listed.  Subdirectories come without
+ * children.  Directories come first, then files, each sorted by name.
+ * Ignored files are left out unless asked for.  These are then flagged.
+ */
+func ListDir(root, relativePath string, filter *Filter, withIgnored bool) (dir Entry, err error) {
+
+	absolutePath, relativePath, err := resolve(root, relativePath)
+	if err != nil {
+		return dir, err
+	}
+
+	dirInfo, err := os.Stat(absolutePath)
+	if err != nil {
+		return dir, err
+	} else if !dirInfo.IsDir() {
+		return dir, errors.New("Not a directory.")
+	}
+
+	dir = newEntry(relativePath, dirInfo)
+	dir.Children = []Entry{}
+
+	fileInfos, err := ioutil.ReadDir(absolutePath)
+	if err != nil {
+		return dir, err
+	}
+
+	for _, fileInfo := range fileInfos {
+		child := newEntry(path.Join(relativePath, fileInfo.Name()), fileInfo)
+		child.IsIgnored = filter.IsIgnored(child.Path, fileInfo.IsDir())
+
+		if withIgnored || !child.IsIgnored {
+			dir.Children = append(dir.Children, child)
+		}
+	}
+
+	sort.SliceStable(dir.Children, func(i, j int) bool {
+
+		if dir.Children[i].Type != dir.Children[j].Type {
+			return dir.Children[i].Type == DirType
+		}
+
+		return strings.ToLower(dir.Children[i].Name) < strings.ToLower(dir.Children[j].Name)
+	})
+
+	return dir, nil
+}
+
+/**
+ * Visit every file in the codebase that is not ignored.
+ *
+ * Ignored directories are not entered.  Returning an error from the visitor
+ * stops the walk.  That error is then returned.
+ */
+func Walk(root string, filter *Filter, visit func(relativePath string, fileInfo os.FileInfo) error) (err error) {
+
+	return walkFrom(root, root, filter, visit)
+}
+
+/**
+ * Visit every file under a directory of the codebase that is not ignored.
+ *
+ * @see Walk()
+ */
+func walkFrom(root, startDir string, filter *Filter, visit func(relativePath string, fileInfo os.FileInfo) error) (err error) {
+
+	return filepath.Walk(startDir, func(absolutePath string, fileInfo os.FileInfo, err error) error {
+
+		if err != nil || absolutePath == root {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(root, absolutePath)
+		if err != nil {
+			return nil
+		}
+
+		relativePath = filepath.ToSlash(relativePath)
+
+		if filter.IsIgnored(relativePath, fileInfo.IsDir()) {
+			if fileInfo.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if fileInfo.IsDir() {
+			return nil
+		}
+
+		return visit(relativePath, fileInfo)
+	})
+}
+
+/**
+ * Absolute path for a path inside the codebase.
+ *
+ * Also returns the cleaned up relative path.
+ */
+func resolve(root, relativePath string) (absolutePath, cleanPath string, err error) {
+
+	if strings.Contains("/"+filepath.ToSlash(relativePath)+"/", "/../") {
+		return absolutePath, cleanPath, ErrOutsideCodebase
+	}
+
+	cleanPath = strings.Trim(path.Clean("/"+filepath.ToSlash(relativePath)), "/")
+	absolutePath = filepath.Join(root, filepath.FromSlash(cleanPath))
+
+	return absolutePath, cleanPath, nil
+}
+
+func newEntry(relativePath string, fileInfo os.FileInfo) (entry Entry) {
+
+	entry = Entry{
+		Name:    fileInfo.Name(),
+		Path:    relativePath,
+		Type:    FileType,
+		Size:    fileInfo.Size(),
+		ModTime: fileInfo.ModTime(),
+	}
+
+	if fileInfo.IsDir() {
+		entry.Type = DirType
+	}
+
+	return entry
+}
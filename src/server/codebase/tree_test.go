@@ -0,0 +1,103 @@
+package codebase
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+/**
+ * Tests for ListDir().
+ */
+func TestListDir(t *testing.T) {
+
+	root := prepareCodebase(t)
+	defer os.RemoveAll(root)
+
+	filter := NewFilter(root, nil)
+
+	dir, err := ListDir(root, "", filter, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, child := range dir.Children {
+		names = append(names, child.Name)
+	}
+
+	// Directories first.  The ignored vendor directory is left out.
+	expected := []string{"src", ".gitignore", "index.php"}
+	if len(expected) != len(names) {
+		t.Fatalf("Expected %v, got %v", expected, names)
+	}
+
+	for i := range expected {
+		if expected[i] != names[i] {
+			t.Errorf("Expected %v, got %v", expected, names)
+		}
+	}
+
+	if dir.Children[0].Type != DirType || dir.Children[0].Children != nil || dir.Children[2].Size != 5 {
+		t.Errorf("Unexpected entries: %v", dir.Children)
+	}
+
+	dir, err = ListDir(root, "", filter, true)
+	if err != nil || len(dir.Children) != 4 || !dir.Children[1].IsIgnored {
+		t.Errorf("Expected the flagged vendor directory: %v, %v", dir.Children, err)
+	}
+
+	if dir, err = ListDir(root, "src/", filter, false); err != nil || dir.Path != "src" || len(dir.Children) != 1 {
+		t.Errorf("Unexpected subdirectory listing: %v, %v", dir, err)
+	}
+
+	if _, err = ListDir(root, "../", filter, false); err != ErrOutsideCodebase {
+		t.Errorf("Expected refusal, got: %v", err)
+	}
+}
+
+/**
+ * Tests for Walk().
+ */
+func TestWalk(t *testing.T) {
+
+	root := prepareCodebase(t)
+	defer os.RemoveAll(root)
+
+	paths := []string{}
+	err := Walk(root, NewFilter(root, nil), func(relativePath string, fileInfo os.FileInfo) error {
+		paths = append(paths, relativePath)
+		return nil
+	})
+	sort.Strings(paths)
+
+	expected := []string{".gitignore", "index.php", "src/App.php"}
+	if err != nil || len(expected) != len(paths) {
+		t.Fatalf("Expected %v, got %v, %v", expected, paths, err)
+	}
+
+	for i := range expected {
+		if expected[i] != paths[i] {
+			t.Errorf("Expected %v, got %v", expected, paths)
+		}
+	}
+}
+
+func prepareCodebase(t *testing.T) (root string) {
+
+	root, err := ioutil.TempDir("", "footle-codebase")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.MkdirAll(filepath.Join(root, "src"), 0755)
+	os.MkdirAll(filepath.Join(root, "vendor", "lib"), 0755)
+	ioutil.WriteFile(filepath.Join(root, ".gitignore"), []byte("vendor/\n"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "index.php"), []byte("<?php"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "src", "App.php"), []byte("<?php"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "vendor", "lib", "Lib.php"), []byte("<?php"), 0644)
+
+	return root
+}
@@ -5,7 +5,11 @@
 
 package config
 
-import "strconv"
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+)
 
 /**
  * The Config class stores command line arguments and flags.
@@ -58,6 +62,28 @@ func (c Config) DetermineCodeDir() (codeDir string) {
 	return codeDir
 }
 
+/**
+ * Turn a file URI from the DBGp engine into a local path.
+ *
+ * The engine may be in another machine where the codebase lives elsewhere.
+ * Examples:
+ *   - file:///remote-codebase/foo/bar.txt -> /codebase/foo/bar.txt
+ *   - file:///elsewhere/bar.txt -> /elsewhere/bar.txt
+ *
+ * @see DetermineCodeDir()
+ */
+func (c Config) ToLocalPath(fileUri string) (localPath string) {
+
+	enginePath := strings.TrimPrefix(fileUri, "file://")
+
+	relativePath, err := filepath.Rel(c.DetermineCodeDir(), enginePath)
+	if err != nil || strings.HasPrefix(relativePath, "..") {
+		return enginePath
+	}
+
+	return filepath.Join(c.GetCodebase(), relativePath)
+}
+
 /**
  * Getter for network port where Footle listens for HTTP requests.
  */
@@ -74,6 +100,14 @@ func (c Config) GetDBGpPort() int {
 	return c.getInt("dbgp-port")
 }
 
+/**
+ * Getter for network port to listen for Debug Adapter Protocol clients.
+ */
+func (c Config) GetDAPPort() int {
+
+	return c.getInt("dap-port")
+}
+
 /**
  * Getter for alternate HTTP UI path.
  */
@@ -82,6 +116,59 @@ func (c Config) GetUIPath() string {
 	return c.GetArg("ui-path")
 }
 
+/**
+ * Getter for the session file where DBGp traffic is recorded.
+ *
+ * Empty when there is no recording.
+ */
+func (c Config) GetRecordFile() string {
+
+	return c.GetArg("record-file")
+}
+
+/**
+ * Getter for the recorded session file to replay.
+ *
+ * Empty when there is nothing to replay.
+ */
+func (c Config) GetReplayFile() string {
+
+	return c.GetArg("replay-file")
+}
+
+/**
+ * Getter for the patterns of files to hide from the file browser.
+ *
+ * These use the .gitignore syntax.
+ */
+func (c Config) GetExcludes() (excludes []string) {
+
+	for _, exclude := range strings.Split(c.GetArg("excludes"), ",") {
+		if exclude = strings.TrimSpace(exclude); exclude != "" {
+			excludes = append(excludes, exclude)
+		}
+	}
+
+	return excludes
+}
+
+/**
+ * Getter for the character encodings of non-UTF-8 source files.
+ *
+ * Each is either ENCODING or PATTERN=ENCODING.  Patterns use the .gitignore
+ * syntax.
+ */
+func (c Config) GetEncodings() (encodings []string) {
+
+	for _, encoding := range strings.Split(c.GetArg("encodings"), ",") {
+		if encoding = strings.TrimSpace(encoding); encoding != "" {
+			encodings = append(encodings, encoding)
+		}
+	}
+
+	return encodings
+}
+
 /**
  * Return value of configuration item as an integer.
  *
@@ -115,6 +202,22 @@ func (c Config) HasHTTP() bool {
 	return c.GetFlag("has-http")
 }
 
+/**
+ * Getter for the presence of the codebase watcher.
+ */
+func (c Config) HasWatcher() bool {
+
+	return c.GetFlag("has-watcher")
+}
+
+/**
+ * Getter for the presence of the Debug Adapter Protocol interface.
+ */
+func (c Config) HasDAP() bool {
+
+	return c.GetDAPPort() > 0
+}
+
 /**
  * Predicate for determine verbosity.
  *
@@ -34,13 +34,18 @@ func Get() Config {
 	config.flags = make(map[string]bool)
 
 	// Now load the configuration passed from the command line.
-	codebase, remoteCodebase, uiPath, verbosity, httpPort, DBGpPort, hasCmdLine, hasHTTP := getFlagsAndArgs()
+	codebase, remoteCodebase, uiPath, recordFile, replayFile, excludes, encodings, verbosity, httpPort, DBGpPort, DAPPort, hasCmdLine, hasHTTP, hasWatcher := getFlagsAndArgs()
 
 	config.SetArg("codebase", codebase)
 	config.SetArg("remote-codebase", remoteCodebase)
 	config.SetArg("http-port", strconv.Itoa(httpPort))
 	config.SetArg("dbgp-port", strconv.Itoa(DBGpPort))
+	config.SetArg("dap-port", strconv.Itoa(DAPPort))
 	config.SetArg("ui-path", uiPath)
+	config.SetArg("record-file", recordFile)
+	config.SetArg("replay-file", replayFile)
+	config.SetArg("excludes", excludes)
+	config.SetArg("encodings", encodings)
 	config.SetArg("verbosity", verbosity)
 
 	if hasCmdLine {
@@ -55,6 +60,12 @@ func Get() Config {
 		config.UnsetFlag("has-http")
 	}
 
+	if hasWatcher {
+		config.SetFlag("has-watcher")
+	} else {
+		config.UnsetFlag("has-watcher")
+	}
+
 	return config
 }
 
@@ -69,23 +80,36 @@ func Get() Config {
  *    in a remote machine.
  *  - HTTP port: Network port of the HTTP interface.
  *  - DBGp port: Network port to listen for the DBGp server.
+ *  - DAP port: Network port to listen for Debug Adapter Protocol clients.
  *  - UI path: Location of the HTTP UI.
+ *  - Record file: Session file for recording DBGp traffic.
+ *  - Replay file: Recorded session file to play back as a fake DBGp engine.
+ *  - Excludes: Comma separated .gitignore style patterns to hide from the file
+ *    browser.
+ *  - Encodings: Comma separated character encodings of non-UTF-8 source files.
  *
  * Flag:
  *  - cli: We want the command line.
  *  - nohttp : No HTTP.
+ *  - nowatch : Do not watch the codebase for file changes.
  *  - v, vv, vvv: Verbosity level.
  */
-func getFlagsAndArgs() (codebase, remoteCodebase, uiPath, verbosity string, httpPort, DBGpPort int, hasCmdLine, hasHTTP bool) {
+func getFlagsAndArgs() (codebase, remoteCodebase, uiPath, recordFile, replayFile, excludes, encodings, verbosity string, httpPort, DBGpPort, DAPPort int, hasCmdLine, hasHTTP, hasWatcher bool) {
 
 	codebaseArg := flag.String("codebase", "", "[Optional] Path of directory whose code you want to debug; e.g. /var/www/html/ (default is current dir)")
 	remoteCodebaseArg := flag.String("codebase-remote", "", "[Optional] When Footle and the DBGp server (e.g. xdebug) are in different machines, this is the path of the source code directory in the remote machine.  This scenario is *not* recommended.  Try as a last resort.  Footle assumes that a copy of the source code is present in the local machine.  To tell Footle where this local copy is, either run footle from inside that copy or use the -codebase option.")
 	DBGpPortArg := flag.Int("port-dbgp", 9000, "[Optional] Network port to listen for the DBGp server.")
 	httpPortArg := flag.Int("port-http", 1234, "[Optional] Network port for Footle's Web interface.")
+	DAPPortArg := flag.Int("port-dap", 0, "[Optional] Network port for Debug Adapter Protocol clients such as VS Code.  The DAP interface is off unless a port is given.")
 	uiPathArg := flag.String("ui-path", "", "[Optional] Location of an alternate HTTP UI.  Only relevant during UI development.")
+	replayFileArg := flag.String("replay", "", "[Optional] Play back a session file recorded with -record.  A fake DBGp engine then answers Footle's commands.  No PHP needed.")
+	recordFileArg := flag.String("record", "", "[Optional] Record all DBGp traffic to this session file.  Useful for bug reports.")
+	excludesArg := flag.String("exclude", "", "[Optional] Comma separated list of files and directories to hide from the file browser, in addition to those in .gitignore files; e.g. vendor/,*.min.js")
+	encodingsArg := flag.String("encoding", "", "[Optional] Comma separated character encodings of source files that are not in UTF-8.  Either a single encoding for all such files or PATTERN=ENCODING pairs; e.g. iso-8859-1 or legacy/=iso-8859-1,*.inc=windows-1252 (default is windows-1252 for any file that is not valid UTF-8)")
 
 	hasCmdLineFlag := flag.Bool("cli", false, "[Optional] Launch command line debugger.")
 	noHTTPFlag := flag.Bool("nohttp", false, "[Optional] Do *not* launch HTTP interface of the debugger.")
+	noWatchFlag := flag.Bool("nowatch", false, "[Optional] Do *not* watch the codebase for file changes.  Open files are then not reloaded when saved.")
 
 	LowVerbosityFlag := flag.Bool("v", false, "[Optional] Low verbosity.  Unused.")
 	MediumVerbosityFlag := flag.Bool("vv", false, "[Optional] Medium verbosity.  Unused.")
@@ -97,9 +121,15 @@ func getFlagsAndArgs() (codebase, remoteCodebase, uiPath, verbosity string, http
 	remoteCodebase = *remoteCodebaseArg
 	httpPort = *httpPortArg
 	DBGpPort = *DBGpPortArg
+	DAPPort = *DAPPortArg
 	uiPath = *uiPathArg
+	recordFile = *recordFileArg
+	replayFile = *replayFileArg
+	excludes = *excludesArg
+	encodings = *encodingsArg
 	hasCmdLine = *hasCmdLineFlag
 	hasHTTP = !*noHTTPFlag
+	hasWatcher = !*noWatchFlag
 
 	if codebase == "" {
 		currentDir, err := os.Getwd()
@@ -0,0 +1,373 @@
+/**
+ * Keep breakpoints on their statements when source files change.
+ *
+ * Editing a file above a breakpoint shifts the breakpoint's statement up or
+ * down.  So we keep a snapshot of each file that has breakpoints.  Each
+ * breakpoint also carries a fingerprint: the lines around it.  When a file
+ * changes, a line diff between the snapshot and the new content tells where
+ * unchanged lines have gone.  A breakpoint on a changed line is placed relative
+ * to its nearest unchanged neighbour and then nudged towards the best match for
+ * its fingerprint.
+ */
+
+package breakpoint
+
+import (
+	"io/ioutil"
+	"log"
+	"server/config"
+	"server/dbgp/command"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Number of lines on either side of a breakpoint in its fingerprint.
+ */
+const fingerprintRadius = 2
+
+/**
+ * How far a breakpoint on a changed line may be nudged to match its
+ * fingerprint.
+ */
+const searchRadius = 5
+
+/**
+ * Beyond this size, the changed part of a file is not diffed line by line.
+ *
+ * The size is the product of the number of changed lines before and after.
+ */
+const maxDiffSize = 4000000
+
+/**
+ * File content at the time its breakpoints were last placed.
+ *
+ * Key: File URI
+ * Value: Lines of the file.
+ */
+var snapshots = make(map[string][]string)
+
+/**
+ * Move breakpoints of a changed file to where their statements are now.
+ *
+ * Both pending and established breakpoints are moved.  During a debugging
+ * session, moved breakpoints are removed from the DBGp engine and set again.
+ *
+ * Returns whether any breakpoint has moved.
+ */
+func Reanchor(fileUri string, DBGpCmds chan string, isSessionActive bool) (hasMoved bool) {
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	return reanchor(fileUri, DBGpCmds, isSessionActive)
+}
+
+/**
+ * @see Reanchor()
+ */
+func reanchor(fileUri string, DBGpCmds chan string, isSessionActive bool) (hasMoved bool) {
+
+	oldLines, hasSnapshot := snapshots[fileUri]
+	if !hasSnapshot {
+		return false
+	}
+
+	newLines, err := readLines(fileUri)
+	if err != nil {
+		return false
+	}
+
+	lineMap := matchLines(oldLines, newLines)
+
+	for i := range pending {
+		if pending[i].Filename == fileUri && reanchorBreakpoint(&pending[i], lineMap, newLines) {
+			hasMoved = true
+		}
+	}
+
+	for _, breakpointRecord := range established {
+		if breakpointRecord.Filename != fileUri || !reanchorBreakpoint(breakpointRecord, lineMap, newLines) {
+			continue
+		}
+
+		hasMoved = true
+
+		if isSessionActive {
+			resetAtEngine(*breakpointRecord, DBGpCmds)
+		}
+	}
+
+	snapshots[fileUri] = newLines
+
+	return hasMoved
+}
+
+/**
+ * Reanchor breakpoints in all files with breakpoints.
+ *
+ * Useful before a debugging session starts as files may have changed since
+ * their breakpoints were set.
+ */
+func reanchorAll() {
+
+	for fileUri := range snapshots {
+		reanchor(fileUri, nil, false)
+	}
+}
+
+/**
+ * Take a snapshot of the breakpoint's file if we have none and record the
+ * breakpoint's fingerprint.
+ */
+func anchor(b *breakpoint) {
+
+	if _, hasSnapshot := snapshots[b.Filename]; !hasSnapshot {
+		if lines, err := readLines(b.Filename); err == nil {
+			snapshots[b.Filename] = lines
+		}
+	}
+
+	b.Fingerprint = takeFingerprint(snapshots[b.Filename], b.LineNo)
+}
+
+/**
+ * Drop snapshots of files without any breakpoint.
+ */
+func forgetUnusedSnapshots() {
+
+	for fileUri := range snapshots {
+		isUsed := false
+
+		for _, breakpointRecord := range pending {
+			isUsed = isUsed || breakpointRecord.Filename == fileUri
+		}
+
+		for _, breakpointRecord := range established {
+			isUsed = isUsed || breakpointRecord.Filename == fileUri
+		}
+
+		if !isUsed {
+			delete(snapshots, fileUri)
+		}
+	}
+}
+
+/**
+ * Move a breakpoint according to the line diff.
+ *
+ * Returns whether the breakpoint has moved.
+ */
+func reanchorBreakpoint(b *breakpoint, lineMap map[int]int, newLines []string) (hasMoved bool) {
+
+	newLineNo := reanchorLine(b.LineNo, b.Fingerprint, lineMap, newLines)
+	hasMoved = (newLineNo != b.LineNo)
+
+	b.LineNo = newLineNo
+	b.Fingerprint = takeFingerprint(newLines, newLineNo)
+
+	return hasMoved
+}
+
+/**
+ * New line number for an old line.
+ *
+ * Unchanged lines go wherever the diff says.  Changed or deleted lines are
+ * placed relative to the nearest unchanged line above, or failing that below.
+ * Then the fingerprint is matched against the lines nearby.
+ */
+func reanchorLine(lineNo int, fingerprint []string, lineMap map[int]int, newLines []string) int {
+
+	if newLineNo, isUnchanged := lineMap[lineNo]; isUnchanged {
+		return newLineNo
+	}
+
+	above, below := 0, 0
+	for oldLineNo := range lineMap {
+		if oldLineNo < lineNo && oldLineNo > above {
+			above = oldLineNo
+		} else if oldLineNo > lineNo && (below == 0 || oldLineNo < below) {
+			below = oldLineNo
+		}
+	}
+
+	estimate := lineNo
+	if above > 0 {
+		estimate = lineMap[above] + (lineNo - above)
+	} else if below > 0 {
+		estimate = lineMap[below] - (below - lineNo)
+	}
+
+	estimate = clamp(estimate, 1, len(newLines))
+
+	bestLineNo, bestScore := estimate, scoreFingerprint(fingerprint, newLines, estimate)
+	for distance := 1; distance <= searchRadius; distance++ {
+		for _, candidate := range []int{estimate - distance, estimate + distance} {
+			if candidate < 1 || candidate > len(newLines) {
+				continue
+			}
+
+			if score := scoreFingerprint(fingerprint, newLines, candidate); score > bestScore {
+				bestLineNo, bestScore = candidate, score
+			}
+		}
+	}
+
+	return bestLineNo
+}
+
+/**
+ * The lines around the given line.
+ *
+ * Lines beyond either end of the file are empty.  Indentation is ignored.
+ */
+func takeFingerprint(lines []string, lineNo int) (fingerprint []string) {
+
+	for i := lineNo - fingerprintRadius; i <= lineNo+fingerprintRadius; i++ {
+		line := ""
+		if i >= 1 && i <= len(lines) {
+			line = strings.TrimSpace(lines[i-1])
+		}
+
+		fingerprint = append(fingerprint, line)
+	}
+
+	return fingerprint
+}
+
+/**
+ * How well does a fingerprint match the lines around the given line?
+ *
+ * The breakpoint's own line counts double.
+ */
+func scoreFingerprint(fingerprint []string, lines []string, lineNo int) (score int) {
+
+	candidate := takeFingerprint(lines, lineNo)
+
+	for i := range fingerprint {
+		if i >= len(candidate) || fingerprint[i] != candidate[i] {
+			continue
+		}
+
+		score++
+		if i == fingerprintRadius {
+			score++
+		}
+	}
+
+	return score
+}
+
+/**
+ * Line diff.
+ *
+ * Finds the longest common subsequence of lines.  Returns the new line number
+ * for each unchanged old line.  Line numbers start at 1.
+ *
+ * Edits tend to be small.  So the common lines at the start and end are
+ * matched first.  Only the rest is diffed properly.
+ */
+func matchLines(oldLines, newLines []string) (lineMap map[int]int) {
+
+	lineMap = make(map[int]int)
+
+	prefixLen := 0
+	for prefixLen < len(oldLines) && prefixLen < len(newLines) && oldLines[prefixLen] == newLines[prefixLen] {
+		prefixLen++
+		lineMap[prefixLen] = prefixLen
+	}
+
+	suffixLen := 0
+	for suffixLen < len(oldLines)-prefixLen && suffixLen < len(newLines)-prefixLen && oldLines[len(oldLines)-1-suffixLen] == newLines[len(newLines)-1-suffixLen] {
+		lineMap[len(oldLines)-suffixLen] = len(newLines) - suffixLen
+		suffixLen++
+	}
+
+	oldMiddle := oldLines[prefixLen : len(oldLines)-suffixLen]
+	newMiddle := newLines[prefixLen : len(newLines)-suffixLen]
+
+	if len(oldMiddle)*len(newMiddle) > maxDiffSize {
+		return lineMap
+	}
+
+	// commonLen[i][j]: Length of the longest common subsequence of
+	// oldMiddle[i:] and newMiddle[j:].
+	commonLen := make([][]int32, len(oldMiddle)+1)
+	for i := range commonLen {
+		commonLen[i] = make([]int32, len(newMiddle)+1)
+	}
+
+	for i := len(oldMiddle) - 1; i >= 0; i-- {
+		for j := len(newMiddle) - 1; j >= 0; j-- {
+			if oldMiddle[i] == newMiddle[j] {
+				commonLen[i][j] = commonLen[i+1][j+1] + 1
+			} else if commonLen[i+1][j] >= commonLen[i][j+1] {
+				commonLen[i][j] = commonLen[i+1][j]
+			} else {
+				commonLen[i][j] = commonLen[i][j+1]
+			}
+		}
+	}
+
+	for i, j := 0, 0; i < len(oldMiddle) && j < len(newMiddle); {
+		if oldMiddle[i] == newMiddle[j] {
+			lineMap[prefixLen+i+1] = prefixLen + j + 1
+			i++
+			j++
+		} else if commonLen[i+1][j] >= commonLen[i][j+1] {
+			i++
+		} else {
+			j++
+		}
+	}
+
+	return lineMap
+}
+
+/**
+ * Remove a moved breakpoint from the DBGp engine and set it again.
+ *
+ * The engine's responses lead to a fresh breakpoint listing.
+ */
+func resetAtEngine(b breakpoint, DBGpCmds chan string) {
+
+	removeCmd, err := command.Prepare("breakpoint_remove", []string{strconv.Itoa(b.DBGpId)})
+	if err != nil {
+		return
+	}
+
+	setCmd, err := command.Prepare("breakpoint_set", []string{b.Filename, strconv.Itoa(b.LineNo)})
+	if err != nil {
+		return
+	}
+
+	DBGpCmds <- removeCmd
+	DBGpCmds <- setCmd
+}
+
+/**
+ * Read the local copy of a file into its lines.
+ */
+func readLines(fileUri string) (lines []string, err error) {
+
+	src, err := ioutil.ReadFile(config.Get().ToLocalPath(fileUri))
+	if err != nil {
+		log.Printf("Cannot keep track of breakpoints: %v", err)
+		return lines, err
+	}
+
+	return strings.Split(string(src), "\n"), nil
+}
+
+func clamp(n, min, max int) int {
+
+	if n > max {
+		n = max
+	}
+
+	if n < min {
+		n = min
+	}
+
+	return n
+}
@@ -0,0 +1,140 @@
+package breakpoint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"server/dbgp/message"
+	"strings"
+	"sync"
+	"testing"
+)
+
+/**
+ * Tests for matchLines().
+ */
+func TestMatchLines(t *testing.T) {
+
+	oldLines := []string{"a", "b", "c", "d", "e"}
+	newLines := []string{"a", "x", "y", "b", "d", "e"}
+
+	lineMap := matchLines(oldLines, newLines)
+
+	expected := map[int]int{1: 1, 2: 4, 4: 5, 5: 6}
+	if len(expected) != len(lineMap) {
+		t.Fatalf("Expected %v, got %v", expected, lineMap)
+	}
+
+	for oldLineNo, newLineNo := range expected {
+		if lineMap[oldLineNo] != newLineNo {
+			t.Errorf("Expected line %d to become %d, got %v", oldLineNo, newLineNo, lineMap)
+		}
+	}
+}
+
+/**
+ * Tests for Reanchor().
+ *
+ * Breakpoints should stay with their statements when lines are added above
+ * them or when their own line is edited.
+ */
+func TestReanchor(t *testing.T) {
+
+	tmpDir, err := ioutil.TempDir("", "footle-breakpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	defer func() {
+		pending = nil
+		established.Empty()
+		snapshots = make(map[string][]string)
+	}()
+
+	filename := filepath.Join(tmpDir, "foo.php")
+	fileUri := "file://" + filename
+
+	oldSrc := []string{"<?php", "$a = 1;", "$b = 2;", "$c = 3;", "$d = 4;"}
+	ioutil.WriteFile(filename, []byte(strings.Join(oldSrc, "\n")), 0644)
+
+	Enqueue(Line_type_breakpoint, fileUri, "3")
+	established.AddLine(fileUri, 5, 7, true)
+	anchor(established[7])
+
+	newSrc := []string{"<?php", "// New comment.", "", "$a = 1;", "$b = 20;", "$c = 3;", "$d = 4;"}
+	ioutil.WriteFile(filename, []byte(strings.Join(newSrc, "\n")), 0644)
+
+	DBGpCmds := make(chan string, 4)
+
+	if !Reanchor(fileUri, DBGpCmds, true) {
+		t.Fatal("Breakpoints have not moved.")
+	}
+
+	if 5 != pending[0].LineNo {
+		t.Errorf("Expected the edited line's breakpoint on line 5, got %d", pending[0].LineNo)
+	}
+
+	if 7 != established[7].LineNo {
+		t.Errorf("Expected the established breakpoint on line 7, got %d", established[7].LineNo)
+	}
+
+	// The established breakpoint is moved at the engine.
+	if 2 != len(DBGpCmds) || !strings.HasPrefix(<-DBGpCmds, "breakpoint_remove") || !strings.Contains(<-DBGpCmds, "-n 7") {
+		t.Error("The established breakpoint has not been set again at the engine.")
+	}
+
+	if Reanchor(fileUri, DBGpCmds, true) {
+		t.Error("Breakpoints have moved without any change to the file.")
+	}
+}
+
+/**
+ * Breakpoints should survive changes from several goroutines at once.
+ *
+ * Meant for "go test -race".
+ */
+func TestConcurrentChanges(t *testing.T) {
+
+	tmpDir, err := ioutil.TempDir("", "footle-breakpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	defer func() {
+		pending = nil
+		established.Empty()
+		snapshots = make(map[string][]string)
+	}()
+
+	filename := filepath.Join(tmpDir, "foo.php")
+	fileUri := "file://" + filename
+	ioutil.WriteFile(filename, []byte("<?php\n$a = 1;\n$b = 2;\n"), 0644)
+
+	var wait sync.WaitGroup
+	wait.Add(2)
+
+	// Like the goroutine processing UI commands.
+	go func() {
+		defer wait.Done()
+
+		for i := 0; i < 50; i++ {
+			Enqueue(Line_type_breakpoint, fileUri, "2")
+			Reanchor(fileUri, nil, false)
+			PrepareFakeMsg()
+		}
+	}()
+
+	// Like the goroutine processing DBGp messages.
+	go func() {
+		defer wait.Done()
+
+		for i := 0; i < 50; i++ {
+			RenewList(map[int]message.Breakpoint{i: {Type: Line_type_breakpoint, Filename: fileUri, LineNo: 3, Id: i, State: BreakpointEnabledState}})
+			Delete(i)
+		}
+	}()
+
+	wait.Wait()
+}
@@ -0,0 +1,37 @@
+/**
+ * Breakpoint placement.
+ *
+ * Xdebug ignores or moves breakpoints on lines that have nothing to execute,
+ * e.g. blank lines, comments, or the middle of a multi-line array.  So we move
+ * such breakpoints to the next line that can carry one before the engine gets
+ * to see them.
+ */
+
+package breakpoint
+
+import (
+	"io/ioutil"
+	"log"
+	"server/config"
+	"server/php"
+)
+
+/**
+ * The line where a breakpoint requested for the given line should go.
+ *
+ * This is the next executable line.  The requested line is left alone when the
+ * file is unreadable or has no executable line at or after it.  The local copy
+ * of the file is consulted.
+ */
+func snapToExecutableLine(fileUri string, lineNo int) int {
+
+	src, err := ioutil.ReadFile(config.Get().ToLocalPath(fileUri))
+	if err != nil {
+		log.Printf("Cannot check breakpoint placement: %v", err)
+		return lineNo
+	}
+
+	executableLines := php.ExecutableLines(php.Tokenize(string(src)))
+
+	return php.NextExecutableLine(executableLines, lineNo)
+}
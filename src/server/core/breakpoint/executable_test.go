@@ -0,0 +1,67 @@
+package breakpoint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"server/config"
+	"testing"
+)
+
+/**
+ * Tests for moving pending breakpoints to executable lines.
+ */
+func TestEnqueueSnapsToExecutableLine(t *testing.T) {
+
+	tmpDir, err := ioutil.TempDir("", "footle-breakpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	filename := filepath.Join(tmpDir, "foo.php")
+	ioutil.WriteFile(filename, []byte("<?php\n\n// Comment.\n$foo = [\n  1,\n];\n"), 0644)
+	fileUri := "file://" + filename
+
+	defer func() { pending = nil }()
+
+	cases := map[string]int{"2": 4, "4": 4, "5": 5, "9": 9}
+	for requestedLineNo, expected := range cases {
+		lineNo, err := Enqueue(Line_type_breakpoint, fileUri, requestedLineNo)
+		if err != nil || expected != lineNo {
+			t.Errorf("Expected line %d for line %s, got %d, %v", expected, requestedLineNo, lineNo, err)
+		}
+	}
+
+	if _, err := Enqueue(Line_type_breakpoint, fileUri, "foo"); err == nil {
+		t.Error("Accepted an invalid line number.")
+	}
+}
+
+/**
+ * Breakpoints in a remote codebase should be placed using the local copy.
+ */
+func TestSnapToExecutableLineWithRemoteCodebase(t *testing.T) {
+
+	tmpDir, err := ioutil.TempDir("", "footle-breakpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ioutil.WriteFile(filepath.Join(tmpDir, "foo.php"), []byte("<?php\n\n// Comment.\n$foo = 1;\n"), 0644)
+
+	conf := config.Get()
+	codebase, remoteCodebase := conf.GetCodebase(), conf.GetRemoteCodebase()
+	defer func() {
+		conf.SetArg("codebase", codebase)
+		conf.SetArg("remote-codebase", remoteCodebase)
+	}()
+
+	conf.SetArg("codebase", tmpDir)
+	conf.SetArg("remote-codebase", "/var/www")
+
+	if lineNo := snapToExecutableLine("file:///var/www/foo.php", 2); lineNo != 4 {
+		t.Errorf("Expected line 4, got %d", lineNo)
+	}
+}
@@ -7,12 +7,13 @@ const Line_type_breakpoint = "line"
 const Code_type_breakpoint = "code"
 
 type breakpoint struct {
-	Type     string
-	State    bool
-	LineNo   int
-	Filename string
-	Code     string
-	DBGpId   int
+	Type        string
+	State       bool
+	LineNo      int
+	Filename    string
+	Code        string
+	DBGpId      int
+	Fingerprint []string // Lines around the breakpoint.  @see drift.go
 }
 
 type breakpointList map[int]*breakpoint
@@ -5,7 +5,7 @@
 package breakpoint
 
 import (
-	"log"
+	"fmt"
 	"server/dbgp/message"
 	"strconv"
 )
@@ -26,6 +26,9 @@ var lastPendingBreakpointId int = 0
  */
 func ListAllBreakpoints() (breakpoints map[int]message.Breakpoint) {
 
+	lock.Lock()
+	defer lock.Unlock()
+
 	breakpoints = make(map[int]message.Breakpoint)
 
 	for k, breakpointRecord := range pending {
@@ -49,6 +52,29 @@ func ListAllBreakpoints() (breakpoints map[int]message.Breakpoint) {
 	return breakpoints
 }
 
+/**
+ * Is there a pending or established breakpoint in the given file?
+ */
+func IsSetIn(fileUri string) bool {
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	for _, breakpointRecord := range pending {
+		if breakpointRecord.Filename == fileUri {
+			return true
+		}
+	}
+
+	for _, breakpointRecord := range established {
+		if breakpointRecord.Filename == fileUri {
+			return true
+		}
+	}
+
+	return false
+}
+
 /**
  * Renew breakpoint list.
  *
@@ -56,11 +82,31 @@ func ListAllBreakpoints() (breakpoints map[int]message.Breakpoint) {
  */
 func RenewList(breakpoints map[int]message.Breakpoint) {
 
+	lock.Lock()
+	defer lock.Unlock()
+
+	previous := make(map[int]breakpoint)
+	for id, breakpointRecord := range established {
+		previous[id] = *breakpointRecord
+	}
+
 	established.Empty()
 
 	for _, v := range breakpoints {
 		add(v.Type, v.Filename, v.LineNo, v.Id, v.State)
 	}
+
+	// Fingerprints of known breakpoints are kept as the file may have changed
+	// since they were taken.
+	for id, breakpointRecord := range established {
+		if previousRecord, isKnown := previous[id]; isKnown && previousRecord.LineNo == breakpointRecord.LineNo {
+			breakpointRecord.Fingerprint = previousRecord.Fingerprint
+		} else {
+			anchor(breakpointRecord)
+		}
+	}
+
+	forgetUnusedSnapshots()
 }
 
 /**
@@ -68,19 +114,30 @@ func RenewList(breakpoints map[int]message.Breakpoint) {
  */
 func Delete(breakpointId int) {
 
+	lock.Lock()
+	defer lock.Unlock()
+
 	delete(established, breakpointId)
+	forgetUnusedSnapshots()
 }
 
 /**
  * Add a *pending* breakpoint record.
  *
- * Only deals with line breakpoints at the moment.
+ * Only deals with line breakpoints at the moment.  Line breakpoints are moved
+ * to the next executable line.  Returns the line where the breakpoint has
+ * ended up.
  */
-func Enqueue(breakpointType, arg0, arg1 string) {
+func Enqueue(breakpointType, arg0, arg1 string) (lineNo int, err error) {
+
+	lock.Lock()
+	defer lock.Unlock()
 
 	if breakpointType == Line_type_breakpoint {
-		enqueueLine(arg0, arg1)
+		lineNo, err = enqueueLine(arg0, arg1)
 	}
+
+	return lineNo, err
 }
 
 /**
@@ -100,16 +157,19 @@ func add(breakpointType, filename string, lineNo, id int, state string) {
 
 /**
  * Add a pending breakpoint record for a source code line.
+ *
+ * @see snapToExecutableLine()
  */
-func enqueueLine(filename, lineNoArg string) {
-
-	lineNo, err := strconv.Atoi(lineNoArg)
+func enqueueLine(filename, lineNoArg string) (lineNo int, err error) {
 
-	if filename == "" || err != nil {
-		log.Println(err)
-		return
+	lineNo, err = strconv.Atoi(lineNoArg)
+	if err != nil {
+		return lineNo, err
+	} else if filename == "" {
+		return lineNo, fmt.Errorf("Breakpoint without a filename.")
 	}
 
+	lineNo = snapToExecutableLine(filename, lineNo)
 	pendingBreakpointId := getNewId()
 
 	b := breakpoint{
@@ -120,7 +180,10 @@ func enqueueLine(filename, lineNoArg string) {
 		State:    true,
 	}
 
+	anchor(&b)
 	pending.push(b)
+
+	return lineNo, nil
 }
 
 /**
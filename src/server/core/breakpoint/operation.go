@@ -8,11 +8,21 @@ import (
 	"server/dbgp/command"
 	"server/dbgp/message"
 	"strconv"
+	"sync"
 )
 
 var established breakpointList = make(breakpointList)
 var pending Queue
 
+/**
+ * Guards all breakpoint records, file snapshots, and temporary breakpoints.
+ *
+ * Breakpoints change from both the goroutine processing UI commands and the
+ * one processing DBGp messages.  Every exported function takes this lock.
+ * Unexported functions expect it to be held already.
+ */
+var lock sync.Mutex
+
 /**
  * Send breakpoint creation commands for queued breakpoints.
  *
@@ -22,6 +32,12 @@ var pending Queue
  */
 func SendPending(DBGpCmds chan string) {
 
+	lock.Lock()
+	defer lock.Unlock()
+
+	// Source files may have changed since the last session.
+	reanchorAll()
+
 	// As well as pending breakpoints, breakpoints from the previous session have
 	// to be set again.
 	for _, v := range established {
@@ -48,6 +64,9 @@ func SendPending(DBGpCmds chan string) {
  */
 func RemovePending(breakpointId string) (err error) {
 
+	lock.Lock()
+	defer lock.Unlock()
+
 	breakpointIdNum, err := strconv.Atoi(breakpointId)
 
 	if err != nil {
@@ -68,6 +87,8 @@ func RemovePending(breakpointId string) (err error) {
 		delete(established, breakpointIdNum)
 	}
 
+	forgetUnusedSnapshots()
+
 	return err
 }
 
@@ -91,6 +112,9 @@ func BroadcastPending(DBGpMessages chan message.Message) {
  */
 func PrepareFakeMsg() (msg message.Message) {
 
+	lock.Lock()
+	defer lock.Unlock()
+
 	fakeMsg := FakeMessage{}
 	fakeMsg.init("breakpoint_list")
 	fakeMsg.AddExistingBreakpoints(established)
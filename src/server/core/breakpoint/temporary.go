@@ -0,0 +1,162 @@
+/**
+ * Temporary breakpoints.
+ *
+ * A temporary breakpoint is set by the "run_to" command.  It lives until the
+ * next break, wherever that break happens to be.  Temporary breakpoints are
+ * kept apart from the pending and established breakpoint lists so that they
+ * are never displayed as regular breakpoints or carried over to the next
+ * debugging session.
+ */
+
+package breakpoint
+
+import (
+	"server/dbgp/command"
+	"server/dbgp/message"
+	"strconv"
+)
+
+type temporaryBreakpoint struct {
+	Filename string
+	LineNo   int
+	TxId     int // Transaction ID of the breakpoint_set command.
+	DBGpId   int // Assigned by the DBGp engine in its response.
+}
+
+/**
+ * The temporary breakpoint waiting for the next break.
+ */
+var temporary *temporaryBreakpoint
+
+/**
+ * DBGp engine assigned IDs of temporary breakpoints.
+ *
+ * A temporary breakpoint may still be present in the DBGp engine's breakpoint
+ * listing for a short while after we have asked for its removal.  So we keep
+ * its ID until the engine stops listing it.
+ */
+var temporaryIds map[int]bool = make(map[int]bool)
+
+/**
+ * Ask the DBGp engine to create a temporary breakpoint.
+ *
+ * Any earlier temporary breakpoint is forgotten.  It is removed at the next
+ * break anyway.
+ */
+func SetTemporary(fileUri, lineNo string, DBGpCmds chan string) (err error) {
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	cmd, TxId, err := command.PrepareTemporaryBreakpoint(fileUri, lineNo)
+	if err != nil {
+		return err
+	}
+
+	lineNoNum, _ := strconv.Atoi(lineNo)
+	temporary = &temporaryBreakpoint{
+		Filename: fileUri,
+		LineNo:   lineNoNum,
+		TxId:     TxId,
+	}
+
+	DBGpCmds <- cmd
+
+	return err
+}
+
+/**
+ * Note down the ID of the temporary breakpoint.
+ *
+ * The DBGp engine assigns this ID in its response to our breakpoint_set
+ * command.  Returns true when the given message is that response.
+ */
+func RecordTemporaryId(msg message.Message) (isTemporary bool) {
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if temporary == nil || msg.Properties.Command != "breakpoint_set" || msg.Properties.TxId != temporary.TxId {
+		return false
+	}
+
+	temporary.DBGpId = msg.Properties.BreakpointId
+	temporaryIds[temporary.DBGpId] = true
+
+	return true
+}
+
+/**
+ * Remove the temporary breakpoint at a break.
+ *
+ * The DBGp engine may only disable a temporary breakpoint after its first hit
+ * rather than deleting it.  So we always ask for its removal.
+ */
+func RemoveTemporary(DBGpCmds chan string) {
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if temporary == nil {
+		return
+	}
+
+	hasEngineAssignedId := (temporary.DBGpId != 0)
+	if hasEngineAssignedId {
+		cmd, err := command.Prepare("breakpoint_remove", []string{strconv.Itoa(temporary.DBGpId)})
+
+		if err == nil {
+			DBGpCmds <- cmd
+		}
+	}
+
+	temporary = nil
+}
+
+/**
+ * Forget all about temporary breakpoints.
+ *
+ * Useful at the start and end of debugging sessions.
+ */
+func ForgetTemporary() {
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	temporary = nil
+
+	for id := range temporaryIds {
+		delete(temporaryIds, id)
+	}
+}
+
+/**
+ * Filter out temporary breakpoints from a breakpoint listing.
+ *
+ * Temporary breakpoints that are absent from the listing are gone from the
+ * DBGp engine too.  So we stop keeping track of them.
+ */
+func WithoutTemporary(breakpoints map[int]message.Breakpoint) (filtered map[int]message.Breakpoint) {
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	filtered = make(map[int]message.Breakpoint)
+
+	for id, breakpointRecord := range breakpoints {
+		if !temporaryIds[id] {
+			filtered[id] = breakpointRecord
+		}
+	}
+
+	for id := range temporaryIds {
+		_, isListed := breakpoints[id]
+		isAwaitingBreak := (temporary != nil && temporary.DBGpId == id)
+
+		if !isListed && !isAwaitingBreak {
+			delete(temporaryIds, id)
+		}
+	}
+
+	return filtered
+}
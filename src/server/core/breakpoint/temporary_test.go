@@ -0,0 +1,61 @@
+package breakpoint
+
+import (
+	"server/dbgp/message"
+	"testing"
+)
+
+/**
+ * Tests for keeping temporary breakpoints out of breakpoint listings.
+ */
+func TestWithoutTemporary(t *testing.T) {
+
+	defer ForgetTemporary()
+
+	DBGpCmds := make(chan string, 2)
+
+	if err := SetTemporary("file:///foo/bar.php", "12", DBGpCmds); err != nil {
+		t.Fatal(err)
+	}
+	<-DBGpCmds
+
+	// The engine's response to an unrelated breakpoint_set command.
+	unrelatedResponse := message.Message{}
+	unrelatedResponse.Properties = message.Properties{Command: "breakpoint_set", TxId: temporary.TxId + 1, BreakpointId: 41}
+	if RecordTemporaryId(unrelatedResponse) {
+		t.Error("Mistook an unrelated breakpoint_set response for the temporary breakpoint.")
+	}
+
+	response := message.Message{}
+	response.Properties = message.Properties{Command: "breakpoint_set", TxId: temporary.TxId, BreakpointId: 42}
+	if !RecordTemporaryId(response) {
+		t.Error("Failed to spot the response for the temporary breakpoint.")
+	}
+
+	listing := map[int]message.Breakpoint{
+		41: message.Breakpoint{Filename: "file:///foo/qux.php", LineNo: 3, Id: 41},
+		42: message.Breakpoint{Filename: "file:///foo/bar.php", LineNo: 12, Id: 42},
+	}
+
+	filtered := WithoutTemporary(listing)
+	if _, exists := filtered[42]; exists {
+		t.Error("Temporary breakpoint has leaked into the breakpoint listing.")
+	}
+
+	if _, exists := filtered[41]; !exists {
+		t.Error("Regular breakpoint has gone missing from the breakpoint listing.")
+	}
+
+	// At the break, the temporary breakpoint should be removed.
+	RemoveTemporary(DBGpCmds)
+
+	expectedCmd := "breakpoint_remove -i "
+	if cmd := <-DBGpCmds; cmd[:len(expectedCmd)] != expectedCmd {
+		t.Errorf("Expected breakpoint_remove command, got %q.", cmd)
+	}
+
+	// Even if the engine keeps listing it for a while.
+	if _, exists := WithoutTemporary(listing)[42]; exists {
+		t.Error("Removed temporary breakpoint has leaked into the breakpoint listing.")
+	}
+}
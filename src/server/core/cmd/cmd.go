@@ -12,6 +12,7 @@ package cmd
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -23,7 +24,10 @@ func Is(cmdName string) (result bool) {
 	result = cmdName == "on" ||
 		cmdName == "off" ||
 		cmdName == "continue" ||
-		cmdName == "update_source"
+		cmdName == "update_source" ||
+		cmdName == "run_to" ||
+		cmdName == "record" ||
+		cmdName == "stop_recording"
 
 	return result
 }
@@ -36,10 +40,13 @@ func Validate(cmdName string, args []string) (err error) {
 	argCount := len(args)
 	valid := false
 
-	if (cmdName == "on" || cmdName == "off" || cmdName == "continue") && argCount == 0 {
+	if (cmdName == "on" || cmdName == "off" || cmdName == "continue" || cmdName == "stop_recording") && argCount == 0 {
 		valid = true
-	} else if cmdName == "update_source" && argCount == 1 {
+	} else if (cmdName == "update_source" || cmdName == "record") && argCount == 1 {
 		valid = true
+	} else if cmdName == "run_to" && argCount == 2 {
+		lineNo, err := strconv.Atoi(args[1])
+		valid = (err == nil && lineNo > 0)
 	}
 
 	if valid {
@@ -48,10 +55,14 @@ func Validate(cmdName string, args []string) (err error) {
 
 	cmd := cmdName + strings.Join(args, " ")
 
-	if cmdName == "on" || cmdName == "off" || cmdName == "continue" {
+	if cmdName == "on" || cmdName == "off" || cmdName == "continue" || cmdName == "stop_recording" {
 		err = fmt.Errorf("Invalid command: %s; The right format is: %s", cmd, cmdName)
 	} else if cmdName == "update_source" {
 		err = fmt.Errorf("Invalid command: %s; The right format is: update_source FILENAME", cmd)
+	} else if cmdName == "record" {
+		err = fmt.Errorf("Invalid command: %s; The right format is: record FILENAME", cmd)
+	} else if cmdName == "run_to" {
+		err = fmt.Errorf("Invalid command: %s; The right format is: run_to FILENAME LINE-NUMBER", cmd)
 	} else {
 		err = fmt.Errorf("Invalid command: %s", cmd)
 	}
@@ -21,6 +21,18 @@ func TestValidate(t *testing.T) {
 		t.Error("Misidentified valid update_source command.")
 	}
 
+	if err := Validate("run_to", []string{"foo.php", "12"}); err != nil {
+		t.Error("Misidentified valid run_to command.")
+	}
+
+	if err := Validate("record", []string{"session.jsonl"}); err != nil {
+		t.Error("Misidentified valid record command.")
+	}
+
+	if err := Validate("stop_recording", []string{}); err != nil {
+		t.Error("Misidentified valid stop_recording command.")
+	}
+
 	// Fail cases.
 	if err := Validate("continue", []string{"12"}); err == nil {
 		t.Error("Failed to spot invalid continue command.")
@@ -34,6 +46,18 @@ func TestValidate(t *testing.T) {
 		t.Error("Failed to spot invalid update_source command.")
 	}
 
+	if err := Validate("run_to", []string{"foo.php"}); err == nil {
+		t.Error("Failed to spot invalid run_to command.")
+	}
+
+	if err := Validate("run_to", []string{"foo.php", "bar"}); err == nil {
+		t.Error("Failed to spot invalid line number for run_to.")
+	}
+
+	if err := Validate("record", []string{}); err == nil {
+		t.Error("Failed to spot invalid record command.")
+	}
+
 	if err := Validate("foo", []string{}); err == nil {
 		t.Error("Failed to spot invalid command.")
 	}
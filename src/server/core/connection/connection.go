@@ -16,6 +16,7 @@ import "log"
 import "net"
 import "server/config"
 import "strconv"
+import "sync"
 
 type Connection struct {
 	sock       net.Listener
@@ -27,6 +28,10 @@ type Connection struct {
 	config config.Config
 
 	initialized bool
+
+	// Guards the fields above.  The goroutines for UI commands, DBGp commands,
+	// and DBGp messages all share the connection.
+	lock sync.Mutex
 }
 
 /**
@@ -62,11 +67,15 @@ func GetConnection() *Connection {
  */
 func (c *Connection) WaitUntilActive() {
 
-	if c.isActive {
+	c.lock.Lock()
+	isActive, wait := c.isActive, c.wait
+	c.lock.Unlock()
+
+	if isActive {
 		return
 	}
 
-	<-c.wait
+	<-wait
 }
 
 /**
@@ -77,12 +86,17 @@ func (c *Connection) WaitUntilActive() {
  */
 func (c *Connection) Activate() {
 
-	if c.isActive {
+	c.lock.Lock()
+	isActive := c.isActive
+	c.lock.Unlock()
+
+	if isActive {
 		return
 	}
 
-	c.signalActivation()
+	// Listen before anyone waiting for activation tries to accept a connection.
 	c.startListeningForDBGpEngine()
+	c.signalActivation()
 }
 
 /**
@@ -90,6 +104,9 @@ func (c *Connection) Activate() {
  */
 func (c *Connection) Deactivate() {
 
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	if !c.isActive {
 		return
 	}
@@ -97,23 +114,30 @@ func (c *Connection) Deactivate() {
 	c.isActive = false
 	c.wait = make(chan bool)
 
-	c.Disconnect()
+	c.disconnect()
 	c.stopListening()
 }
 
 /**
  * Establish connection with a DBGp engine.
  */
-func (c *Connection) Connect() *net.Conn {
+func (c *Connection) Connect() net.Conn {
 
-	conn, err := c.sock.Accept()
+	c.lock.Lock()
+	sock := c.sock
+	c.lock.Unlock()
+
+	conn, err := sock.Accept()
 
 	if err != nil {
 		log.Println(err)
 	}
 
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	c.connection = conn
-	return &c.connection
+	return c.connection
 }
 
 /**
@@ -121,6 +145,17 @@ func (c *Connection) Connect() *net.Conn {
  */
 func (c *Connection) Disconnect() error {
 
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.disconnect()
+}
+
+/**
+ * @see Disconnect()
+ */
+func (c *Connection) disconnect() error {
+
 	var err error
 
 	if c.connection == nil {
@@ -143,11 +178,12 @@ func (c *Connection) IsOnAir() bool {
 
 	ignore := []byte{}
 
-	if nil == c.connection {
+	connection := c.Get()
+	if nil == connection {
 		return false
 	}
 
-	if readCount, err := c.connection.Write(ignore); nil != err {
+	if readCount, err := connection.Write(ignore); nil != err {
 		_ = readCount
 		return false
 	}
@@ -158,9 +194,29 @@ func (c *Connection) IsOnAir() bool {
 /**
  * Return an instance of the network connection, active or not.
  */
-func (c *Connection) Get() *net.Conn {
+func (c *Connection) Get() net.Conn {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.connection
+}
+
+/**
+ * Network address where we are listening for the DBGp engine.
+ *
+ * Useful when listening on port 0, i.e. any free port.  Nil when not listening.
+ */
+func (c *Connection) Addr() net.Addr {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.sock == nil || !c.isActive {
+		return nil
+	}
 
-	return &c.connection
+	return c.sock.Addr()
 }
 
 /**
@@ -168,6 +224,9 @@ func (c *Connection) Get() *net.Conn {
  */
 func (c *Connection) signalActivation() {
 
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	if c.isActive {
 		return
 	}
@@ -194,11 +253,16 @@ func (c *Connection) startListeningForDBGpEngine() {
 		log.Fatal(err)
 	}
 
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	c.sock = sock
 }
 
 /**
  * Stop listening for the DBGp engine.
+ *
+ * Expects the lock to be held.
  */
 func (c *Connection) stopListening() {
 
@@ -20,7 +20,7 @@ func TestWaitUntilActive(t *testing.T) {
 	var conn Connection
 	conn.wait = make(chan bool)
 
-	activated := false
+	activated := make(chan bool)
 
 	go func() {
 		time.Sleep(time.Millisecond)
@@ -29,11 +29,12 @@ func TestWaitUntilActive(t *testing.T) {
 
 	go func() {
 		conn.WaitUntilActive()
-		activated = true
+		close(activated)
 	}()
 
-	time.Sleep(3 * time.Millisecond)
-	if activated == false {
+	select {
+	case <-activated:
+	case <-time.After(time.Second):
 		t.Error("Activation failed.")
 	}
 
@@ -41,15 +42,16 @@ func TestWaitUntilActive(t *testing.T) {
 	var conn2 Connection
 	conn2.wait = make(chan bool)
 
-	activated = false
+	activated = make(chan bool)
 
 	go func() {
 		conn2.WaitUntilActive()
-		activated = true
+		close(activated)
 	}()
 
-	time.Sleep(3 * time.Millisecond)
-	if activated == true {
+	select {
+	case <-activated:
 		t.Error("Failed to wait despite no activation.")
+	case <-time.After(3 * time.Millisecond):
 	}
 }
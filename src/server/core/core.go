@@ -6,15 +6,20 @@
 package core
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"server/cli"
 	"server/config"
 	"server/core/breakpoint"
 	footlecmd "server/core/cmd"
 	conn "server/core/connection"
 	"server/core/current-state"
+	"server/core/recorder"
+	"server/core/tracker"
 	"server/dbgp/command"
 	"server/dbgp/message"
+	"strconv"
 )
 
 /**
@@ -25,22 +30,25 @@ import (
  * Footle's behavior.  These are acted up on.  Some other commands (e.g.
  * breakpoint_set, breakpoint_remove) need special treatment outside a
  * debugging session to allow breakpoint management at all times.
+ *
+ * Every command is answered with a message tagged with the UI client that has
+ * issued it.
  */
-func ProcessUICmds(CmdsFromUIs, DBGpCmds chan string, DBGpMessages chan message.Message, DBGpConnection *conn.Connection) {
+func ProcessUICmds(CmdsFromUIs chan tracker.Request, DBGpCmds chan string, DBGpMessages chan message.Message, DBGpConnection *conn.Connection) {
 
-	for cmd := range CmdsFromUIs {
-		cmdAlias, cmdArgs, err := command.Break(cmd)
+	for request := range CmdsFromUIs {
+		cmdAlias, cmdArgs, err := command.Break(request.Cmd)
 		if nil != err {
-			log.Println(err)
+			rejectRequest(request, cmdAlias, message.InvalidCmdErrorCode, err, DBGpMessages)
 			continue
 		}
 
 		if footlecmd.Is(cmdAlias) {
-			processFootleCmds(cmdAlias, cmdArgs, DBGpMessages, DBGpConnection)
-		} else if DBGpCmdName, err := command.Extract(cmd); err == nil {
-			processDBGpCmds(DBGpCmdName, cmdArgs, DBGpCmds, DBGpMessages, DBGpConnection)
+			processFootleCmds(request, cmdAlias, cmdArgs, DBGpCmds, DBGpMessages, DBGpConnection)
+		} else if DBGpCmdName, err := command.Extract(request.Cmd); err == nil {
+			processDBGpCmds(request, DBGpCmdName, cmdArgs, DBGpCmds, DBGpMessages, DBGpConnection)
 		} else {
-			log.Println(err)
+			rejectRequest(request, cmdAlias, message.InvalidCmdErrorCode, err, DBGpMessages)
 		}
 	}
 }
@@ -51,26 +59,94 @@ func ProcessUICmds(CmdsFromUIs, DBGpCmds chan string, DBGpMessages chan message.
  * Some messages need automated responses.  These are acted up on.  Some other
  * messages are going to affect the state of the UIs.  These are broadcast to
  * the UIs.
+ *
+ * Responses to commands from UIs are tagged with the UI client that has issued
+ * the command.  The response is also handed over to the client if it is
+ * waiting for it.
+ *
+ * At each break, the UIs are warned if the engine is running a different copy
+ * of the file.
  */
-func ProcessDBGpMessages(DBGpCmds chan string, DBGpMessages, MsgsForCmdLineUI, MsgsForHTTPUI chan message.Message) {
+func ProcessDBGpMessages(DBGpCmds chan string, DBGpMessages, MsgsForCmdLineUI, MsgsForHTTPUI, MsgsForDAPUI chan message.Message, DBGpConnection *conn.Connection) {
 
 	for msg := range DBGpMessages {
 		state := msg.State
 
-		if state == "stopping" {
+		request, isTracked := tracker.Settle(msg)
+		if isTracked {
+			msg.Origin = request.Origin
+			nameSourceFile(&msg, request)
+		}
+
+		if msg.MessageType == "response" && msg.Properties.Command == "detach" {
+			// The engine responds to "detach" with the "stopping" state.  But
+			// there is no need to stop the script.  It will carry on by itself.
+			execution.reset()
+			breakpoint.ForgetTemporary()
+			forgetSources()
+			finishDetachment(msg, DBGpConnection)
+			msg.State = "detached"
+			tracker.FailAll(message.SessionEndErrorCode, "Detached from the DBGp engine.")
+		} else if state == "stopping" {
+			execution.reset()
+			breakpoint.ForgetTemporary()
+			forgetSources()
+			tracker.FailAll(message.SessionEndErrorCode, "The debugging session has ended.")
 			endSession(DBGpCmds)
 		} else if state == "starting" {
+			execution.reset()
+			breakpoint.ForgetTemporary()
+			forgetSources()
+			tracker.FailAll(message.SessionEndErrorCode, "A new debugging session has started.")
 			setInitialDBGpConfig(DBGpCmds)
 			breakpoint.SendPending(DBGpCmds)
 			proceedWithSession(DBGpCmds)
+		} else if state == "stopped" {
+			execution.reset()
+		} else if state == "break" && msg.Properties.Command != "status" {
+			releaseHeldCmds(DBGpCmds)
+			breakpoint.RemoveTemporary(DBGpCmds)
+
+			if execution.takeInterruption() {
+				inspectInterruptedBreak(msg, DBGpCmds)
+			}
+		} else if command.IsContinuation(msg.Properties.Command) && msg.Properties.ErrorCode != 0 {
+			// The script has not resumed after all.
+			releaseHeldCmds(DBGpCmds)
+		} else if state == "" && msg.Properties.Command == "stack_get" {
+			// Tell the UIs where an interrupted script has stopped.
+			if breakMsg, ok := prepareBreakFromStack(msg); ok {
+				request.Answer(msg)
+				broadcastMsgToUIs(msg, MsgsForCmdLineUI, MsgsForHTTPUI, MsgsForDAPUI)
+				currentstate.SaveLastMsg(msg)
+				msg, isTracked = breakMsg, false
+			}
 		} else if state == "" && (msg.Properties.Command == "breakpoint_set" || msg.Properties.Command == "breakpoint_remove") {
+			breakpoint.RecordTemporaryId(msg)
 			requestBreakpointList(DBGpCmds)
 		} else if state == "" && msg.Properties.Command == "breakpoint_list" {
+			msg.Breakpoints = breakpoint.WithoutTemporary(msg.Breakpoints)
 			breakpoint.RenewList(msg.Breakpoints)
+		} else if !isTracked && isAwaitedSource(msg) {
+			// The engine's copy of a file.  Only the verdict is for the UIs.
+			if warning, hasWarning := takeSource(msg); hasWarning {
+				broadcastMsgToUIs(warning, MsgsForCmdLineUI, MsgsForHTTPUI, MsgsForDAPUI)
+			}
+
+			continue
+		}
+
+		if isTracked {
+			request.Answer(msg)
 		}
 
-		broadcastMsgToUIs(msg, MsgsForCmdLineUI, MsgsForHTTPUI)
+		broadcastMsgToUIs(msg, MsgsForCmdLineUI, MsgsForHTTPUI, MsgsForDAPUI)
 		currentstate.SaveLastMsg(msg)
+
+		// Does the engine run the same code as we have?
+		if warning, hasWarning := checkSource(msg, DBGpCmds); hasWarning {
+			broadcastMsgToUIs(warning, MsgsForCmdLineUI, MsgsForHTTPUI, MsgsForDAPUI)
+		}
 	}
 }
 
@@ -84,7 +160,7 @@ func ProcessDBGpMessages(DBGpCmds chan string, DBGpMessages, MsgsForCmdLineUI, M
  *   - Some DBGp commands are queued when the DBGp engine is unavailable.  These
  *     queued commands are later sent when the engine makes contact.
  */
-func processDBGpCmds(cmdName string, cmdArgs []string, DBGpCmds chan string, DBGpMessages chan message.Message, DBGpConnection *conn.Connection) {
+func processDBGpCmds(request tracker.Request, cmdName string, cmdArgs []string, DBGpCmds chan string, DBGpMessages chan message.Message, DBGpConnection *conn.Connection) {
 
 	if cmdName == "breakpoint_set" {
 		// Filepaths coming from UIs *could be* relative paths.  These need to be
@@ -93,7 +169,7 @@ func processDBGpCmds(cmdName string, cmdArgs []string, DBGpCmds chan string, DBG
 		cmdArgs[0] = toAbsoluteUri(cmdArgs[0], config)
 	}
 
-	if (cmdName == "run" || cmdName == "step_over") && DBGpConnection.IsOnAir() {
+	if command.IsContinuation(cmdName) && DBGpConnection.IsOnAir() {
 		fakeCmd := message.Properties{Command: "run"}
 		fakeState := "running"
 		broadcastFakeMsg(fakeCmd, fakeState, DBGpMessages)
@@ -104,39 +180,80 @@ func processDBGpCmds(cmdName string, cmdArgs []string, DBGpCmds chan string, DBG
 		filename := cmdArgs[0]
 		lineNo := cmdArgs[1]
 
-		breakpoint.Enqueue(breakpoint.Line_type_breakpoint, filename, lineNo)
-		breakpoint.BroadcastPending(DBGpMessages)
+		placedLineNo, err := breakpoint.Enqueue(breakpoint.Line_type_breakpoint, filename, lineNo)
+		if err != nil {
+			rejectRequest(request, cmdName, message.InvalidCmdErrorCode, err, DBGpMessages)
+			return
+		}
+
+		msg := breakpoint.PrepareFakeMsg()
+		msg.Properties.Filename = filename
+		msg.Properties.LineNumber = placedLineNo
+		if lineNo != strconv.Itoa(placedLineNo) {
+			msg.Properties.Notice = fmt.Sprintf("Line %s cannot carry a breakpoint.  The breakpoint has been moved to line %d.", lineNo, placedLineNo)
+		}
+
+		msg.Origin = request.Origin
+		request.Answer(msg)
+		DBGpMessages <- msg
 	} else if cmdName == "breakpoint_remove" && !DBGpConnection.IsOnAir() {
 		// Example command from UI: breakpoint_remove 18
 		breakpointId := cmdArgs[0]
 		breakpoint.RemovePending(breakpointId)
 		breakpoint.BroadcastPending(DBGpMessages)
-	} else if err := command.Validate(cmdName, cmdArgs); err == nil {
-		fullDBGpCmd, _ := command.Prepare(cmdName, cmdArgs)
+		request.Answer(breakpoint.PrepareFakeMsg())
+	} else if cmdName == "breakpoint_list" && !DBGpConnection.IsOnAir() {
+		// Only pending breakpoints exist outside a debugging session.
+		request.Answer(breakpoint.PrepareFakeMsg())
+	} else if fullDBGpCmd, TxId, err := command.PrepareWTxId(cmdName, cmdArgs); err == nil {
+		tracker.Track(TxId, cmdName, request)
 		DBGpCmds <- fullDBGpCmd
+	} else {
+		rejectRequest(request, cmdName, message.InvalidCmdErrorCode, err, DBGpMessages)
 	}
 }
 
 /**
  * Processing of Footle's internal commands.
  */
-func processFootleCmds(cmdAlias string, cmdArgs []string, DBGpMessages chan message.Message, DBGpConnection *conn.Connection) {
+func processFootleCmds(request tracker.Request, cmdAlias string, cmdArgs []string, DBGpCmds chan string, DBGpMessages chan message.Message, DBGpConnection *conn.Connection) {
 
 	if cmdAlias == "on" {
 		DBGpConnection.Activate()
 
 		fakeCmd := message.Properties{Command: "on"}
-		broadcastFakeMsg(fakeCmd, "awake", DBGpMessages)
+		answerWFakeMsg(request, fakeCmd, "awake", DBGpMessages)
 	} else if cmdAlias == "off" {
 		DBGpConnection.Deactivate()
 
 		fakeCmd := message.Properties{Command: "off"}
-		broadcastFakeMsg(fakeCmd, "asleep", DBGpMessages)
-	} else if cmdAlias == "continue" {
-		DBGpConnection.Disconnect()
+		answerWFakeMsg(request, fakeCmd, "asleep", DBGpMessages)
+	} else if cmdAlias == "continue" && DBGpConnection.IsOnAir() && execution.isScriptRunning() {
+		// While the script runs, the engine only listens for "break" and
+		// "status".  A "detach" would wait until the next break.  So we hang up
+		// right away and let the script carry on by itself.
+		execution.reset()
+		breakpoint.ForgetTemporary()
+		tracker.FailAll(message.SessionEndErrorCode, "Detached from the DBGp engine.")
+
+		if err := DBGpConnection.Disconnect(); err != nil {
+			log.Println(err)
+		}
 
 		fakeCmd := message.Properties{Command: "continue"}
-		broadcastFakeMsg(fakeCmd, "stopped", DBGpMessages)
+		answerWFakeMsg(request, fakeCmd, "detached", DBGpMessages)
+	} else if cmdAlias == "continue" && DBGpConnection.IsOnAir() {
+		// The connection is closed once the engine acknowledges the detachment.
+		// @see finishDetachment()
+		detachCmd, TxId, err := command.PrepareWTxId("detach", []string{})
+
+		if err == nil {
+			tracker.Track(TxId, "detach", request)
+			DBGpCmds <- detachCmd
+		}
+	} else if cmdAlias == "continue" {
+		fakeCmd := message.Properties{Command: "continue"}
+		answerWFakeMsg(request, fakeCmd, "stopped", DBGpMessages)
 	} else if cmdAlias == "update_source" && len(cmdArgs) == 1 {
 		filename := cmdArgs[0]
 
@@ -147,17 +264,115 @@ func processFootleCmds(cmdAlias string, cmdArgs []string, DBGpMessages chan mess
 			log.Printf("File doesn't exist: %s", filename)
 		}
 
+		// Breakpoints follow their statements when lines are added or removed
+		// above them.  During a session, the engine's fresh breakpoint listing
+		// reaches the UIs instead.
+		fileUri := toAbsoluteUri(filename, config)
+		if breakpoint.Reanchor(fileUri, DBGpCmds, DBGpConnection.IsOnAir()) && !DBGpConnection.IsOnAir() {
+			breakpoint.BroadcastPending(DBGpMessages)
+		}
+
+		fakeCmd := message.Properties{Command: cmdAlias, Filename: filename}
+		answerWFakeMsg(request, fakeCmd, "", DBGpMessages)
+	} else if cmdAlias == "run_to" {
+		runTo(request, cmdArgs, DBGpCmds, DBGpMessages, DBGpConnection)
+	} else if cmdAlias == "record" && request.Origin != cli.Origin {
+		// Anyone who can reach the HTTP or DAP ports could write anywhere.
+		err := fmt.Errorf("Recording can only be started from the command line.")
+		rejectRequest(request, cmdAlias, message.InvalidCmdErrorCode, err, DBGpMessages)
+	} else if cmdAlias == "record" && len(cmdArgs) == 1 {
+		if err := recorder.Start(cmdArgs[0]); err != nil {
+			rejectRequest(request, cmdAlias, message.FileErrorCode, err, DBGpMessages)
+			return
+		}
+
+		fakeCmd := message.Properties{Command: cmdAlias, Filename: cmdArgs[0]}
+		answerWFakeMsg(request, fakeCmd, "", DBGpMessages)
+	} else if cmdAlias == "stop_recording" {
+		filename := recorder.Filepath()
+
+		if err := recorder.Stop(); err != nil {
+			rejectRequest(request, cmdAlias, message.FileErrorCode, err, DBGpMessages)
+			return
+		}
+
 		fakeCmd := message.Properties{Command: cmdAlias, Filename: filename}
-		broadcastFakeMsg(fakeCmd, "", DBGpMessages)
+		answerWFakeMsg(request, fakeCmd, "", DBGpMessages)
+	} else {
+		err := fmt.Errorf("Invalid command: %s", request.Cmd)
+		rejectRequest(request, cmdAlias, message.InvalidCmdErrorCode, err, DBGpMessages)
+	}
+}
+
+/**
+ * Carry on with execution until the given line.
+ *
+ * Set a temporary breakpoint on the given line and then issue the DBGp "run"
+ * command.  The temporary breakpoint is removed at the next break.
+ *
+ * Example command from UI: run_to index.php 18
+ */
+func runTo(request tracker.Request, cmdArgs []string, DBGpCmds chan string, DBGpMessages chan message.Message, DBGpConnection *conn.Connection) {
+
+	if err := footlecmd.Validate("run_to", cmdArgs); err != nil {
+		rejectRequest(request, "run_to", message.InvalidCmdErrorCode, err, DBGpMessages)
+		return
+	}
+
+	if !DBGpConnection.IsOnAir() {
+		err := fmt.Errorf("The run_to command needs an active debugging session.")
+		rejectRequest(request, "run_to", message.NoSessionErrorCode, err, DBGpMessages)
+		return
+	}
+
+	// Filepaths coming from UIs *could be* relative paths.
+	config := config.Get()
+	fileUri := toAbsoluteUri(cmdArgs[0], config)
+	lineNo := cmdArgs[1]
+
+	if err := breakpoint.SetTemporary(fileUri, lineNo, DBGpCmds); err != nil {
+		rejectRequest(request, "run_to", message.InvalidCmdErrorCode, err, DBGpMessages)
+		return
+	}
+
+	// The response to "run" arrives at the next break and settles the request.
+	runCmd, TxId, err := command.PrepareWTxId("run", []string{})
+	if err != nil {
+		return
+	}
+
+	fakeCmd := message.Properties{Command: "run"}
+	broadcastFakeMsg(fakeCmd, "running", DBGpMessages)
+
+	tracker.Track(TxId, "run", request)
+	DBGpCmds <- runCmd
+}
+
+/**
+ * Drop the connection with the DBGp engine after a "detach" command.
+ *
+ * The engine has stopped debugging by now and the script is running freely.
+ * When the engine has failed to detach, we drop the connection anyway as that
+ * is the next best thing.
+ */
+func finishDetachment(msg message.Message, DBGpConnection *conn.Connection) {
+
+	if msg.Properties.ErrorCode != 0 {
+		log.Printf("Failed to detach from DBGp engine: %s", msg.Properties.ErrorMessage)
+	}
+
+	if err := DBGpConnection.Disconnect(); err != nil {
+		log.Println(err)
 	}
 }
 
 /**
  * Pass on a DBGP message to all the user interfaces.
  *
- * User interfaces include the command line interface and the HTTP interface.
+ * User interfaces include the command line interface, the HTTP interface, and
+ * the Debug Adapter Protocol interface.
  */
-func broadcastMsgToUIs(msg message.Message, toCmdLine, toHTTP chan<- message.Message) {
+func broadcastMsgToUIs(msg message.Message, toCmdLine, toHTTP, toDAP chan<- message.Message) {
 
 	if nil != toCmdLine {
 		toCmdLine <- msg
@@ -166,6 +381,33 @@ func broadcastMsgToUIs(msg message.Message, toCmdLine, toHTTP chan<- message.Mes
 	if nil != toHTTP {
 		toHTTP <- msg
 	}
+
+	if nil != toDAP {
+		toDAP <- msg
+	}
+}
+
+/**
+ * Note down the file behind a "source" response.
+ *
+ * The DBGp engine does not name the file in its response.  So we take it from
+ * the command.  Then UIs can tell the file's encoding.  Chunks of the current
+ * file remain unnamed.
+ *
+ * Example command: source foo/bar.php
+ */
+func nameSourceFile(msg *message.Message, request tracker.Request) {
+
+	if msg.Properties.Command != "source" || msg.Properties.Filename != "" {
+		return
+	}
+
+	_, cmdArgs, err := command.Break(request.Cmd)
+	if err != nil || len(cmdArgs) != 1 {
+		return
+	}
+
+	msg.Properties.Filename = toAbsoluteUri(cmdArgs[0], config.Get())
 }
 
 /**
@@ -174,17 +416,53 @@ func broadcastMsgToUIs(msg message.Message, toCmdLine, toHTTP chan<- message.Mes
  * Knowing about the execution states resulting from the internal commands
  * allow UIs to offer better UX.
  *
- * Example commands: on, off, continue, update_source.
+ * Example commands: on, off, continue, update_source, run_to.
  */
 func broadcastFakeMsg(prop message.Properties, state string, DBGpMessages chan message.Message) {
 
-	fakeMsg := message.Message{}
+	DBGpMessages <- prepareFakeMsg(prop, state)
+}
+
+/**
+ * Answer a Footle internal command and broadcast the answer.
+ */
+func answerWFakeMsg(request tracker.Request, prop message.Properties, state string, DBGpMessages chan message.Message) {
+
+	fakeMsg := prepareFakeMsg(prop, state)
+	fakeMsg.Origin = request.Origin
+
+	request.Answer(fakeMsg)
+	DBGpMessages <- fakeMsg
+}
+
+/**
+ * Prepare a response for Footle's internal commands.
+ */
+func prepareFakeMsg(prop message.Properties, state string) (fakeMsg message.Message) {
+
 	fakeMsg.MessageType = "response"
 	fakeMsg.Properties.Command = prop.Command
 	fakeMsg.Properties.Filename = prop.Filename
 	fakeMsg.State = state
 
-	DBGpMessages <- fakeMsg
+	return fakeMsg
+}
+
+/**
+ * Turn down a command from a UI.
+ *
+ * The error is broadcast so that the UI client that has issued the command
+ * gets to hear about it.
+ */
+func rejectRequest(request tracker.Request, cmdName string, errorCode int, err error, DBGpMessages chan message.Message) {
+
+	log.Println(err)
+
+	errorMsg := message.PrepareErrorMsg(cmdName, 0, errorCode, err.Error())
+	errorMsg.Origin = request.Origin
+
+	request.Answer(errorMsg)
+	DBGpMessages <- errorMsg
 }
 
 /**
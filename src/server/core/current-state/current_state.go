@@ -1,15 +1,18 @@
 /**
  * Footle's current state.
  *
- * Current state = execution state + breakpoints.
+ * Current state = execution state + breakpoints + what we know about the
+ * current break and debugging session.
  *
- * Possible execution states: awake, asleep, break, stopped.  These states are
- * entered into due to messages from the DBGP engine and commands from the UIs.
+ * Possible execution states: awake, asleep, break, stopped, detached.  These
+ * states are entered into due to messages from the DBGP engine and commands
+ * from the UIs.
  *
  * Please note that Footle's current state is different from the DBGp engine's
  * state.  Footle may be sleeping while the DBGp engine could still be active.
  *
- * The current state is needed during UI initialization.
+ * The current state is needed during UI initialization.  A UI joining during a
+ * break can then show the stack trace and variables straight away.
  */
 
 package currentstate
@@ -17,24 +20,62 @@ package currentstate
 import (
 	"server/core/breakpoint"
 	"server/dbgp/message"
+	"sync"
 )
 
 /**
- * Last message that changed execution state of Footle.
+ * Everything a late-joining UI needs to catch up.
  */
-var lastMsg message.Message
+type snapshot struct {
+	sync.Mutex
+
+	lastMsg    message.Message // Last message that changed execution state.
+	sessionMsg message.Message // Init message of the current debugging session.
+	stackMsg   message.Message
+	contexts   map[string]message.Message // Keyed by context name: local, global.
+	watches    map[string]message.Message // property_get results keyed by variable name.
+	watchOrder []string
+}
+
+var state = snapshot{
+	contexts: make(map[string]message.Message),
+	watches:  make(map[string]message.Message),
+}
 
 /**
- * Fetch the last execution state and existing breakpoints.
+ * Fetch the last execution state, existing breakpoints, and the details of the
+ * current debugging session.
  *
- * These are represented in the form of messages for UIs.
+ * These are represented in the form of messages for UIs.  The messages are in
+ * the order UIs would have received them.
  */
 func Get() (stateMessages []message.Message) {
 
+	state.Lock()
+	defer state.Unlock()
+
 	stateMessages = []message.Message{}
 
-	if isRelevant(lastMsg) {
-		stateMessages = append(stateMessages, lastMsg)
+	if state.sessionMsg.MessageType != "" {
+		stateMessages = append(stateMessages, state.sessionMsg)
+	}
+
+	if isRelevant(state.lastMsg) {
+		stateMessages = append(stateMessages, state.lastMsg)
+	}
+
+	if state.stackMsg.MessageType != "" {
+		stateMessages = append(stateMessages, state.stackMsg)
+	}
+
+	for _, contextName := range []string{"local", "global"} {
+		if contextMsg, exists := state.contexts[contextName]; exists {
+			stateMessages = append(stateMessages, contextMsg)
+		}
+	}
+
+	for _, varName := range state.watchOrder {
+		stateMessages = append(stateMessages, state.watches[varName])
 	}
 
 	breakpointListingMsg := breakpoint.PrepareFakeMsg()
@@ -46,13 +87,98 @@ func Get() (stateMessages []message.Message) {
 }
 
 /**
- * Save the last message that changed execution state of Footle.
+ * Record a message that is relevant to the current state.
+ *
+ * Remembers the last message that changed execution state of Footle.  Also
+ * remembers the latest stack trace, variables, and session details.  These
+ * are forgotten once the script moves on.
  */
 func SaveLastMsg(msg message.Message) {
 
+	state.Lock()
+	defer state.Unlock()
+
 	if isRelevant(msg) {
-		lastMsg = msg
+		state.lastMsg = msg
+	}
+
+	if msg.MessageType == "init" {
+		state.forgetSession()
+		state.sessionMsg = msg
+		return
+	}
+
+	switch msg.State {
+	case "running":
+		state.forgetBreak()
+	case "stopped", "detached", "asleep":
+		state.forgetSession()
 	}
+
+	if msg.MessageType != "response" || msg.Properties.ErrorCode != 0 {
+		return
+	}
+
+	switch msg.Properties.Command {
+	case "stack_get":
+		state.stackMsg = msg
+	case "context_get":
+		state.saveContext(msg)
+	case "property_get":
+		state.saveWatch(msg)
+	}
+}
+
+/**
+ * Keep the variables of a context.
+ *
+ * Empty contexts cannot be told apart and are ignored.
+ */
+func (s *snapshot) saveContext(msg message.Message) {
+
+	if len(msg.Context.Local) > 0 {
+		s.contexts["local"] = msg
+	} else if len(msg.Context.Global) > 0 {
+		s.contexts["global"] = msg
+	}
+}
+
+/**
+ * Keep the latest value of a variable that a UI has asked for.
+ */
+func (s *snapshot) saveWatch(msg message.Message) {
+
+	if len(msg.Context.Local) == 0 {
+		return
+	}
+
+	varName := msg.Context.Local[0].Fullname
+
+	if _, exists := s.watches[varName]; !exists {
+		s.watchOrder = append(s.watchOrder, varName)
+	}
+
+	s.watches[varName] = msg
+}
+
+/**
+ * Forget everything about the current break.
+ */
+func (s *snapshot) forgetBreak() {
+
+	s.stackMsg = message.Message{}
+	s.contexts = make(map[string]message.Message)
+	s.watches = make(map[string]message.Message)
+	s.watchOrder = nil
+}
+
+/**
+ * Forget everything about the current debugging session.
+ */
+func (s *snapshot) forgetSession() {
+
+	s.sessionMsg = message.Message{}
+	s.forgetBreak()
 }
 
 /**
@@ -65,7 +191,7 @@ func SaveLastMsg(msg message.Message) {
 func isRelevant(msg message.Message) bool {
 
 	switch msg.State {
-	case "init", "break", "stopped", "awake", "asleep":
+	case "init", "break", "stopped", "detached", "awake", "asleep":
 		return true
 	}
 
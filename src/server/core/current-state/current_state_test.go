@@ -0,0 +1,58 @@
+package currentstate
+
+import (
+	"server/dbgp/message"
+	"testing"
+)
+
+/**
+ * Tests for the state snapshot as a debugging session progresses.
+ */
+func TestGet(t *testing.T) {
+
+	initMsg := message.Message{MessageType: "init", State: "starting"}
+	initMsg.Session.Engine = "Xdebug"
+	SaveLastMsg(initMsg)
+
+	breakMsg := message.Message{MessageType: "response", State: "break"}
+	breakMsg.Properties.Command = "step_into"
+	SaveLastMsg(breakMsg)
+
+	stackMsg := message.Message{MessageType: "response", Stacktrace: []message.StackLevel{{Where: "foo"}}}
+	stackMsg.Properties.Command = "stack_get"
+	SaveLastMsg(stackMsg)
+
+	contextMsg := message.Message{MessageType: "response"}
+	contextMsg.Properties.Command = "context_get"
+	contextMsg.Context.Local = []message.Variable{{Fullname: "$bar"}}
+	SaveLastMsg(contextMsg)
+
+	watchMsg := message.Message{MessageType: "response"}
+	watchMsg.Properties.Command = "property_get"
+	watchMsg.Context.Local = []message.Variable{{Fullname: "$baz"}}
+	SaveLastMsg(watchMsg)
+	SaveLastMsg(watchMsg)
+
+	stateMessages := Get()
+	if 5 != len(stateMessages) {
+		t.Fatalf("Expected five state messages, got: %v", stateMessages)
+	}
+
+	if "Xdebug" != stateMessages[0].Session.Engine || "break" != stateMessages[1].State || "stack_get" != stateMessages[2].Properties.Command || "context_get" != stateMessages[3].Properties.Command || "property_get" != stateMessages[4].Properties.Command {
+		t.Errorf("Unexpected state messages: %v", stateMessages)
+	}
+
+	// The script moves on.
+	SaveLastMsg(message.Message{MessageType: "response", State: "running"})
+
+	if stateMessages = Get(); 2 != len(stateMessages) {
+		t.Errorf("Break details have outlived the break: %v", stateMessages)
+	}
+
+	// The session ends.
+	SaveLastMsg(message.Message{MessageType: "response", State: "stopped"})
+
+	if stateMessages = Get(); 1 != len(stateMessages) || "stopped" != stateMessages[0].State {
+		t.Errorf("Session details have outlived the session: %v", stateMessages)
+	}
+}
@@ -0,0 +1,129 @@
+/**
+ * @file
+ * Package for noticing when the DBGp engine runs code that differs from ours.
+ *
+ * Footle assumes that the local codebase is a copy of what the DBGp engine is
+ * running.  When the two differ, breaks and breakpoints land on the wrong
+ * lines.  So we fetch the engine's copy of each file it breaks in and compare
+ * it with the local file.
+ *
+ * The engine's copies are kept until the debugging session ends.  A file the
+ * engine is running does not change during a session.  The local copy might,
+ * so that is compared afresh every time.  Line endings are ignored as they do
+ * not move lines.
+ */
+
+package divergence
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io/ioutil"
+	"sync"
+)
+
+type digest [sha256.Size]byte
+
+/**
+ * The DBGp engine's copy of a file.
+ */
+type engineFile struct {
+	src         []byte
+	isAvailable bool   // False when the engine has refused to share the file.
+	hash        digest // Of src.
+	hasWarned   bool
+	warnedHash  digest // Of the local copy we have last warned about.
+}
+
+var engineFiles = make(map[string]*engineFile)
+var lock sync.Mutex
+
+/**
+ * Has the engine's copy of the given file been fetched during this session?
+ */
+func IsKnown(fileUri string) bool {
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	_, isKnown := engineFiles[fileUri]
+
+	return isKnown
+}
+
+/**
+ * Keep the engine's copy of a file.
+ *
+ * A nil src means the engine has refused to share the file.  Such files are
+ * never reported as different.
+ */
+func Remember(fileUri string, src []byte) {
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	engineFiles[fileUri] = &engineFile{src: src, isAvailable: src != nil, hash: hash(src)}
+}
+
+/**
+ * Compare the local copy of a file with the engine's copy.
+ *
+ * isNews tells whether this is the first time this local copy has been found
+ * different.  Useful for warning only once rather than at every break.  A
+ * missing local copy counts as different.
+ */
+func Compare(fileUri, localPath string) (isDiverged, isNews bool) {
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	engineCopy, isKnown := engineFiles[fileUri]
+	if !isKnown || !engineCopy.isAvailable {
+		return false, false
+	}
+
+	localSrc, err := ioutil.ReadFile(localPath)
+	localHash := hash(localSrc)
+	if err == nil && localHash == engineCopy.hash {
+		return false, false
+	}
+
+	isNews = !engineCopy.hasWarned || localHash != engineCopy.warnedHash
+	engineCopy.hasWarned, engineCopy.warnedHash = true, localHash
+
+	return true, isNews
+}
+
+/**
+ * The engine's copy of a file, if we have it.
+ */
+func EngineCopy(fileUri string) (src []byte, isAvailable bool) {
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if engineCopy, isKnown := engineFiles[fileUri]; isKnown && engineCopy.isAvailable {
+		return engineCopy.src, true
+	}
+
+	return nil, false
+}
+
+/**
+ * Drop the engine's copies.
+ *
+ * Called when a debugging session starts or ends.  The next session may run
+ * different code.
+ */
+func Forget() {
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	engineFiles = make(map[string]*engineFile)
+}
+
+func hash(src []byte) digest {
+
+	return sha256.Sum256(bytes.Replace(src, []byte("\r\n"), []byte("\n"), -1))
+}
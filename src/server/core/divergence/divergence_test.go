@@ -0,0 +1,69 @@
+package divergence
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/**
+ * Tests for Compare().
+ *
+ * Differences should be news only once for each local copy.
+ */
+func TestCompare(t *testing.T) {
+
+	tmpDir, err := ioutil.TempDir("", "footle-divergence")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	defer Forget()
+
+	localPath := filepath.Join(tmpDir, "index.php")
+	fileUri := "file:///var/www/index.php"
+	ioutil.WriteFile(localPath, []byte("<?php\r\necho 1;\r\n"), 0644)
+
+	if isDiverged, _ := Compare(fileUri, localPath); isDiverged || IsKnown(fileUri) {
+		t.Error("Unknown files should not count as different.")
+	}
+
+	Remember(fileUri, []byte("<?php\necho 1;\n"))
+
+	if isDiverged, _ := Compare(fileUri, localPath); isDiverged {
+		t.Error("Line endings should not matter.")
+	}
+
+	ioutil.WriteFile(localPath, []byte("<?php\necho 2;\n"), 0644)
+
+	if isDiverged, isNews := Compare(fileUri, localPath); !isDiverged || !isNews {
+		t.Errorf("Expected news of a difference, got: %v, %v", isDiverged, isNews)
+	}
+
+	if isDiverged, isNews := Compare(fileUri, localPath); !isDiverged || isNews {
+		t.Errorf("Expected an old difference, got: %v, %v", isDiverged, isNews)
+	}
+
+	os.Remove(localPath)
+
+	if isDiverged, isNews := Compare(fileUri, localPath); !isDiverged || !isNews {
+		t.Errorf("A missing local copy should be news, got: %v, %v", isDiverged, isNews)
+	}
+
+	if src, isAvailable := EngineCopy(fileUri); !isAvailable || string(src) != "<?php\necho 1;\n" {
+		t.Errorf("Unexpected engine copy: %q, %v", src, isAvailable)
+	}
+
+	Remember(fileUri, nil)
+
+	if isDiverged, _ := Compare(fileUri, localPath); isDiverged {
+		t.Error("Files the engine has refused to share should not count as different.")
+	}
+
+	Forget()
+
+	if IsKnown(fileUri) {
+		t.Error("Engine copies should be forgotten.")
+	}
+}
@@ -0,0 +1,139 @@
+/**
+ * @file
+ * Keep track of whether the DBGp engine is running the script.
+ *
+ * While the script is running, the DBGp engine only listens for a handful of
+ * commands such as "break".  All other DBGp commands are held back until
+ * execution stops.
+ */
+
+package core
+
+import (
+	"log"
+	"sync"
+)
+
+type executionState struct {
+	sync.Mutex
+
+	isRunning     bool
+	isInterrupted bool
+	held          []string
+}
+
+/**
+ * Execution state of the current debugging session.
+ *
+ * Shared between the goroutines that send commands and process messages.
+ */
+var execution executionState
+
+/**
+ * Note down that the script is about to resume execution.
+ */
+func (e *executionState) run() {
+
+	e.Lock()
+	defer e.Unlock()
+
+	e.isRunning = true
+}
+
+/**
+ * Is the script running?
+ */
+func (e *executionState) isScriptRunning() bool {
+
+	e.Lock()
+	defer e.Unlock()
+
+	return e.isRunning
+}
+
+/**
+ * Hold back the given DBGp command when the script is running.
+ *
+ * Returns true when the command has been held back.
+ */
+func (e *executionState) holdIfRunning(DBGpCmd string) (isHeld bool) {
+
+	e.Lock()
+	defer e.Unlock()
+
+	if !e.isRunning {
+		return false
+	}
+
+	e.held = append(e.held, DBGpCmd)
+	return true
+}
+
+/**
+ * Note down that execution has stopped.
+ *
+ * Returns the DBGp commands that have been held back in the meantime.
+ */
+func (e *executionState) halt() (held []string) {
+
+	e.Lock()
+	defer e.Unlock()
+
+	held = e.held
+
+	e.isRunning = false
+	e.held = nil
+
+	return held
+}
+
+/**
+ * Note down that the script is being interrupted by the "break" command.
+ */
+func (e *executionState) interrupt() {
+
+	e.Lock()
+	defer e.Unlock()
+
+	e.isInterrupted = true
+}
+
+/**
+ * Has the last break been caused by the "break" command?
+ *
+ * Only answers once per interruption.
+ */
+func (e *executionState) takeInterruption() (wasInterrupted bool) {
+
+	e.Lock()
+	defer e.Unlock()
+
+	wasInterrupted = e.isInterrupted
+	e.isInterrupted = false
+
+	return wasInterrupted
+}
+
+/**
+ * Start afresh.
+ *
+ * Commands held back during a debugging session are meaningless outside it.
+ */
+func (e *executionState) reset() {
+
+	e.takeInterruption()
+
+	if held := e.halt(); len(held) > 0 {
+		log.Printf("Dropping %d DBGp command(s) as the debugging session has ended.", len(held))
+	}
+}
+
+/**
+ * Send the DBGp commands that have been held back during execution.
+ */
+func releaseHeldCmds(DBGpCmds chan string) {
+
+	for _, DBGpCmd := range execution.halt() {
+		DBGpCmds <- DBGpCmd
+	}
+}
@@ -0,0 +1,45 @@
+/**
+ * @file
+ * Tests for keeping track of script execution.
+ */
+
+package core
+
+import "testing"
+
+/**
+ * Tests for holding back DBGp commands while the script is running.
+ */
+func TestExecutionState(t *testing.T) {
+
+	var e executionState
+
+	if e.holdIfRunning("stack_get -i 1\x00") {
+		t.Error("Held back a command while execution has stopped.")
+	}
+
+	e.run()
+
+	if !e.holdIfRunning("stack_get -i 2\x00") || !e.holdIfRunning("context_get -i 3 -c 0\x00") {
+		t.Error("Failed to hold back commands while the script is running.")
+	}
+
+	held := e.halt()
+	if len(held) != 2 || held[0] != "stack_get -i 2\x00" {
+		t.Errorf("Held back commands have not been released in order: %q", held)
+	}
+
+	if e.holdIfRunning("stack_get -i 4\x00") {
+		t.Error("Held back a command after execution has stopped.")
+	}
+
+	// Interruptions are only reported once.
+	e.interrupt()
+	if !e.takeInterruption() {
+		t.Error("Failed to report interruption.")
+	}
+
+	if e.takeInterruption() {
+		t.Error("Reported the same interruption twice.")
+	}
+}
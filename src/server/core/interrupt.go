@@ -0,0 +1,65 @@
+/**
+ * @file
+ * Deal with breaks caused by the "break" command.
+ *
+ * When a running script is interrupted, the UIs know nothing about where
+ * execution has stopped.  So we fetch the stack trace and local variables on
+ * their behalf.  Some DBGp engines do not mention the current file and line
+ * for such breaks.  In that case, the top of the stack trace tells us where we
+ * are.
+ */
+
+package core
+
+import (
+	"server/dbgp/command"
+	"server/dbgp/message"
+)
+
+/**
+ * Are we waiting for the stack trace to determine the location of a break?
+ *
+ * Only used by the goroutine running ProcessDBGpMessages().
+ */
+var isAwaitingBreakLocation bool
+
+/**
+ * Request the stack trace and local variables for an interrupted script.
+ */
+func inspectInterruptedBreak(breakMsg message.Message, DBGpCmds chan string) {
+
+	isAwaitingBreakLocation = (breakMsg.Properties.Filename == "")
+
+	for _, cmdArgs := range [][]string{{"stack_get"}, {"context_get", "local"}} {
+		DBGpCmd, err := command.Prepare(cmdArgs[0], cmdArgs[1:])
+
+		if err == nil {
+			DBGpCmds <- DBGpCmd
+		}
+	}
+}
+
+/**
+ * Prepare a break message from the top of a stack trace.
+ *
+ * Only relevant when we are waiting to learn the location of an interrupted
+ * script.
+ */
+func prepareBreakFromStack(stackMsg message.Message) (breakMsg message.Message, ok bool) {
+
+	if !isAwaitingBreakLocation || stackMsg.Properties.Command != "stack_get" || len(stackMsg.Stacktrace) == 0 {
+		return breakMsg, false
+	}
+
+	isAwaitingBreakLocation = false
+
+	currentLevel := stackMsg.Stacktrace[0]
+
+	breakMsg.MessageType = "response"
+	breakMsg.State = "break"
+	breakMsg.Properties.Command = "break"
+	breakMsg.Properties.Filename = currentLevel.Filename
+	breakMsg.Properties.LineNumber = currentLevel.LineNo
+
+	return breakMsg, true
+}
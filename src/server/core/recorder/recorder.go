@@ -0,0 +1,147 @@
+/**
+ * @file
+ * Records the traffic between Footle and the DBGp engine.
+ *
+ * Every DBGp packet is written to a session file along with its time and
+ * direction.  Such recordings can be attached to bug reports and replayed
+ * later.
+ *
+ * A session file has one JSON object per line.  Example:
+ *   {"time":"2026-10-18T10:15:04.12Z","direction":"recv","packet":"<?xml ..."}
+ *   {"time":"2026-10-18T10:15:04.13Z","direction":"send","packet":"step_into -i 3"}
+ *
+ * Directions:
+ *   - connect: The DBGp engine has connected.  No packet.
+ *   - recv: Packet from the DBGp engine.
+ *   - send: Command for the DBGp engine.  Without the trailing null byte.
+ *   - disconnect: The DBGp engine has gone.  No packet.
+ */
+
+package recorder
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const Connect = "connect"
+const Recv = "recv"
+const Send = "send"
+const Disconnect = "disconnect"
+
+/**
+ * A single line of a session file.
+ */
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"`
+	Packet    string    `json:"packet,omitempty"`
+}
+
+type recorder struct {
+	sync.Mutex
+
+	file     *os.File
+	encoder  *json.Encoder
+	filepath string
+}
+
+/**
+ * Footle records to one session file at a time.
+ */
+var current recorder
+
+/**
+ * Start recording to the given file.
+ *
+ * An existing file is appended to.  Any ongoing recording stops first.
+ */
+func Start(filepath string) (err error) {
+
+	file, err := os.OpenFile(filepath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	current.Lock()
+	defer current.Unlock()
+
+	current.stopUnlocked()
+
+	current.file = file
+	current.encoder = json.NewEncoder(file)
+	current.filepath = filepath
+
+	return nil
+}
+
+/**
+ * Stop recording.
+ *
+ * Stopping when not recording is harmless.
+ */
+func Stop() (err error) {
+
+	current.Lock()
+	defer current.Unlock()
+
+	return current.stopUnlocked()
+}
+
+/**
+ * Same as Stop() for callers holding the lock.
+ */
+func (r *recorder) stopUnlocked() (err error) {
+
+	if r.file == nil {
+		return nil
+	}
+
+	err = r.file.Close()
+
+	r.file = nil
+	r.encoder = nil
+	r.filepath = ""
+
+	return err
+}
+
+/**
+ * Path of the session file being recorded to.  Empty when not recording.
+ */
+func Filepath() string {
+
+	current.Lock()
+	defer current.Unlock()
+
+	return current.filepath
+}
+
+/**
+ * Write a DBGp packet to the session file, if recording.
+ */
+func Record(direction, packet string) {
+
+	current.Lock()
+	defer current.Unlock()
+
+	if current.encoder == nil {
+		return
+	}
+
+	entry := Entry{
+		Time:      time.Now(),
+		Direction: direction,
+		Packet:    strings.TrimRight(packet, "\x00"),
+	}
+
+	if err := current.encoder.Encode(entry); err != nil {
+		// A broken recording is not worth interrupting the debugging session.
+		log.Println(err)
+		current.stopUnlocked()
+	}
+}
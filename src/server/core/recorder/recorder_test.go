@@ -0,0 +1,75 @@
+package recorder
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/**
+ * Tests for recording a session and then stopping.
+ */
+func TestRecord(t *testing.T) {
+
+	tmpDir, err := ioutil.TempDir("", "footle-recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sessionFile := filepath.Join(tmpDir, "session.jsonl")
+
+	// Nothing is recorded before the start.
+	Record(Recv, "<init/>")
+
+	if err := Start(sessionFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if Filepath() != sessionFile {
+		t.Errorf("Recording to the wrong file: %s", Filepath())
+	}
+
+	Record(Connect, "")
+	Record(Send, "status -i 1\x00")
+	Record(Recv, "<response/>")
+
+	if err := Stop(); err != nil {
+		t.Error(err)
+	}
+
+	// Nothing is recorded after the end either.
+	Record(Disconnect, "")
+
+	file, err := os.Open(sessionFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if 3 != len(entries) {
+		t.Fatalf("Expected three entries, got: %v", entries)
+	}
+
+	if Send != entries[1].Direction || "status -i 1" != entries[1].Packet {
+		t.Errorf("Unexpected entry for sent command: %v", entries[1])
+	}
+
+	if entries[2].Time.Before(entries[0].Time) {
+		t.Error("Entries are out of order.")
+	}
+}
@@ -0,0 +1,261 @@
+/**
+ * @file
+ * Replays a recorded session as a fake DBGp engine.
+ *
+ * The fake engine connects to Footle's DBGp port just like Xdebug would.  It
+ * then answers Footle's commands with the responses from the recording.  This
+ * lets us work on the UIs or reproduce reported bugs without PHP or Xdebug.
+ *
+ * Each command from Footle is matched with the next recorded command of the
+ * same name.  The DBGp packets that followed the recorded command are then
+ * sent back.  Transaction IDs are rewritten to match Footle's command.
+ * Commands missing from the recording receive an error response.
+ *
+ * @see recorder.go
+ */
+
+package recorder
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"server/dbgp/command"
+	"strings"
+	"time"
+)
+
+/**
+ * How long to keep knocking on Footle's DBGp port.
+ */
+const dialAttempts = 10
+const dialInterval = 500 * time.Millisecond
+
+/**
+ * DBGp error code for commands missing from the recording.
+ *
+ * Same as DBGp's "Command not available" error.
+ */
+const notRecordedErrorCode = 5
+
+/**
+ * Load a session file.
+ *
+ * Returns one list of entries for each connection of the DBGp engine.
+ */
+func Load(filepath string) (sessions [][]Entry, err error) {
+
+	file, err := os.Open(filepath)
+	if err != nil {
+		return sessions, err
+	}
+	defer file.Close()
+
+	var session []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return sessions, fmt.Errorf("Invalid session file entry at line %d: %s", lineNo, err)
+		}
+
+		// Keep the disconnection so that replay can hang up at the same point.
+		if entry.Direction == Disconnect {
+			session = append(session, entry)
+		}
+
+		if entry.Direction == Connect || entry.Direction == Disconnect {
+			if len(session) > 0 {
+				sessions = append(sessions, session)
+			}
+
+			session = nil
+			continue
+		}
+
+		session = append(session, entry)
+	}
+
+	if len(session) > 0 {
+		sessions = append(sessions, session)
+	}
+
+	return sessions, scanner.Err()
+}
+
+/**
+ * Replay a session file against Footle's DBGp port.
+ *
+ * Each recorded connection is replayed over its own connection.
+ */
+func Replay(filepath, address string) (err error) {
+
+	sessions, err := Load(filepath)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		conn, err := dial(address)
+		if err != nil {
+			return err
+		}
+
+		err = replaySession(conn, session)
+		conn.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Finished replaying %s", filepath)
+
+	return nil
+}
+
+/**
+ * Connect to Footle's DBGp port.
+ *
+ * Footle may not be listening yet.  So we try a few times.
+ */
+func dial(address string) (conn net.Conn, err error) {
+
+	for attempt := 0; attempt < dialAttempts; attempt++ {
+		if conn, err = net.Dial("tcp", address); err == nil {
+			return conn, nil
+		}
+
+		time.Sleep(dialInterval)
+	}
+
+	return conn, err
+}
+
+/**
+ * Play the part of the DBGp engine for a single connection.
+ *
+ * Ends when Footle disconnects or when the recorded engine has disconnected.
+ * A recording that simply runs out keeps the connection open.  Further
+ * commands then receive error responses.
+ */
+func replaySession(conn io.ReadWriter, session []Entry) (err error) {
+
+	// The init packet and anything else before the first command.
+	cursor, err := sendRecordedPackets(conn, session, 0, "", 0)
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+
+	for !isDisconnection(session, cursor) {
+		DBGpCmd, err := reader.ReadString('\x00')
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		cmdName := command.ExtractCmdName(DBGpCmd)
+		TxId, _ := command.ExtractTxId(DBGpCmd)
+
+		recordedCmdPosition := findRecordedCmd(session, cursor, cmdName)
+		if recordedCmdPosition < 0 {
+			if err = writePacket(conn, prepareNotRecordedResponse(cmdName, TxId)); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		recordedTxIdAttr := ""
+		if recordedTxId, err := command.ExtractTxId(session[recordedCmdPosition].Packet); err == nil {
+			recordedTxIdAttr = fmt.Sprintf(`transaction_id="%d"`, recordedTxId)
+		}
+
+		cursor, err = sendRecordedPackets(conn, session, recordedCmdPosition+1, recordedTxIdAttr, TxId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/**
+ * Has the recorded DBGp engine disconnected at this position?
+ */
+func isDisconnection(session []Entry, position int) bool {
+
+	return position < len(session) && session[position].Direction == Disconnect
+}
+
+/**
+ * Send recorded DBGp engine packets until the next recorded command or
+ * disconnection.
+ *
+ * Responses carrying the recorded transaction ID are given Footle's
+ * transaction ID instead.
+ *
+ * Returns the position of the next recorded command or disconnection.
+ */
+func sendRecordedPackets(conn io.Writer, session []Entry, position int, recordedTxIdAttr string, TxId int) (nextPosition int, err error) {
+
+	for ; position < len(session) && session[position].Direction == Recv; position++ {
+		packet := session[position].Packet
+
+		if recordedTxIdAttr != "" {
+			packet = strings.Replace(packet, recordedTxIdAttr, fmt.Sprintf(`transaction_id="%d"`, TxId), 1)
+		}
+
+		if err = writePacket(conn, packet); err != nil {
+			return position, err
+		}
+	}
+
+	return position, nil
+}
+
+/**
+ * Position of the next recorded command with the given name.
+ *
+ * -1 when there is none.
+ */
+func findRecordedCmd(session []Entry, position int, cmdName string) int {
+
+	for ; position < len(session); position++ {
+		if session[position].Direction == Send && command.ExtractCmdName(session[position].Packet) == cmdName {
+			return position
+		}
+	}
+
+	return -1
+}
+
+/**
+ * Write a DBGp packet the way a DBGp engine does.
+ *
+ * Format: DATA-LENGTH NULL XML NULL
+ */
+func writePacket(conn io.Writer, packet string) (err error) {
+
+	_, err = fmt.Fprintf(conn, "%d\x00%s\x00", len(packet), packet)
+
+	return err
+}
+
+/**
+ * Error response for a command that is absent from the recording.
+ */
+func prepareNotRecordedResponse(cmdName string, TxId int) (packet string) {
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="iso-8859-1"?>
+<response xmlns="urn:debugger_protocol_v1" command="%s" transaction_id="%d"><error code="%d"><message><![CDATA[Not found in the recording.]]></message></error></response>`, cmdName, TxId, notRecordedErrorCode)
+}
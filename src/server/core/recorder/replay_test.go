@@ -0,0 +1,99 @@
+package recorder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"server/dbgp"
+	"strings"
+	"testing"
+)
+
+/**
+ * Tests for replaySession().
+ *
+ * Footle's end of the connection reads the init packet, issues a recorded and
+ * an unrecorded command, and then checks the responses.
+ */
+func TestReplaySession(t *testing.T) {
+
+	session := []Entry{
+		{Direction: Recv, Packet: `<init fileuri="file:///foo/index.php"/>`},
+		{Direction: Send, Packet: "status -i 1"},
+		{Direction: Recv, Packet: `<response command="status" transaction_id="1" status="starting"/>`},
+		{Direction: Send, Packet: "run -i 2"},
+		{Direction: Recv, Packet: `<response command="run" transaction_id="2" status="break"/>`},
+		{Direction: Disconnect},
+	}
+
+	footleEnd, engineEnd := net.Pipe()
+	defer footleEnd.Close()
+
+	replayErrors := make(chan error)
+	go func() {
+		replayErrors <- replaySession(engineEnd, session)
+		engineEnd.Close()
+	}()
+
+	if packet, err := dbgp.Read(footleEnd); err != nil || !strings.HasPrefix(packet, "<init") {
+		t.Fatalf("Expected init packet, got: %s, %v", packet, err)
+	}
+
+	// Unrecorded command.
+	fmt.Fprintf(footleEnd, "eval -i 6 -- Zm9v\x00")
+	if packet, err := dbgp.Read(footleEnd); err != nil || !strings.Contains(packet, `transaction_id="6"`) || !strings.Contains(packet, "<error") {
+		t.Errorf("Expected error response, got: %s, %v", packet, err)
+	}
+
+	// The status command is skipped.  Replay carries on from "run".
+	fmt.Fprintf(footleEnd, "run -i 7\x00")
+	if packet, err := dbgp.Read(footleEnd); err != nil || packet != `<response command="run" transaction_id="7" status="break"/>` {
+		t.Errorf("Expected recorded run response, got: %s, %v", packet, err)
+	}
+
+	// The recorded engine has disconnected.
+	if err := <-replayErrors; err != nil {
+		t.Error(err)
+	}
+}
+
+/**
+ * Tests for Load().
+ *
+ * Each connection of the DBGp engine becomes its own session.
+ */
+func TestLoad(t *testing.T) {
+
+	tmpDir, err := ioutil.TempDir("", "footle-replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sessionFile := filepath.Join(tmpDir, "session.jsonl")
+	content := `{"direction":"connect"}
+{"direction":"recv","packet":"<init/>"}
+{"direction":"disconnect"}
+{"direction":"connect"}
+{"direction":"recv","packet":"<init/>"}
+{"direction":"send","packet":"status -i 1"}
+`
+	if err := ioutil.WriteFile(sessionFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := Load(sessionFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if 2 != len(sessions) || 2 != len(sessions[0]) || 2 != len(sessions[1]) {
+		t.Fatalf("Wrong sessions: %v", sessions)
+	}
+
+	if !isDisconnection(sessions[0], 1) || isDisconnection(sessions[1], 1) {
+		t.Errorf("Misplaced disconnection: %v", sessions)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"server/config"
 	conn "server/core/connection"
+	"server/core/recorder"
 	"server/dbgp"
 	"server/dbgp/message"
 )
@@ -24,16 +25,20 @@ func RecvMsgsFromDBGpEngine(DBGpConnection *conn.Connection, DBGpMessages chan<-
 
 		activeDBGpConnection := DBGpConnection.Connect()
 
-		if *activeDBGpConnection == nil {
+		if activeDBGpConnection == nil {
 			continue
 		}
 
+		recorder.Record(recorder.Connect, "")
+
 		for {
-			msg, err := dbgp.Read(*activeDBGpConnection)
+			msg, err := dbgp.Read(activeDBGpConnection)
 			if len(msg) == 0 || nil != err {
 				break
 			}
 
+			recorder.Record(recorder.Recv, msg)
+
 			if parsedMsg, err := message.Decode(msg); nil == err {
 				DBGpMessages <- parsedMsg
 			}
@@ -43,6 +48,7 @@ func RecvMsgsFromDBGpEngine(DBGpConnection *conn.Connection, DBGpMessages chan<-
 			}
 		}
 
+		recorder.Record(recorder.Disconnect, "")
 		DBGpConnection.Disconnect()
 	}
 }
@@ -8,10 +8,23 @@ import (
 	"log"
 	"server/config"
 	conn "server/core/connection"
+	"server/core/recorder"
+	"server/core/tracker"
+	"server/dbgp/command"
+	"server/dbgp/message"
 )
 
 /**
  * Send DBGp command to DBGp engine (e.g. Xdebug).
+ *
+ * While the script is running, only asynchronous commands (e.g. break) are
+ * sent.  The rest are held back until execution stops.
+ *
+ * Commands sent to the engine start their response timeout.  Commands that
+ * cannot be sent for the lack of a debugging session are settled with an error.
+ *
+ * @see executionState
+ * @see tracker.Dispatch()
  */
 func SendCmdsToDBGpEngine(DBGpConnection *conn.Connection, in <-chan string) {
 
@@ -19,19 +32,46 @@ func SendCmdsToDBGpEngine(DBGpConnection *conn.Connection, in <-chan string) {
 
 	for DBGpCmd := range in {
 		connection := DBGpConnection.Get()
+		DBGpCmdName := command.ExtractCmdName(DBGpCmd)
+
+		if !command.IsAsync(DBGpCmdName) && execution.holdIfRunning(DBGpCmd) {
+			if config.IsVerbose() {
+				log.Printf("Holding back until execution stops: %s", DBGpCmd)
+			}
+
+			continue
+		}
 
 		if DBGpConnection.IsOnAir() {
 			if config.IsVerbose() {
 				log.Println(DBGpCmd)
 			}
 
-			_, err := (*connection).Write([]byte(DBGpCmd))
+			// Note down the state change *before* the engine has a chance to
+			// respond.
+			if command.IsContinuation(DBGpCmdName) {
+				execution.run()
+			} else if DBGpCmdName == "break" {
+				execution.interrupt()
+			}
+
+			_, err := connection.Write([]byte(DBGpCmd))
 
 			if nil != err {
 				log.Fatal(err)
 			}
+
+			recorder.Record(recorder.Send, DBGpCmd)
+
+			if TxId, err := command.ExtractTxId(DBGpCmd); err == nil {
+				tracker.Dispatch(TxId)
+			}
 		} else {
 			log.Println("Cannot speak to an inactive connection.")
+
+			if TxId, err := command.ExtractTxId(DBGpCmd); err == nil {
+				tracker.Fail(TxId, message.NoSessionErrorCode, "No active debugging session.")
+			}
 		}
 	}
 }
@@ -0,0 +1,127 @@
+/**
+ * @file
+ * Warn the UIs when the DBGp engine runs a different copy of a file.
+ *
+ * At the first break in a file, the engine's copy is fetched with the DBGp
+ * "source" command.  The response is ours alone and does not reach the UIs.
+ * From then on, the local copy is compared with the engine's copy at every
+ * break in that file.
+ *
+ * @see divergence
+ */
+
+package core
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"server/config"
+	"server/core/divergence"
+	"server/dbgp/command"
+	"server/dbgp/message"
+	"strings"
+)
+
+/**
+ * Files whose engine copy is on its way, keyed by transaction ID.
+ *
+ * Only used by the goroutine running ProcessDBGpMessages().
+ */
+var awaitedSources = make(map[int]string)
+
+/**
+ * Check the file of a break against the local copy.
+ *
+ * Returns a warning for the UIs when the two are newly found to differ.
+ */
+func checkSource(breakMsg message.Message, DBGpCmds chan string) (warning message.Message, hasWarning bool) {
+
+	fileUri := breakMsg.Properties.Filename
+	if breakMsg.MessageType != "response" || breakMsg.State != "break" || !strings.HasPrefix(fileUri, "file://") {
+		return warning, false
+	}
+
+	if divergence.IsKnown(fileUri) {
+		return compareSource(fileUri)
+	}
+
+	for _, awaitedFileUri := range awaitedSources {
+		if awaitedFileUri == fileUri {
+			return warning, false
+		}
+	}
+
+	sourceCmd, TxId, err := command.PrepareWTxId("source", []string{fileUri})
+	if err != nil {
+		return warning, false
+	}
+
+	awaitedSources[TxId] = fileUri
+	DBGpCmds <- sourceCmd
+
+	return warning, false
+}
+
+/**
+ * Is this the engine's copy of a file we have asked for?
+ */
+func isAwaitedSource(msg message.Message) bool {
+
+	_, isAwaited := awaitedSources[msg.Properties.TxId]
+
+	return msg.Properties.Command == "source" && isAwaited
+}
+
+/**
+ * Keep the engine's copy of a file and compare it with the local copy.
+ *
+ * Files the engine refuses to share are left alone.
+ */
+func takeSource(sourceMsg message.Message) (warning message.Message, hasWarning bool) {
+
+	fileUri := awaitedSources[sourceMsg.Properties.TxId]
+	delete(awaitedSources, sourceMsg.Properties.TxId)
+
+	src, err := base64.StdEncoding.DecodeString(sourceMsg.Content)
+	if sourceMsg.Properties.ErrorCode != 0 || err != nil {
+		divergence.Remember(fileUri, nil)
+		return warning, false
+	}
+
+	divergence.Remember(fileUri, src)
+
+	return compareSource(fileUri)
+}
+
+/**
+ * Prepare a warning if the local copy of a file differs from the engine's.
+ */
+func compareSource(fileUri string) (warning message.Message, hasWarning bool) {
+
+	config := config.Get()
+
+	isDiverged, isNews := divergence.Compare(fileUri, config.ToLocalPath(fileUri))
+	if !isDiverged || !isNews {
+		return warning, false
+	}
+
+	filename := strings.TrimPrefix(fileUri, "file://")
+	if relativePath, err := filepath.Rel(config.DetermineCodeDir(), filename); err == nil && !strings.HasPrefix(relativePath, "..") {
+		filename = relativePath
+	}
+
+	warning = prepareFakeMsg(message.Properties{Command: "diverged", Filename: fileUri}, "")
+	warning.Properties.Notice = fmt.Sprintf("The DBGp engine is running a different copy of %s.  Breaks and breakpoints may be on the wrong lines.", filename)
+
+	return warning, true
+}
+
+/**
+ * Start afresh with a new debugging session.
+ */
+func forgetSources() {
+
+	awaitedSources = make(map[int]string)
+	divergence.Forget()
+}
@@ -0,0 +1,66 @@
+/**
+ * @file
+ * Commands from UIs along with where they have come from.
+ */
+
+package tracker
+
+import (
+	"server/dbgp/message"
+)
+
+/**
+ * A command issued by a UI.
+ *
+ * Origin identifies the UI client.  Examples: "cli", "http:3f2a".  When Reply
+ * is present, the response to the command is delivered there as well.
+ */
+type Request struct {
+	Cmd    string
+	Origin string
+	Reply  chan message.Message
+}
+
+/**
+ * A command whose response is only broadcast.
+ */
+func New(cmd, origin string) Request {
+
+	return Request{Cmd: cmd, Origin: origin}
+}
+
+/**
+ * A command whose issuer is going to wait for the response.
+ */
+func NewSync(cmd, origin string) Request {
+
+	return Request{Cmd: cmd, Origin: origin, Reply: make(chan message.Message, 1)}
+}
+
+/**
+ * Is anyone waiting for the response?
+ */
+func (r Request) IsSync() bool {
+
+	return r.Reply != nil
+}
+
+/**
+ * Deliver the response to whoever is waiting for it.
+ *
+ * Only the first answer counts.  Later answers are dropped rather than
+ * blocking the caller.
+ */
+func (r Request) Answer(msg message.Message) {
+
+	if !r.IsSync() {
+		return
+	}
+
+	msg.Origin = r.Origin
+
+	select {
+	case r.Reply <- msg:
+	default:
+	}
+}
@@ -0,0 +1,192 @@
+/**
+ * @file
+ * Match DBGp responses with the UI requests that have caused them.
+ *
+ * Each DBGp command carries a transaction ID which the DBGp engine repeats in
+ * its response.  We note down the request behind each transaction ID and
+ * settle it when the response arrives.  Requests that stay unanswered for too
+ * long are settled with an error message.
+ */
+
+package tracker
+
+import (
+	"server/dbgp/command"
+	"server/dbgp/message"
+	"sync"
+	"time"
+)
+
+/**
+ * How long to wait for a response once a command has reached the DBGp engine.
+ */
+const ResponseTimeout = 10 * time.Second
+
+/**
+ * How often to look for overdue requests.
+ */
+const timeoutCheckInterval = time.Second
+
+type record struct {
+	request  Request
+	cmdName  string
+	deadline time.Time // Zero until the command is sent.
+}
+
+type requestTracker struct {
+	sync.Mutex
+
+	records map[int]*record
+}
+
+/**
+ * Requests awaiting a response, keyed by transaction ID.
+ *
+ * Shared between the goroutines that process commands, send them, and process
+ * messages.
+ */
+var tracker = requestTracker{records: make(map[int]*record)}
+
+/**
+ * Note down the request behind a DBGp command.
+ */
+func Track(TxId int, cmdName string, request Request) {
+
+	tracker.Lock()
+	defer tracker.Unlock()
+
+	tracker.records[TxId] = &record{request: request, cmdName: cmdName}
+}
+
+/**
+ * Start the clock for a DBGp command that has just been sent.
+ *
+ * Commands that resume execution (e.g. run) only receive a response at the
+ * next break which could be a long time away.  So these never time out.
+ */
+func Dispatch(TxId int) {
+
+	tracker.Lock()
+	defer tracker.Unlock()
+
+	r, exists := tracker.records[TxId]
+	if !exists || command.IsContinuation(r.cmdName) {
+		return
+	}
+
+	r.deadline = time.Now().Add(ResponseTimeout)
+}
+
+/**
+ * Find and forget the request behind a DBGp response.
+ *
+ * It is up to the caller to answer the request once the response has been
+ * processed.
+ */
+func Settle(msg message.Message) (request Request, isTracked bool) {
+
+	if msg.MessageType != "response" || msg.Properties.TxId == 0 {
+		return request, false
+	}
+
+	tracker.Lock()
+	r, isTracked := tracker.records[msg.Properties.TxId]
+	delete(tracker.records, msg.Properties.TxId)
+	tracker.Unlock()
+
+	if !isTracked {
+		return request, false
+	}
+
+	return r.request, true
+}
+
+/**
+ * Settle a request with an error.
+ */
+func Fail(TxId, errorCode int, errorMessage string) (errorMsg message.Message, isTracked bool) {
+
+	tracker.Lock()
+	r, isTracked := tracker.records[TxId]
+	delete(tracker.records, TxId)
+	tracker.Unlock()
+
+	if !isTracked {
+		return errorMsg, false
+	}
+
+	errorMsg = message.PrepareErrorMsg(r.cmdName, TxId, errorCode, errorMessage)
+	errorMsg.Origin = r.request.Origin
+	r.request.Answer(errorMsg)
+
+	return errorMsg, true
+}
+
+/**
+ * Settle all outstanding requests with an error.
+ *
+ * Useful when the debugging session is over and no response is coming.
+ */
+func FailAll(errorCode int, errorMessage string) {
+
+	for _, TxId := range outstanding(func(r *record) bool { return true }) {
+		Fail(TxId, errorCode, errorMessage)
+	}
+}
+
+/**
+ * Settle the overdue requests with a timeout error.
+ *
+ * Returns the error messages so that these can be broadcast.
+ */
+func Expire(now time.Time) (errorMsgs []message.Message) {
+
+	isOverdue := func(r *record) bool {
+		return !r.deadline.IsZero() && now.After(r.deadline)
+	}
+
+	for _, TxId := range outstanding(isOverdue) {
+		errorMsg, isTracked := Fail(TxId, message.TimeoutErrorCode, "No response from the DBGp engine.")
+
+		if isTracked {
+			errorMsgs = append(errorMsgs, errorMsg)
+		}
+	}
+
+	return errorMsgs
+}
+
+/**
+ * Keep settling overdue requests.
+ *
+ * Timeout errors are written to the given channel like any other DBGp
+ * message so that the UIs get to hear about them.
+ */
+func WatchTimeouts(DBGpMessages chan<- message.Message) {
+
+	ticker := time.NewTicker(timeoutCheckInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		for _, errorMsg := range Expire(now) {
+			DBGpMessages <- errorMsg
+		}
+	}
+}
+
+/**
+ * Transaction IDs of the outstanding requests that match the given filter.
+ */
+func outstanding(filter func(*record) bool) (TxIds []int) {
+
+	tracker.Lock()
+	defer tracker.Unlock()
+
+	for TxId, r := range tracker.records {
+		if filter(r) {
+			TxIds = append(TxIds, TxId)
+		}
+	}
+
+	return TxIds
+}
@@ -0,0 +1,73 @@
+/**
+ * @file
+ * Tests for matching DBGp responses with UI requests.
+ */
+
+package tracker
+
+import (
+	"server/dbgp/message"
+	"testing"
+	"time"
+)
+
+/**
+ * Tests for settling a request with its response.
+ */
+func TestSettle(t *testing.T) {
+
+	request := NewSync("stack_get", "http:foo")
+	Track(101, "stack_get", request)
+	Dispatch(101)
+
+	response := message.Message{MessageType: "response"}
+	response.Properties = message.Properties{Command: "stack_get", TxId: 101}
+
+	settled, isTracked := Settle(response)
+	if !isTracked || settled.Origin != "http:foo" {
+		t.Fatalf("Failed to match response with its request: %v", settled)
+	}
+
+	settled.Answer(response)
+	if reply := <-request.Reply; reply.Origin != "http:foo" || reply.Properties.TxId != 101 {
+		t.Errorf("Unexpected reply: %v", reply)
+	}
+
+	if _, isTracked = Settle(response); isTracked {
+		t.Error("A request has been settled twice.")
+	}
+}
+
+/**
+ * Tests for timing out requests that receive no response.
+ */
+func TestExpire(t *testing.T) {
+
+	request := NewSync("stack_get", "cli")
+	Track(201, "stack_get", request)
+	Track(202, "run", New("run", "cli"))
+	Track(203, "context_get", New("context_get", "cli"))
+
+	Dispatch(201)
+	Dispatch(202)
+	// 203 is never sent.
+
+	if errorMsgs := Expire(time.Now()); len(errorMsgs) != 0 {
+		t.Errorf("Requests have expired too early: %v", errorMsgs)
+	}
+
+	errorMsgs := Expire(time.Now().Add(ResponseTimeout + time.Second))
+	if len(errorMsgs) != 1 || errorMsgs[0].Properties.TxId != 201 {
+		t.Fatalf("Expected only the stack_get request to expire, got: %v", errorMsgs)
+	}
+
+	reply := <-request.Reply
+	if reply.Properties.ErrorCode != message.TimeoutErrorCode || reply.Properties.Command != "stack_get" {
+		t.Errorf("Unexpected reply for a timed out request: %v", reply)
+	}
+
+	FailAll(message.SessionEndErrorCode, "Debugging session has ended.")
+	if TxIds := outstanding(func(*record) bool { return true }); len(TxIds) != 0 {
+		t.Errorf("Requests still outstanding: %v", TxIds)
+	}
+}
@@ -0,0 +1,345 @@
+/**
+ * @file
+ * Watch the codebase for file changes.
+ *
+ * Whenever a file with breakpoints or a file the UIs have displayed is saved,
+ * the "update_source" Footle command is issued for it.  The resulting message
+ * tells the UIs to reload the file.  Breakpoints in the file are also moved to
+ * follow their statements.  The file and symbol indexes are refreshed for all
+ * saved as well as deleted files.
+ *
+ * Editors often write a file several times in quick succession when saving.  So
+ * changes to each file are debounced.  Files ignored by .gitignore files or by
+ * the exclude list are left alone.
+ *
+ * File change notifications from the OS (e.g. inotify) are preferred.  When
+ * these are unavailable, or when we run out of inotify watches, the codebase
+ * is polled instead.
+ */
+
+package watcher
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"server/codebase"
+	"server/config"
+	"server/core/breakpoint"
+	"server/core/tracker"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+/**
+ * Identifies the watcher as the issuer of "update_source" commands.
+ */
+const Origin = "watcher"
+
+/**
+ * How long a file has to stay unchanged before we announce its change.
+ */
+const debounceDelay = 300 * time.Millisecond
+
+/**
+ * The longest we keep quiet about a file that never stops changing.
+ */
+const maxDebounceWait = 3 * time.Second
+
+/**
+ * How often to look for changes when polling.
+ */
+const pollInterval = 2 * time.Second
+
+/**
+ * Files the UIs have displayed, relative to the codebase and slash separated.
+ *
+ * @see NoteDisplayed()
+ */
+var displayed = struct {
+	sync.Mutex
+	files map[string]bool
+}{files: make(map[string]bool)}
+
+/**
+ * Issue "update_source" commands for changed files in the codebase.
+ *
+ * Runs until Footle exits.
+ */
+func Watch(codeDir string, CmdsFromUI chan tracker.Request) {
+
+	changes := make(chan string)
+	filter := codebase.NewFilter(codeDir, config.Get().GetExcludes())
+
+	go debounce(changes, debounceDelay, maxDebounceWait, func(path string) {
+		relativePath, err := filepath.Rel(codeDir, path)
+		if err != nil {
+			return
+		}
+
+		codebase.RefreshIndex(relativePath)
+
+		// Deleted files have nothing to reload.  Footle commands are split at
+		// whitespace.
+		if _, err := os.Stat(path); err != nil || strings.ContainsAny(relativePath, " \t\n") || !isOfInterest(relativePath) {
+			return
+		}
+
+		CmdsFromUI <- tracker.New("update_source "+filepath.ToSlash(relativePath), Origin)
+	})
+
+	if err := notify(codeDir, filter, changes); err != nil {
+		log.Printf("File change notifications are unavailable (%s).  Polling the codebase instead.", err)
+		poll(codeDir, filter, pollInterval, changes, nil)
+	}
+}
+
+/**
+ * Note down a file the UIs have displayed.
+ *
+ * Changes to this file will reach the UIs from now on.  The path is relative
+ * to the codebase and slash separated.
+ */
+func NoteDisplayed(relativePath string) {
+
+	displayed.Lock()
+	defer displayed.Unlock()
+
+	displayed.files[relativePath] = true
+}
+
+/**
+ * Do the UIs care about changes to this file?
+ *
+ * They do when the file has breakpoints or when they have displayed it.
+ */
+func isOfInterest(relativePath string) bool {
+
+	displayed.Lock()
+	isDisplayed := displayed.files[filepath.ToSlash(relativePath)]
+	displayed.Unlock()
+
+	if isDisplayed {
+		return true
+	}
+
+	fileUri := "file://" + filepath.Join(config.Get().DetermineCodeDir(), relativePath)
+
+	return breakpoint.IsSetIn(fileUri)
+}
+
+/**
+ * Report file changes as notified by the OS.
+ *
+ * Returns an error when notifications cannot be set up.  Otherwise never
+ * returns.
+ */
+func notify(codeDir string, filter *codebase.Filter, changes chan<- string) (err error) {
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	if err = watchTree(watcher, codeDir, filter, codeDir); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	for {
+		select {
+		case event := <-watcher.Events:
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+
+			if fileInfo, err := os.Stat(event.Name); err != nil {
+				// Deleted or renamed away.
+				if !isIgnored(filter, codeDir, event.Name, false) {
+					changes <- event.Name
+				}
+			} else if fileInfo.IsDir() {
+				// New directory.
+				if isIgnored(filter, codeDir, event.Name, true) {
+					continue
+				}
+
+				if err := watchTree(watcher, codeDir, filter, event.Name); err != nil {
+					log.Println(err)
+				}
+			} else if !isIgnored(filter, codeDir, event.Name, false) {
+				changes <- event.Name
+			}
+		case err := <-watcher.Errors:
+			log.Println(err)
+		}
+	}
+}
+
+/**
+ * Ask for notifications from a directory and all its subdirectories.
+ *
+ * Notifications are per directory.  So each directory is watched separately.
+ */
+func watchTree(watcher *fsnotify.Watcher, codeDir string, filter *codebase.Filter, root string) (err error) {
+
+	return filepath.Walk(root, func(path string, fileInfo os.FileInfo, err error) error {
+
+		if err != nil || !fileInfo.IsDir() {
+			return nil
+		}
+
+		if path != root && isIgnored(filter, codeDir, path, true) {
+			return filepath.SkipDir
+		}
+
+		return watcher.Add(path)
+	})
+}
+
+/**
+ * Report file changes by scanning the codebase every now and then.
+ *
+ * Runs until the stop channel is closed.
+ */
+func poll(codeDir string, filter *codebase.Filter, interval time.Duration, changes chan<- string, stop <-chan struct{}) {
+
+	knownFiles := scan(codeDir, filter)
+
+	for {
+		select {
+		case <-time.After(interval):
+		case <-stop:
+			return
+		}
+
+		currentFiles := scan(codeDir, filter)
+
+		for path, fileInfo := range currentFiles {
+			if knownFileInfo, isKnown := knownFiles[path]; !isKnown || !fileInfo.ModTime().Equal(knownFileInfo.ModTime()) || fileInfo.Size() != knownFileInfo.Size() {
+				changes <- path
+			}
+		}
+
+		for path := range knownFiles {
+			if _, isPresent := currentFiles[path]; !isPresent {
+				changes <- path
+			}
+		}
+
+		knownFiles = currentFiles
+	}
+}
+
+/**
+ * Details of all the files in the codebase that we care about.
+ */
+func scan(codeDir string, filter *codebase.Filter) (files map[string]os.FileInfo) {
+
+	files = make(map[string]os.FileInfo)
+
+	filepath.Walk(codeDir, func(path string, fileInfo os.FileInfo, err error) error {
+
+		if err != nil {
+			return nil
+		}
+
+		if path != codeDir && isIgnored(filter, codeDir, path, fileInfo.IsDir()) {
+			if fileInfo.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !fileInfo.IsDir() {
+			files[path] = fileInfo
+		}
+
+		return nil
+	})
+
+	return files
+}
+
+/**
+ * Report each changed file once it has stopped changing.
+ *
+ * Each file is debounced on its own.  A file that keeps changing is still
+ * reported once it has been changing for maxWait.
+ */
+func debounce(changes <-chan string, delay, maxWait time.Duration, report func(path string)) {
+
+	firstChanges := make(map[string]time.Time)
+	lastChanges := make(map[string]time.Time)
+	var timeout <-chan time.Time
+
+	for {
+		select {
+		case path, isOpen := <-changes:
+			if !isOpen {
+				return
+			}
+
+			if _, isChanging := firstChanges[path]; !isChanging {
+				firstChanges[path] = time.Now()
+			}
+
+			lastChanges[path] = time.Now()
+		case <-timeout:
+			now := time.Now()
+
+			for path, firstChange := range firstChanges {
+				if !dueAt(firstChange, lastChanges[path], delay, maxWait).After(now) {
+					report(path)
+
+					delete(firstChanges, path)
+					delete(lastChanges, path)
+				}
+			}
+		}
+
+		timeout = nil
+		if len(firstChanges) == 0 {
+			continue
+		}
+
+		var nextDue time.Time
+		for path, firstChange := range firstChanges {
+			if due := dueAt(firstChange, lastChanges[path], delay, maxWait); nextDue.IsZero() || due.Before(nextDue) {
+				nextDue = due
+			}
+		}
+
+		timeout = time.After(time.Until(nextDue))
+	}
+}
+
+/**
+ * When to report a changed file.
+ */
+func dueAt(firstChange, lastChange time.Time, delay, maxWait time.Duration) time.Time {
+
+	due := lastChange.Add(delay)
+	if deadline := firstChange.Add(maxWait); deadline.Before(due) {
+		return deadline
+	}
+
+	return due
+}
+
+/**
+ * Should this file or directory be left alone?
+ */
+func isIgnored(filter *codebase.Filter, codeDir, path string, isDir bool) bool {
+
+	relativePath, err := filepath.Rel(codeDir, path)
+	if err != nil || strings.HasPrefix(relativePath, "..") {
+		return false
+	}
+
+	return filter.IsIgnored(filepath.ToSlash(relativePath), isDir)
+}
@@ -0,0 +1,197 @@
+package watcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"server/codebase"
+	"testing"
+	"time"
+)
+
+/**
+ * Tests for debounce().
+ *
+ * Repeated changes to a file should be reported once.
+ */
+func TestDebounce(t *testing.T) {
+
+	changes := make(chan string)
+	reports := make(chan string, 10)
+
+	go debounce(changes, 20*time.Millisecond, time.Second, func(path string) {
+		reports <- path
+	})
+	defer close(changes)
+
+	changes <- "foo.php"
+	changes <- "foo.php"
+	changes <- "bar.php"
+
+	reported := map[string]int{}
+	for i := 0; i < 2; i++ {
+		select {
+		case path := <-reports:
+			reported[path]++
+		case <-time.After(time.Second):
+			t.Fatal("Changes have not been reported.")
+		}
+	}
+
+	select {
+	case path := <-reports:
+		t.Errorf("Change reported twice: %s", path)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if reported["foo.php"] != 1 || reported["bar.php"] != 1 {
+		t.Errorf("Unexpected reports: %v", reported)
+	}
+}
+
+/**
+ * Tests for debounce().
+ *
+ * A file that keeps changing should hold back neither itself nor other files
+ * for long.
+ */
+func TestDebounceBusyFile(t *testing.T) {
+
+	changes := make(chan string)
+	reports := make(chan string, 10)
+
+	go debounce(changes, 20*time.Millisecond, 200*time.Millisecond, func(path string) {
+		reports <- path
+	})
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go func() {
+		defer close(changes)
+
+		for {
+			select {
+			case changes <- "busy.log":
+				time.Sleep(5 * time.Millisecond)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	changes <- "foo.php"
+
+	deadline := time.After(time.Second)
+	reported := map[string]bool{}
+	for !reported["foo.php"] || !reported["busy.log"] {
+		select {
+		case path := <-reports:
+			if !reported["busy.log"] && path == "busy.log" && !reported["foo.php"] {
+				t.Error("The busy file should not be reported before the quiet one.")
+			}
+
+			reported[path] = true
+		case <-deadline:
+			t.Fatalf("Changes have not been reported: %v", reported)
+		}
+	}
+}
+
+/**
+ * Tests for poll().
+ */
+func TestPoll(t *testing.T) {
+
+	codeDir, err := ioutil.TempDir("", "footle-watcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(codeDir)
+
+	os.Mkdir(filepath.Join(codeDir, "vendor"), 0755)
+	ioutil.WriteFile(filepath.Join(codeDir, "index.php"), []byte("<?php"), 0644)
+
+	filter := codebase.NewFilter(codeDir, []string{"vendor/"})
+
+	changes := make(chan string, 10)
+	stop := make(chan struct{})
+	go poll(codeDir, filter, 10*time.Millisecond, changes, stop)
+	defer close(stop)
+
+	time.Sleep(30 * time.Millisecond)
+	ioutil.WriteFile(filepath.Join(codeDir, "vendor", "lib.php"), []byte("<?php"), 0644)
+	ioutil.WriteFile(filepath.Join(codeDir, "index.php"), []byte("<?php echo 1;"), 0644)
+
+	select {
+	case path := <-changes:
+		if path != filepath.Join(codeDir, "index.php") {
+			t.Errorf("Unexpected change: %s", path)
+		}
+	case <-time.After(time.Second):
+		t.Error("Change has not been noticed.")
+	}
+
+	os.Remove(filepath.Join(codeDir, "index.php"))
+
+	select {
+	case path := <-changes:
+		if path != filepath.Join(codeDir, "index.php") {
+			t.Errorf("Unexpected change: %s", path)
+		}
+	case <-time.After(time.Second):
+		t.Error("Deletion has not been noticed.")
+	}
+}
+
+/**
+ * Tests for isIgnored().
+ *
+ * The exclude list and .gitignore files decide.
+ */
+func TestIsIgnored(t *testing.T) {
+
+	codeDir, err := ioutil.TempDir("", "footle-watcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(codeDir)
+
+	ioutil.WriteFile(filepath.Join(codeDir, ".gitignore"), []byte("node_modules/\n*.log\n"), 0644)
+	filter := codebase.NewFilter(codeDir, []string{"vendor/"})
+
+	cases := map[string]bool{
+		"index.php":                  false,
+		"vendor/autoload.php":        true,
+		"web/node_modules/x/y.js":    true,
+		".git/HEAD":                  true,
+		"error.log":                  true,
+		"modules/vendors/vendor.php": false,
+	}
+
+	for path, expected := range cases {
+		if expected != isIgnored(filter, codeDir, filepath.Join(codeDir, filepath.FromSlash(path)), false) {
+			t.Errorf("Expected %v for %s", expected, path)
+		}
+	}
+
+	if isIgnored(filter, codeDir, "/elsewhere/vendor/lib.php", false) {
+		t.Error("Files outside the codebase should not be ignored.")
+	}
+}
+
+/**
+ * Tests for isOfInterest().
+ */
+func TestIsOfInterest(t *testing.T) {
+
+	if isOfInterest("never/displayed.php") {
+		t.Error("Unexpected interest in a file without breakpoints.")
+	}
+
+	NoteDisplayed("src/displayed.php")
+
+	if !isOfInterest(filepath.FromSlash("src/displayed.php")) {
+		t.Error("Displayed files should be of interest.")
+	}
+}
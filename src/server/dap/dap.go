@@ -0,0 +1,100 @@
+/**
+ * @file
+ * Debug Adapter Protocol interface for the debugger.
+ *
+ * Lets editors such as VS Code or Neovim use Footle as their PHP debugger.
+ * This is a third UI next to the command line and HTTP UIs.  All three share
+ * the same breakpoints and debugging session.
+ *
+ * DAP clients connect over TCP.  Example VS Code launch configuration:
+ *   {"type": "php", "request": "attach", "debugServer": 4711}
+ */
+
+package dap
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net"
+	"server/config"
+	"server/core/tracker"
+	"server/dbgp/message"
+	"sync"
+)
+
+/**
+ * Identifies commands issued by DAP clients.
+ *
+ * Each DAP client connection appends its own number.  Example: "dap:2"
+ */
+const Origin = "dap"
+
+/**
+ * The DAP client being served.  Only one at a time.
+ */
+var current *session
+var currentLock sync.Mutex
+
+/**
+ * Serve DAP clients one after another.
+ */
+func Listen(out chan tracker.Request, conf config.Config) {
+
+	address := fmt.Sprintf(":%d", conf.GetDAPPort())
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	paths := pathMapper{localDir: conf.GetCodebase(), engineDir: conf.DetermineCodeDir()}
+
+	for clientNo := 1; ; clientNo++ {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		serve(conn, out, prepareOrigin(clientNo), paths)
+	}
+}
+
+/**
+ * Talk to a DAP client until it departs.
+ */
+func serve(conn net.Conn, out chan tracker.Request, origin string, paths pathMapper) {
+
+	defer conn.Close()
+
+	s := newSession(conn, out, origin, paths)
+
+	currentLock.Lock()
+	current = s
+	currentLock.Unlock()
+
+	s.serve(bufio.NewReader(conn))
+
+	currentLock.Lock()
+	current = nil
+	currentLock.Unlock()
+}
+
+/**
+ * Pass on DBGp messages to the DAP client.
+ *
+ * Messages are dropped when there is no DAP client.
+ */
+func TellDAPClient(in <-chan message.Message) {
+
+	for msg := range in {
+		currentLock.Lock()
+		s := current
+		currentLock.Unlock()
+
+		if s != nil {
+			s.notify(msg)
+		}
+	}
+}
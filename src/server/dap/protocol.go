@@ -0,0 +1,108 @@
+/**
+ * @file
+ * Wire format of the Debug Adapter Protocol.
+ *
+ * Each DAP message is a JSON object preceded by a Content-Length header:
+ *
+ *   Content-Length: 119\r\n
+ *   \r\n
+ *   {"seq": 1, "type": "request", "command": "initialize", ...}
+ *
+ * @see https://microsoft.github.io/debug-adapter-protocol/specification
+ */
+
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Largest DAP message we are willing to read.
+ */
+const maxMessageSize = 1024 * 1024
+
+type request struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type response struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	RequestSeq int         `json:"request_seq"`
+	Success    bool        `json:"success"`
+	Command    string      `json:"command"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+type event struct {
+	Seq   int         `json:"seq"`
+	Type  string      `json:"type"`
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+/**
+ * Read the next DAP message.
+ */
+func readMessage(reader *bufio.Reader) (req request, err error) {
+
+	contentLength := -1
+
+	// Headers end with an empty line.
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return req, err
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" && contentLength >= 0 {
+			break
+		} else if line == "" {
+			continue
+		}
+
+		headerParts := strings.SplitN(line, ":", 2)
+		if len(headerParts) == 2 && strings.TrimSpace(headerParts[0]) == "Content-Length" {
+			contentLength, err = strconv.Atoi(strings.TrimSpace(headerParts[1]))
+
+			if err != nil || contentLength < 0 || contentLength > maxMessageSize {
+				return req, fmt.Errorf("Invalid DAP Content-Length header: %s", line)
+			}
+		}
+	}
+
+	content := make([]byte, contentLength)
+	if _, err = io.ReadFull(reader, content); err != nil {
+		return req, err
+	}
+
+	err = json.Unmarshal(content, &req)
+
+	return req, err
+}
+
+/**
+ * Write a DAP message.
+ */
+func writeMessage(writer io.Writer, msg interface{}) (err error) {
+
+	content, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(writer, "Content-Length: %d\r\n\r\n%s", len(content), content)
+
+	return err
+}
@@ -0,0 +1,684 @@
+/**
+ * @file
+ * A conversation with a single DAP client.
+ */
+
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"server/core/tracker"
+	"server/dbgp/message"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/**
+ * Number of responses and events a DAP client can fall behind.
+ *
+ * A client that falls further behind is disconnected.  Dropping messages
+ * instead would leave it waiting for responses that never come.
+ */
+const outboxSize = 64
+
+/**
+ * What a variablesReference stands for.
+ *
+ * Either a whole context at a stack depth, a variable whose children have
+ * already been fetched, or a variable whose children are yet to be fetched.
+ */
+type varRef struct {
+	contextName string // local or global.
+	depth       int
+	fullname    string // Empty for a whole context.
+	children    []message.Variable
+}
+
+type session struct {
+	sendLock   sync.Mutex
+	seq        int
+	outbox     chan interface{}
+	conn       io.WriteCloser
+	hangUpOnce sync.Once
+	writerDone chan struct{}
+
+	out    chan<- tracker.Request
+	origin string
+	paths  pathMapper
+	closed chan struct{}
+
+	refLock sync.Mutex
+	refs    map[int]varRef
+	lastRef int
+
+	lineLock      sync.Mutex
+	resolvedLines map[string]map[int]int // Requested line -> line of the breakpoint, per file.
+}
+
+/**
+ * Prepare a session for a newly connected DAP client.
+ */
+func newSession(conn io.WriteCloser, out chan<- tracker.Request, origin string, paths pathMapper) *session {
+
+	return &session{
+		outbox:     make(chan interface{}, outboxSize),
+		conn:       conn,
+		writerDone: make(chan struct{}),
+		out:        out,
+		origin:     origin,
+		paths:      paths,
+		closed:     make(chan struct{}),
+		refs:       make(map[int]varRef),
+
+		resolvedLines: make(map[string]map[int]int),
+	}
+}
+
+/**
+ * Serve DAP requests until the client departs.
+ *
+ * Each request is handled in its own goroutine.  Otherwise a request waiting
+ * for a break (e.g. stackTrace while the script is running) would stop us from
+ * hearing about a "pause" request.  Responses and events are written by yet
+ * another goroutine so that a slow client holds up no one.
+ */
+func (s *session) serve(reader *bufio.Reader) {
+
+	go s.write()
+
+	defer func() {
+		close(s.closed)
+		<-s.writerDone
+	}()
+
+	for {
+		req, err := readMessage(reader)
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			log.Println(err)
+			return
+		}
+
+		if req.Type != "request" {
+			continue
+		}
+
+		if req.Command == "disconnect" {
+			s.respond(req, nil)
+			return
+		}
+
+		go s.handle(req)
+	}
+}
+
+/**
+ * Act on a DAP request.
+ */
+func (s *session) handle(req request) {
+
+	var body interface{}
+	var err error
+
+	switch req.Command {
+	case "initialize":
+		body = map[string]bool{
+			"supportsConfigurationDoneRequest": true,
+			"supportsEvaluateForHovers":        true,
+		}
+
+		s.respond(req, body)
+		s.emit("initialized", nil)
+		return
+
+	case "launch", "attach", "configurationDone":
+		// Footle is already waiting for the DBGp engine.
+
+	case "threads":
+		body = map[string]interface{}{
+			"threads": []map[string]interface{}{{"id": threadId, "name": "PHP"}},
+		}
+
+	case "setBreakpoints":
+		body, err = s.setBreakpoints(req.Arguments)
+
+	case "stackTrace":
+		body, err = s.stackTrace()
+
+	case "scopes":
+		body, err = s.scopes(req.Arguments)
+
+	case "variables":
+		body, err = s.variables(req.Arguments)
+
+	case "evaluate":
+		body, err = s.evaluate(req.Arguments)
+
+	case "next":
+		s.tell("step_over")
+
+	case "stepIn":
+		s.tell("step_into")
+
+	case "stepOut":
+		s.tell("step_out")
+
+	case "continue":
+		s.tell("run")
+		body = map[string]bool{"allThreadsContinued": true}
+
+	case "pause":
+		s.tell("break")
+
+	default:
+		err = fmt.Errorf("Unsupported request: %s", req.Command)
+	}
+
+	if err != nil {
+		s.fail(req, err)
+	} else {
+		s.respond(req, body)
+	}
+}
+
+/**
+ * Replace all breakpoints of a file.
+ *
+ * Breakpoints are shared with the other UIs.  So we compare with the current
+ * breakpoint list and only add or remove the difference.  Outside a debugging
+ * session, breakpoints are pending and hence unverified.
+ *
+ * Breakpoints may end up on a different line than requested.  The client keeps
+ * asking for the requested line though.  So we remember where each breakpoint
+ * has ended up.
+ */
+func (s *session) setBreakpoints(arguments json.RawMessage) (body interface{}, err error) {
+
+	var args struct {
+		Source      source
+		Breakpoints []struct {
+			Line int
+		}
+	}
+
+	if err = json.Unmarshal(arguments, &args); err != nil {
+		return body, err
+	}
+
+	listing, err := s.ask("breakpoint_list")
+	if err != nil {
+		return body, err
+	}
+
+	existingIds := make(map[int]int)
+	for _, breakpointRecord := range listing.Breakpoints {
+		if s.paths.toLocal(breakpointRecord.Filename) == args.Source.Path {
+			existingIds[breakpointRecord.LineNo] = breakpointRecord.Id
+		}
+	}
+
+	wantedLines := make(map[int]bool)
+	for _, wanted := range args.Breakpoints {
+		wantedLines[wanted.Line] = true
+		wantedLines[s.resolveLine(args.Source.Path, wanted.Line)] = true
+	}
+
+	for lineNo, breakpointId := range existingIds {
+		if !wantedLines[lineNo] {
+			s.ask(fmt.Sprintf("breakpoint_remove %d", breakpointId))
+		}
+	}
+
+	footlePath := s.paths.toFootle(args.Source.Path)
+	breakpoints := []map[string]interface{}{}
+	breakpointIds := []int{}
+	isOnAir := false
+
+	for _, wanted := range args.Breakpoints {
+		lineNo := s.resolveLine(args.Source.Path, wanted.Line)
+		breakpointId, exists := existingIds[lineNo]
+
+		if !exists {
+			response, err := s.ask(fmt.Sprintf("breakpoint_set %s %d", footlePath, wanted.Line))
+
+			if err != nil {
+				breakpoints = append(breakpoints, map[string]interface{}{"verified": false, "line": wanted.Line, "message": err.Error()})
+				breakpointIds = append(breakpointIds, 0)
+				continue
+			}
+
+			// Pending breakpoints may have been moved to a line that can carry them.
+			breakpointId = response.Properties.BreakpointId
+			if response.Properties.LineNumber > 0 {
+				lineNo = response.Properties.LineNumber
+			}
+
+			isOnAir = isOnAir || breakpointId > 0
+		}
+
+		// Pending breakpoints have no ID from the DBGp engine yet.
+		isVerified := breakpointId > 0
+		breakpoints = append(breakpoints, map[string]interface{}{"verified": isVerified, "line": lineNo, "source": args.Source})
+		breakpointIds = append(breakpointIds, breakpointId)
+	}
+
+	// The DBGp engine may resolve new breakpoints to a different line.
+	if isOnAir {
+		if listing, err := s.ask("breakpoint_list"); err == nil {
+			for i, breakpointId := range breakpointIds {
+				if breakpointRecord, exists := listing.Breakpoints[breakpointId]; exists && breakpointId > 0 {
+					breakpoints[i]["line"] = breakpointRecord.LineNo
+				}
+			}
+		}
+	}
+
+	for i, wanted := range args.Breakpoints {
+		s.noteResolvedLine(args.Source.Path, wanted.Line, breakpoints[i]["line"].(int))
+	}
+
+	return map[string]interface{}{"breakpoints": breakpoints}, err
+}
+
+/**
+ * Line a breakpoint requested for the given line has ended up on.
+ */
+func (s *session) resolveLine(path string, requestedLine int) (lineNo int) {
+
+	s.lineLock.Lock()
+	defer s.lineLock.Unlock()
+
+	if lineNo, exists := s.resolvedLines[path][requestedLine]; exists {
+		return lineNo
+	}
+
+	return requestedLine
+}
+
+/**
+ * Remember where a breakpoint requested for the given line has ended up.
+ */
+func (s *session) noteResolvedLine(path string, requestedLine, lineNo int) {
+
+	s.lineLock.Lock()
+	defer s.lineLock.Unlock()
+
+	if s.resolvedLines[path] == nil {
+		s.resolvedLines[path] = make(map[int]int)
+	}
+
+	s.resolvedLines[path][requestedLine] = lineNo
+}
+
+/**
+ * Fetch the call stack.
+ */
+func (s *session) stackTrace() (body interface{}, err error) {
+
+	response, err := s.ask("stack_get")
+	if err != nil {
+		return body, err
+	}
+
+	frames := toStackFrames(response.Stacktrace, s.paths)
+
+	return map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)}, err
+}
+
+/**
+ * Local and global variables of a stack frame.
+ */
+func (s *session) scopes(arguments json.RawMessage) (body interface{}, err error) {
+
+	var args struct {
+		FrameId int
+	}
+
+	if err = json.Unmarshal(arguments, &args); err != nil {
+		return body, err
+	}
+
+	depth := toStackDepth(args.FrameId)
+	scopes := []map[string]interface{}{
+		{"name": "Locals", "variablesReference": s.newRef(varRef{contextName: "local", depth: depth}), "expensive": false},
+		{"name": "Globals", "variablesReference": s.newRef(varRef{contextName: "global", depth: depth}), "expensive": true},
+	}
+
+	return map[string]interface{}{"scopes": scopes}, err
+}
+
+/**
+ * Variables behind a variablesReference.
+ */
+func (s *session) variables(arguments json.RawMessage) (body interface{}, err error) {
+
+	var args struct {
+		VariablesReference int
+	}
+
+	if err = json.Unmarshal(arguments, &args); err != nil {
+		return body, err
+	}
+
+	ref, exists := s.lookupRef(args.VariablesReference)
+	if !exists {
+		return body, fmt.Errorf("Unknown variables reference: %d", args.VariablesReference)
+	}
+
+	vars := ref.children
+
+	if ref.fullname == "" && vars == nil {
+		response, err := s.ask(fmt.Sprintf("context_get %s %d", ref.contextName, ref.depth))
+		if err != nil {
+			return body, err
+		}
+
+		vars = response.Context.Local
+		if ref.contextName == "global" {
+			vars = response.Context.Global
+		}
+	} else if vars == nil {
+		response, err := s.ask(fmt.Sprintf("property_get %s %s", ref.contextName, ref.fullname))
+		if err != nil {
+			return body, err
+		}
+
+		if len(response.Context.Local) > 0 {
+			vars = response.Context.Local[0].Children
+		}
+	}
+
+	variables := []variable{}
+	for _, v := range vars {
+		variables = append(variables, s.toVariable(v, ref.contextName, ref.depth))
+	}
+
+	return map[string]interface{}{"variables": variables}, err
+}
+
+/**
+ * Value of an expression.
+ *
+ * The expression is run by the DBGp engine's eval command, so any PHP
+ * expression will do.
+ */
+func (s *session) evaluate(arguments json.RawMessage) (body interface{}, err error) {
+
+	var args struct {
+		Expression string
+	}
+
+	if err = json.Unmarshal(arguments, &args); err != nil {
+		return body, err
+	}
+
+	expression := strings.TrimSpace(args.Expression)
+	if expression == "" {
+		return body, fmt.Errorf("Nothing to evaluate.")
+	}
+
+	response, err := s.ask("eval " + expression)
+	if err != nil {
+		return body, err
+	}
+
+	if len(response.Context.Local) == 0 {
+		return body, fmt.Errorf("Cannot evaluate %s", expression)
+	}
+
+	// The result of eval has no name.  Children are fetched by the expression.
+	value := response.Context.Local[0]
+	if value.Fullname == "" {
+		value.Fullname = expression
+	}
+
+	result := s.toVariable(value, "local", 0)
+
+	return map[string]interface{}{"result": result.Value, "type": result.Type, "variablesReference": result.VariablesReference}, err
+}
+
+/**
+ * Turn a DBGp variable into a DAP variable.
+ *
+ * Composite variables receive a variablesReference for fetching their
+ * children later.
+ */
+func (s *session) toVariable(v message.Variable, contextName string, depth int) variable {
+
+	result := variable{
+		Name:         v.DisplayName,
+		Value:        describeValue(v),
+		Type:         v.VarType,
+		EvaluateName: v.Fullname,
+	}
+
+	if v.IsCompositeType && v.ChildCount > 0 {
+		ref := varRef{contextName: contextName, depth: depth, fullname: v.Fullname}
+		if v.HasLoadedChildren {
+			ref.children = v.Children
+		}
+
+		result.VariablesReference = s.newRef(ref)
+	}
+
+	return result
+}
+
+/**
+ * Act on a message broadcast to all UIs.
+ *
+ * Execution state changes are turned into DAP events.  Notices for all UIs are
+ * shown as output.
+ */
+func (s *session) notify(msg message.Message) {
+
+	isOwnError := (msg.Origin == s.origin && msg.Properties.ErrorCode != 0)
+
+	if msg.MessageType == "response" && msg.State == "break" && msg.Properties.Filename != "" {
+		s.forgetRefs()
+		s.emit("stopped", map[string]interface{}{"reason": stopReason(msg.Properties.Command), "threadId": threadId, "allThreadsStopped": true})
+	} else if msg.State == "running" {
+		s.forgetRefs()
+		s.emit("continued", map[string]interface{}{"threadId": threadId, "allThreadsContinued": true})
+	} else if msg.State == "starting" {
+		s.emit("thread", map[string]interface{}{"reason": "started", "threadId": threadId})
+	} else if msg.State == "stopped" || msg.State == "detached" {
+		s.forgetRefs()
+		s.emit("thread", map[string]interface{}{"reason": "exited", "threadId": threadId})
+	} else if isOwnError {
+		output := fmt.Sprintf("%s: %s\n", msg.Properties.Command, msg.Properties.ErrorMessage)
+		s.emit("output", map[string]string{"category": "stderr", "output": output})
+	} else if msg.Properties.Notice != "" && msg.Origin == "" {
+		// Notices meant for everyone, e.g. a file that differs from the engine's.
+		s.emit("output", map[string]string{"category": "console", "output": msg.Properties.Notice + "\n"})
+	}
+}
+
+/**
+ * Issue a command and wait for its response.
+ *
+ * DBGp errors are returned as errors.
+ */
+func (s *session) ask(cmd string) (response message.Message, err error) {
+
+	request := tracker.NewSync(cmd, s.origin)
+
+	select {
+	case s.out <- request:
+	case <-s.closed:
+		return response, fmt.Errorf("The DAP client has gone.")
+	}
+
+	select {
+	case response = <-request.Reply:
+	case <-s.closed:
+		return response, fmt.Errorf("The DAP client has gone.")
+	}
+
+	if response.Properties.ErrorCode != 0 {
+		return response, fmt.Errorf("%s", response.Properties.ErrorMessage)
+	}
+
+	return response, err
+}
+
+/**
+ * Issue a command without waiting for its response.
+ *
+ * Useful for commands that resume execution.  Their responses only arrive at
+ * the next break which is announced through the "stopped" event.
+ */
+func (s *session) tell(cmd string) {
+
+	select {
+	case s.out <- tracker.New(cmd, s.origin):
+	case <-s.closed:
+	}
+}
+
+/**
+ * Hand out a new variablesReference.
+ */
+func (s *session) newRef(ref varRef) (refId int) {
+
+	s.refLock.Lock()
+	defer s.refLock.Unlock()
+
+	s.lastRef++
+	s.refs[s.lastRef] = ref
+
+	return s.lastRef
+}
+
+/**
+ * Find what a variablesReference stands for.
+ */
+func (s *session) lookupRef(refId int) (ref varRef, exists bool) {
+
+	s.refLock.Lock()
+	defer s.refLock.Unlock()
+
+	ref, exists = s.refs[refId]
+
+	return ref, exists
+}
+
+/**
+ * Variables references are only valid while execution is stopped.
+ */
+func (s *session) forgetRefs() {
+
+	s.refLock.Lock()
+	defer s.refLock.Unlock()
+
+	s.refs = make(map[int]varRef)
+}
+
+/**
+ * Send a successful response.
+ */
+func (s *session) respond(req request, body interface{}) {
+
+	s.send(&response{Type: "response", RequestSeq: req.Seq, Success: true, Command: req.Command, Body: body})
+}
+
+/**
+ * Send a failure response.
+ */
+func (s *session) fail(req request, err error) {
+
+	s.send(&response{Type: "response", RequestSeq: req.Seq, Success: false, Command: req.Command, Message: err.Error()})
+}
+
+/**
+ * Send an event.
+ */
+func (s *session) emit(eventName string, body interface{}) {
+
+	s.send(&event{Type: "event", Event: eventName, Body: body})
+}
+
+/**
+ * Number and queue a response or event.
+ *
+ * Never waits for the client.  A client whose queue is full is disconnected.
+ */
+func (s *session) send(msg interface{}) {
+
+	s.sendLock.Lock()
+	defer s.sendLock.Unlock()
+
+	s.seq++
+
+	switch m := msg.(type) {
+	case *response:
+		m.Seq = s.seq
+	case *event:
+		m.Seq = s.seq
+	}
+
+	select {
+	case s.outbox <- msg:
+	default:
+		s.hangUp("Disconnecting a DAP client that has stopped listening.")
+	}
+}
+
+/**
+ * Write queued responses and events until the session ends.
+ *
+ * Whatever is queued by then is still written, e.g. the response to the
+ * "disconnect" request.
+ */
+func (s *session) write() {
+
+	defer close(s.writerDone)
+
+	for {
+		select {
+		case msg := <-s.outbox:
+			if err := writeMessage(s.conn, msg); err != nil {
+				s.hangUp(err.Error())
+				return
+			}
+		case <-s.closed:
+			for {
+				select {
+				case msg := <-s.outbox:
+					if err := writeMessage(s.conn, msg); err != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+/**
+ * Close the connection to the client.
+ *
+ * Ends the session as the client can no longer be read from.
+ */
+func (s *session) hangUp(reason string) {
+
+	s.hangUpOnce.Do(func() {
+		log.Println(reason)
+		s.conn.Close()
+	})
+}
+
+/**
+ * Identifies commands issued by a DAP client.
+ */
+func prepareOrigin(clientNo int) string {
+
+	return Origin + ":" + strconv.Itoa(clientNo)
+}
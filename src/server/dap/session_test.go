@@ -0,0 +1,223 @@
+/**
+ * @file
+ * Tests for conversing with a DAP client.
+ */
+
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"server/core/tracker"
+	"server/dbgp/message"
+	"strings"
+	"testing"
+	"time"
+)
+
+/**
+ * Tests for a typical conversation.
+ *
+ * Plays the role of the debugger core by answering commands.
+ */
+func TestSession(t *testing.T) {
+
+	clientEnd, serverEnd := net.Pipe()
+	defer clientEnd.Close()
+
+	commands := make(chan tracker.Request)
+	paths := pathMapper{localDir: "/home/foo", engineDir: "/home/foo"}
+	s := newSession(serverEnd, commands, prepareOrigin(1), paths)
+	go s.serve(bufio.NewReader(serverEnd))
+
+	clientReader := bufio.NewReader(clientEnd)
+	clientEnd.SetDeadline(time.Now().Add(2 * time.Second))
+
+	// Initialization.
+	writeMessage(clientEnd, request{Seq: 1, Type: "request", Command: "initialize"})
+
+	initResponse, err := readMessage(clientReader)
+	if err != nil || initResponse.Type != "response" || initResponse.Command != "initialize" {
+		t.Errorf("Unexpected response to initialize: %v, %v", initResponse, err)
+	}
+
+	if initEvent, err := readMessage(clientReader); err != nil || initEvent.Type != "event" {
+		t.Errorf("Expected the initialized event: %v, %v", initEvent, err)
+	}
+
+	// Stack trace.
+	writeMessage(clientEnd, request{Seq: 2, Type: "request", Command: "stackTrace"})
+
+	stackRequest := <-commands
+	if stackRequest.Cmd != "stack_get" || !strings.HasPrefix(stackRequest.Origin, Origin) {
+		t.Errorf("Unexpected command for stackTrace: %v", stackRequest)
+	}
+
+	stackMsg := message.Message{MessageType: "response"}
+	stackMsg.Stacktrace = []message.StackLevel{{Level: 0, Filename: "file:///home/foo/index.php", LineNo: 3, Where: "{main}"}}
+	stackRequest.Answer(stackMsg)
+
+	var stackResponse struct {
+		Success bool
+		Body    struct {
+			StackFrames []stackFrame
+		}
+	}
+
+	if err = decodeNext(clientReader, &stackResponse); err != nil || !stackResponse.Success || len(stackResponse.Body.StackFrames) != 1 || stackResponse.Body.StackFrames[0].Source.Path != "/home/foo/index.php" {
+		t.Errorf("Unexpected stackTrace response: %v, %v", stackResponse, err)
+	}
+
+	// Stepping results in the "stopped" event at the next break.
+	writeMessage(clientEnd, request{Seq: 3, Type: "request", Command: "next"})
+
+	if stepRequest := <-commands; stepRequest.Cmd != "step_over" {
+		t.Errorf("Unexpected command for next: %v", stepRequest)
+	}
+
+	if nextResponse, err := readMessage(clientReader); err != nil || nextResponse.Command != "next" {
+		t.Errorf("Unexpected response to next: %v, %v", nextResponse, err)
+	}
+
+	breakMsg := message.Message{MessageType: "response", State: "break"}
+	breakMsg.Properties = message.Properties{Command: "step_over", Filename: "file:///home/foo/index.php", LineNumber: 4}
+	go s.notify(breakMsg)
+
+	var stoppedEvent struct {
+		Event string
+		Body  struct {
+			Reason string
+		}
+	}
+
+	if err = decodeNext(clientReader, &stoppedEvent); err != nil || stoppedEvent.Event != "stopped" || stoppedEvent.Body.Reason != "step" {
+		t.Errorf("Unexpected event after stepping: %v, %v", stoppedEvent, err)
+	}
+
+	// Breakpoints are reported on the line the DBGp engine has resolved them to.
+	writeMessage(clientEnd, request{Seq: 4, Type: "request", Command: "setBreakpoints", Arguments: json.RawMessage(`{"source": {"path": "/home/foo/index.php"}, "breakpoints": [{"line": 5}]}`)})
+
+	(<-commands).Answer(message.Message{MessageType: "response"})
+
+	if setRequest := <-commands; setRequest.Cmd != "breakpoint_set index.php 5" {
+		t.Errorf("Unexpected command for setBreakpoints: %v", setRequest)
+	} else {
+		setRequest.Answer(message.Message{MessageType: "response", Properties: message.Properties{BreakpointId: 7}})
+	}
+
+	listing := message.Message{MessageType: "response", Breakpoints: map[int]message.Breakpoint{7: {Filename: "file:///home/foo/index.php", LineNo: 6, Id: 7}}}
+	(<-commands).Answer(listing)
+
+	var breakpointsResponse struct {
+		Body struct {
+			Breakpoints []struct {
+				Verified bool
+				Line     int
+			}
+		}
+	}
+
+	if err = decodeNext(clientReader, &breakpointsResponse); err != nil || len(breakpointsResponse.Body.Breakpoints) != 1 || breakpointsResponse.Body.Breakpoints[0].Line != 6 || !breakpointsResponse.Body.Breakpoints[0].Verified {
+		t.Errorf("Unexpected setBreakpoints response: %v, %v", breakpointsResponse, err)
+	}
+
+	// Asking again for the same line keeps the moved breakpoint as it is.
+	writeMessage(clientEnd, request{Seq: 5, Type: "request", Command: "setBreakpoints", Arguments: json.RawMessage(`{"source": {"path": "/home/foo/index.php"}, "breakpoints": [{"line": 5}]}`)})
+
+	if listRequest := <-commands; listRequest.Cmd != "breakpoint_list" {
+		t.Errorf("Unexpected command for setBreakpoints: %v", listRequest)
+	} else {
+		listRequest.Answer(listing)
+	}
+
+	if err = decodeNext(clientReader, &breakpointsResponse); err != nil || len(breakpointsResponse.Body.Breakpoints) != 1 || breakpointsResponse.Body.Breakpoints[0].Line != 6 {
+		t.Errorf("Unexpected setBreakpoints response for a moved breakpoint: %v, %v", breakpointsResponse, err)
+	}
+
+	// Expressions are evaluated by the DBGp engine.
+	writeMessage(clientEnd, request{Seq: 6, Type: "request", Command: "evaluate", Arguments: json.RawMessage(`{"expression": "$x + 1"}`)})
+
+	evalRequest := <-commands
+	if evalRequest.Cmd != "eval $x + 1" {
+		t.Errorf("Unexpected command for evaluate: %v", evalRequest)
+	}
+
+	evalMsg := message.Message{MessageType: "response"}
+	evalMsg.Context.Local = []message.Variable{{VarType: "int", Value: "3"}}
+	evalRequest.Answer(evalMsg)
+
+	var evalResponse struct {
+		Success bool
+		Body    struct {
+			Result string
+		}
+	}
+
+	if err = decodeNext(clientReader, &evalResponse); err != nil || !evalResponse.Success || evalResponse.Body.Result != "3" {
+		t.Errorf("Unexpected evaluate response: %v, %v", evalResponse, err)
+	}
+}
+
+/**
+ * A client that stops reading holds up no one and is disconnected.
+ */
+func TestSlowClient(t *testing.T) {
+
+	clientEnd, serverEnd := net.Pipe()
+	defer clientEnd.Close()
+
+	s := newSession(serverEnd, make(chan tracker.Request), prepareOrigin(1), pathMapper{})
+	served := make(chan struct{})
+	go func() {
+		s.serve(bufio.NewReader(serverEnd))
+		close(served)
+	}()
+
+	notified := make(chan struct{})
+	go func() {
+		for i := 0; i <= outboxSize+1; i++ {
+			s.notify(message.Message{State: "starting"})
+		}
+		close(notified)
+	}()
+
+	select {
+	case <-notified:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Notifying a slow client has blocked.")
+	}
+
+	select {
+	case <-served:
+	case <-time.After(2 * time.Second):
+		t.Fatal("The slow client has not been disconnected.")
+	}
+}
+
+/**
+ * Decode the next DAP message into the given value.
+ */
+func decodeNext(reader *bufio.Reader, v interface{}) (err error) {
+
+	header, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+
+	var contentLength int
+	if _, err = fmt.Sscanf(header, "Content-Length: %d", &contentLength); err != nil {
+		return err
+	}
+
+	reader.ReadString('\n')
+
+	content := make([]byte, contentLength)
+	if _, err = io.ReadFull(reader, content); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(content, v)
+}
@@ -0,0 +1,158 @@
+/**
+ * @file
+ * Translation between DBGp messages and DAP structures.
+ */
+
+package dap
+
+import (
+	"fmt"
+	"path/filepath"
+	"server/dbgp/message"
+	"strings"
+)
+
+/**
+ * PHP has a single thread as far as DAP is concerned.
+ */
+const threadId = 1
+
+type source struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path"`
+}
+
+type stackFrame struct {
+	Id     int    `json:"id"`
+	Name   string `json:"name"`
+	Source source `json:"source"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+type variable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	Type               string `json:"type,omitempty"`
+	EvaluateName       string `json:"evaluateName,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+/**
+ * Converts between DAP client paths and DBGp engine file URIs.
+ *
+ * DAP clients run in Footle's machine and use local absolute paths.  The DBGp
+ * engine may be in another machine where the codebase lives elsewhere.
+ *
+ * @see config.DetermineCodeDir()
+ */
+type pathMapper struct {
+	localDir  string
+	engineDir string
+}
+
+/**
+ * Local path for a file URI from the DBGp engine.
+ *
+ * Example: file:///var/www/html/index.php -> /home/foo/bar/index.php
+ */
+func (p pathMapper) toLocal(fileUri string) (localPath string) {
+
+	enginePath := strings.TrimPrefix(fileUri, "file://")
+
+	relativePath, err := filepath.Rel(p.engineDir, enginePath)
+	if err != nil || strings.HasPrefix(relativePath, "..") {
+		return enginePath
+	}
+
+	return filepath.Join(p.localDir, relativePath)
+}
+
+/**
+ * Filepath fit for Footle commands.
+ *
+ * Footle resolves relative paths against the DBGp engine's codebase.  So paths
+ * inside the local codebase are made relative.
+ */
+func (p pathMapper) toFootle(localPath string) (footlePath string) {
+
+	relativePath, err := filepath.Rel(p.localDir, localPath)
+	if err != nil || strings.HasPrefix(relativePath, "..") {
+		return localPath
+	}
+
+	return relativePath
+}
+
+/**
+ * Turn a DBGp stack trace into DAP stack frames.
+ *
+ * Frame IDs are stack levels plus one as DAP clients treat zero as absence.
+ */
+func toStackFrames(stacktrace []message.StackLevel, paths pathMapper) (frames []stackFrame) {
+
+	frames = []stackFrame{}
+
+	for _, level := range stacktrace {
+		localPath := paths.toLocal(level.Filename)
+
+		frames = append(frames, stackFrame{
+			Id:     level.Level + 1,
+			Name:   level.Where,
+			Source: source{Name: filepath.Base(localPath), Path: localPath},
+			Line:   level.LineNo,
+			Column: 1,
+		})
+	}
+
+	return frames
+}
+
+/**
+ * Stack depth for a DAP frame ID.
+ *
+ * @see toStackFrames()
+ */
+func toStackDepth(frameId int) (depth int) {
+
+	if frameId < 1 {
+		return 0
+	}
+
+	return frameId - 1
+}
+
+/**
+ * Displayable value of a variable.
+ *
+ * Composite types only show their type and size.  Example: array(3)
+ */
+func describeValue(v message.Variable) (value string) {
+
+	if v.IsCompositeType {
+		return fmt.Sprintf("%s(%d)", v.VarType, v.ChildCount)
+	} else if v.VarType == "string" {
+		return fmt.Sprintf("%q", v.Value)
+	} else if v.VarType == "null" || v.VarType == "uninitialized" {
+		return v.VarType
+	}
+
+	return v.Value
+}
+
+/**
+ * DAP stop reason for a break.
+ *
+ * The command that has led to the break tells us why execution has stopped.
+ */
+func stopReason(cmd string) (reason string) {
+
+	switch cmd {
+	case "break":
+		return "pause"
+	case "step_into", "step_over", "step_out":
+		return "step"
+	}
+
+	return "breakpoint"
+}
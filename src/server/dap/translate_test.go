@@ -0,0 +1,79 @@
+/**
+ * @file
+ * Tests for translating between DBGp messages and DAP structures.
+ */
+
+package dap
+
+import (
+	"server/dbgp/message"
+	"testing"
+)
+
+/**
+ * Tests for pathMapper.
+ */
+func TestPathMapper(t *testing.T) {
+
+	paths := pathMapper{localDir: "/home/foo/bar", engineDir: "/var/www/html"}
+
+	if localPath := paths.toLocal("file:///var/www/html/index.php"); localPath != "/home/foo/bar/index.php" {
+		t.Errorf("toLocal() = %s", localPath)
+	}
+
+	if localPath := paths.toLocal("file:///usr/share/php/qux.php"); localPath != "/usr/share/php/qux.php" {
+		t.Errorf("toLocal() = %s for a file outside the codebase", localPath)
+	}
+
+	if footlePath := paths.toFootle("/home/foo/bar/core/index.php"); footlePath != "core/index.php" {
+		t.Errorf("toFootle() = %s", footlePath)
+	}
+
+	if footlePath := paths.toFootle("/tmp/qux.php"); footlePath != "/tmp/qux.php" {
+		t.Errorf("toFootle() = %s for a file outside the codebase", footlePath)
+	}
+}
+
+/**
+ * Tests for toStackFrames().
+ */
+func TestToStackFrames(t *testing.T) {
+
+	paths := pathMapper{localDir: "/home/foo", engineDir: "/home/foo"}
+	stacktrace := []message.StackLevel{
+		{Level: 0, Filename: "file:///home/foo/lib.php", LineNo: 7, Where: "qux"},
+		{Level: 1, Filename: "file:///home/foo/index.php", LineNo: 3, Where: "{main}"},
+	}
+
+	frames := toStackFrames(stacktrace, paths)
+
+	if len(frames) != 2 || frames[0].Id != 1 || frames[0].Source.Path != "/home/foo/lib.php" || frames[0].Line != 7 {
+		t.Errorf("toStackFrames() = %v", frames)
+	}
+
+	if depth := toStackDepth(frames[1].Id); depth != 1 {
+		t.Errorf("toStackDepth(%d) = %d", frames[1].Id, depth)
+	}
+}
+
+/**
+ * Tests for describeValue().
+ */
+func TestDescribeValue(t *testing.T) {
+
+	testCases := []struct {
+		input    message.Variable
+		expected string
+	}{
+		{message.Variable{VarType: "int", Value: "5"}, "5"},
+		{message.Variable{VarType: "string", Value: "foo"}, `"foo"`},
+		{message.Variable{VarType: "array", IsCompositeType: true, ChildCount: 3}, "array(3)"},
+		{message.Variable{VarType: "null"}, "null"},
+	}
+
+	for _, test := range testCases {
+		if value := describeValue(test.input); value != test.expected {
+			t.Errorf("describeValue(%v) = %s", test.input, value)
+		}
+	}
+}
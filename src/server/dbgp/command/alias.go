@@ -13,23 +13,24 @@ import "strings"
  * Mapping between DBGp commands and their aliases.
  */
 var shortCmdFullCmdMap map[string]string = map[string]string{
-	"b":   "breakpoint_set",
-	"bg":  "breakpoint_get",
-	"br":  "breakpoint_remove",
-	"bl":  "breakpoint_list",
-	"vl":  "context_get",
-	"ev":  "eval",
-	"var": "property_get",
-	"r":   "run",
-	"stk": "stack_get",
-	"sr":  "source",
-	"src": "source",
-	"s":   "status",
-	"si":  "step_into",
-	"so":  "step_out",
-	"sv":  "step_over",
-	"sov": "step_over",
-	"st":  "stop",
+	"b":     "breakpoint_set",
+	"bg":    "breakpoint_get",
+	"br":    "breakpoint_remove",
+	"bl":    "breakpoint_list",
+	"vl":    "context_get",
+	"ev":    "eval",
+	"var":   "property_get",
+	"r":     "run",
+	"pause": "break",
+	"stk":   "stack_get",
+	"sr":    "source",
+	"src":   "source",
+	"s":     "status",
+	"si":    "step_into",
+	"so":    "step_out",
+	"sv":    "step_over",
+	"sov":   "step_over",
+	"st":    "stop",
 }
 
 /**
@@ -6,6 +6,7 @@ package command
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -58,6 +59,72 @@ func Prepare(shortCmd string, cmdArgs []string) (DBGpCmd string, err error) {
 	return DBGpCmd, err
 }
 
+/**
+ * Same as Prepare() but also tells the transaction ID of the DBGp command.
+ */
+func PrepareWTxId(shortCmd string, cmdArgs []string) (DBGpCmd string, TxId int, err error) {
+
+	if err = Validate(shortCmd, cmdArgs); nil != err {
+		return DBGpCmd, TxId, err
+	}
+
+	DBGpCmd, TxId, err = PrepareDBGpCmdWTxId(shortCmd, cmdArgs)
+
+	return DBGpCmd, TxId, err
+}
+
+/**
+ * Extract the command name from a full DBGp command.
+ *
+ * Example: "run -i 9\x00" -> "run"
+ */
+func ExtractCmdName(DBGpCmd string) (cmdName string) {
+
+	cmdParts := strings.Fields(strings.TrimRight(DBGpCmd, "\x00"))
+
+	if len(cmdParts) > 0 {
+		cmdName = cmdParts[0]
+	}
+
+	return cmdName
+}
+
+/**
+ * Find the transaction ID in a full DBGp command.
+ *
+ * The transaction ID is the value of the "-i" option.  Anything after "--" is
+ * data (e.g. PHP code for eval) and is ignored.  When "-i" appears more than
+ * once, which is possible in raw DBGp commands, the last one wins as that is
+ * the one we have added.
+ *
+ * Example: "eval -i 7 -- JGZvbw==\x00" -> 7
+ */
+func ExtractTxId(DBGpCmd string) (TxId int, err error) {
+
+	cmdParts := strings.Fields(strings.TrimRight(DBGpCmd, "\x00"))
+	hasTxId := false
+
+	for i := 0; i < len(cmdParts)-1; i++ {
+		if cmdParts[i] == "--" {
+			break
+		} else if cmdParts[i] != "-i" {
+			continue
+		}
+
+		if TxId, err = strconv.Atoi(cmdParts[i+1]); err != nil {
+			return TxId, fmt.Errorf("Invalid transaction ID in DBGp command: %s", DBGpCmd)
+		}
+
+		hasTxId = true
+	}
+
+	if !hasTxId {
+		return TxId, fmt.Errorf("No transaction ID in DBGp command: %s", DBGpCmd)
+	}
+
+	return TxId, err
+}
+
 /**
  * Given a DBGp command, extract the command name.
  *
@@ -33,3 +33,47 @@ func TestExtract(t *testing.T) {
 		t.Errorf("Extract(%s) = %s", failCase, DBGpCmdName)
 	}
 }
+
+/**
+ * Tests for ExtractCmdName().
+ */
+func TestExtractCmdName(t *testing.T) {
+
+	if name := ExtractCmdName("run -i 9\x00"); name != "run" {
+		t.Errorf("Expected run, got %s", name)
+	}
+
+	if name := ExtractCmdName(""); name != "" {
+		t.Errorf("Expected empty command name, got %s", name)
+	}
+}
+
+/**
+ * Tests for ExtractTxId().
+ */
+func TestExtractTxId(t *testing.T) {
+
+	passCases := []struct {
+		DBGpCmd string
+		TxId    int
+	}{
+		// {Input, expected}
+		{"run -i 5\x00", 5},
+		{"breakpoint_set -i 12 -t line -f file:///foo.php -n 3\x00", 12},
+		{"eval -i 7 -- -i 99\x00", 7},
+		{"feature_get -n foo -i 3 -i 42\x00", 42},
+	}
+
+	for _, test := range passCases {
+		if TxId, err := ExtractTxId(test.DBGpCmd); err != nil || TxId != test.TxId {
+			t.Errorf("ExtractTxId(%q) = %d, %v", test.DBGpCmd, TxId, err)
+		}
+	}
+
+	failCases := []string{"run\x00", "run -i foo\x00", "eval -- -i 9\x00"}
+	for _, DBGpCmd := range failCases {
+		if TxId, err := ExtractTxId(DBGpCmd); err == nil {
+			t.Errorf("ExtractTxId(%q) = %d", DBGpCmd, TxId)
+		}
+	}
+}
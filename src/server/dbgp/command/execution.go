@@ -0,0 +1,38 @@
+/**
+ * @file
+ * Classify DBGp commands by their effect on script execution.
+ */
+
+package command
+
+/**
+ * Does the given DBGp command resume script execution?
+ *
+ * The response for these commands only arrives when execution stops again.
+ */
+func IsContinuation(DBGpCmdName string) bool {
+
+	switch resolveAlias(DBGpCmdName) {
+	case "run", "step_into", "step_over", "step_out":
+		return true
+	}
+
+	return false
+}
+
+/**
+ * Can the given DBGp command be sent while the script is running?
+ *
+ * As per the DBGp protocol, only "break" and "status" are allowed while the
+ * DBGp engine is executing the script.  All other commands have to wait until
+ * execution stops.
+ */
+func IsAsync(DBGpCmdName string) bool {
+
+	switch resolveAlias(DBGpCmdName) {
+	case "break", "status":
+		return true
+	}
+
+	return false
+}
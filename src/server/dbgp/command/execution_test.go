@@ -0,0 +1,34 @@
+/**
+ * @file
+ * Tests for classifying DBGp commands.
+ */
+
+package command
+
+import "testing"
+
+/**
+ * Tests for IsContinuation() and IsAsync().
+ */
+func TestExecutionClassification(t *testing.T) {
+
+	for _, cmd := range []string{"run", "r", "step_into", "step_over", "sv", "step_out"} {
+		if !IsContinuation(cmd) {
+			t.Errorf("Failed to spot continuation command %s.", cmd)
+		}
+	}
+
+	if IsContinuation("stack_get") {
+		t.Error("Mistook stack_get for a continuation command.")
+	}
+
+	for _, cmd := range []string{"break", "pause", "status"} {
+		if !IsAsync(cmd) {
+			t.Errorf("Failed to spot async command %s.", cmd)
+		}
+	}
+
+	if IsAsync("breakpoint_set") {
+		t.Error("Mistook breakpoint_set for an async command.")
+	}
+}
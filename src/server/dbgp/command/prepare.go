@@ -5,10 +5,12 @@
 package command
 
 import (
+	"encoding/base64"
 	"fmt"
 	"math"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 /**
@@ -34,6 +36,11 @@ const globalContextLabel = "global"
  */
 var lastTxId int
 
+/**
+ * Guards lastTxId.  Commands are prepared by several goroutines.
+ */
+var txIdLock sync.Mutex
+
 /**
  * Prepare DBGp command from the given values.
  *
@@ -45,11 +52,36 @@ var lastTxId int
  */
 func PrepareDBGpCmd(cmd string, args []string) (DBGpCmd string, err error) {
 
-	TxId := fetchNextTxId()
+	DBGpCmd, _, err = PrepareDBGpCmdWTxId(cmd, args)
+
+	return DBGpCmd, err
+}
+
+/**
+ * Prepare DBGp command and tell its transaction ID.
+ *
+ * The transaction ID is useful for matching the DBGp engine's response with
+ * the command.
+ */
+func PrepareDBGpCmdWTxId(cmd string, args []string) (DBGpCmd string, TxId int, err error) {
+
+	TxId = fetchNextTxId()
+	DBGpCmd, err = prepareDBGpCmd(cmd, args, TxId)
+
+	return DBGpCmd, TxId, err
+}
+
+/**
+ * Prepare DBGp command using the given transaction ID.
+ */
+func prepareDBGpCmd(cmd string, args []string, TxId int) (DBGpCmd string, err error) {
 
 	DBGpCmd = resolveAlias(cmd)
 
 	switch DBGpCmd {
+	case "break":
+		DBGpCmd, err = prepareCmdNoArgs("break", TxId)
+
 	case "breakpoint_set":
 		DBGpCmd, err = prepareBreakpointCmd(args, TxId)
 
@@ -74,6 +106,9 @@ func PrepareDBGpCmd(cmd string, args []string) (DBGpCmd string, err error) {
 	case "dbgp":
 		DBGpCmd, err = prepareRawDBGpCmd(args, TxId)
 
+	case "detach":
+		DBGpCmd, err = prepareCmdNoArgs("detach", TxId)
+
 	case "property_get":
 		DBGpCmd, err = preparePropertyGetCmd(args, TxId)
 
@@ -111,12 +146,13 @@ func PrepareDBGpCmd(cmd string, args []string) (DBGpCmd string, err error) {
 /**
  * Determine the transaction ID for the next DBGp command.
  *
- * Uses global variable "lastTxId"
- *
- * @todo Make it goroutine safe by wrapping it in a lock.
+ * Uses global variable "lastTxId".  Safe to call from multiple goroutines.
  */
 func fetchNextTxId() (nextTxId int) {
 
+	txIdLock.Lock()
+	defer txIdLock.Unlock()
+
 	lastTxId++
 	nextTxId = lastTxId % math.MaxInt32
 
@@ -142,6 +178,46 @@ func prepareBreakpointCmd(args []string, TxId int) (DBGpCmd string, err error) {
 	return DBGpCmd, err
 }
 
+/**
+ * Prepare the DBGp breakpoint_set command for a temporary breakpoint.
+ *
+ * Temporary breakpoints are meant for a single hit only.  The transaction ID
+ * is returned along with the command so that the engine's response to this
+ * very command can be identified later.
+ *
+ * Example: breakpoint_set -i 5 -t line -f /home/foo/php/bar.php -n 9 -r 1
+ */
+func PrepareTemporaryBreakpoint(filepath, lineNumber string) (DBGpCmd string, TxId int, err error) {
+
+	args := []string{filepath, lineNumber}
+
+	if err = validateBreakpointArgs(args); nil != err {
+		return DBGpCmd, TxId, err
+	}
+
+	TxId = fetchNextTxId()
+	DBGpCmd, err = prepareTemporaryBreakpointCmd(args, TxId)
+
+	return DBGpCmd, TxId, err
+}
+
+/**
+ * The DBGp Breakpoint set command with the temporary flag.
+ */
+func prepareTemporaryBreakpointCmd(args []string, TxId int) (DBGpCmd string, err error) {
+
+	if 2 > len(args) {
+		return DBGpCmd, fmt.Errorf("Need at least two args for preparing temporary breakpoint cmd.")
+	}
+
+	filepath := args[0]
+	lineNumber := args[1]
+
+	DBGpCmd = fmt.Sprintf("breakpoint_set -i %d -t line -f %s -n %s -r 1\x00", TxId, filepath, lineNumber)
+
+	return DBGpCmd, err
+}
+
 /**
  * The DBGp Breakpoint get command.
  */
@@ -176,6 +252,9 @@ func prepareBreakpointRemoveCmd(args []string, TxId int) (DBGpCmd string, err er
 
 /**
  * DBGp Eval command.
+ *
+ * The arguments make up the PHP expression.  The DBGp engine expects it in
+ * base64.
  */
 func prepareEvalCmd(args []string, TxId int) (DBGpCmd string, err error) {
 
@@ -183,7 +262,8 @@ func prepareEvalCmd(args []string, TxId int) (DBGpCmd string, err error) {
 		return DBGpCmd, fmt.Errorf("Insufficient number of args for eval.")
 	}
 
-	DBGpCmd = fmt.Sprintf("eval -i %d -- %s\x00", TxId, args[0])
+	expression := base64.StdEncoding.EncodeToString([]byte(strings.Join(args, " ")))
+	DBGpCmd = fmt.Sprintf("eval -i %d -- %s\x00", TxId, expression)
 
 	return DBGpCmd, err
 }
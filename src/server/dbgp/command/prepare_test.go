@@ -50,6 +50,29 @@ func TestPrepareBreakpointCmd(t *testing.T) {
 	}
 }
 
+/**
+ * Tests for prepareTemporaryBreakpointCmd().
+ */
+func TestPrepareTemporaryBreakpointCmd(t *testing.T) {
+
+	// Pass case.
+	cmd, _ := prepareTemporaryBreakpointCmd([]string{
+		"/home/foo/code/php/bar.php",
+		"9",
+	}, 5)
+
+	expected_cmd := "breakpoint_set -i 5 -t line -f /home/foo/code/php/bar.php -n 9 -r 1\x00"
+	if cmd != expected_cmd {
+		t.Errorf("Incorrect temporary breakpoint command. Expected %q, got %q.", expected_cmd, cmd)
+	}
+
+	// Fail case.
+	cmd, err := prepareTemporaryBreakpointCmd([]string{"foo"}, 3)
+	if nil == err {
+		t.Error("Missed insufficient number of args.")
+	}
+}
+
 /**
  * Tests for prepareBreakpointGetCmd().
  */
@@ -93,18 +116,18 @@ func TestPrepareBreakpointRemoveCmd(t *testing.T) {
 /**
  * Tests for prepareEvalCmd().
  *
- * prepareEvalCmd() itself is incomplete at the moment.  So this test evolve.
+ * The expression is sent in base64.
  */
 func TestPrepareEvalCmd(t *testing.T) {
 
 	// Pass case.
-	args := []string{"$a = 2 + 2"}
+	args := []string{"$a", "=", "2", "+", "2"}
 	TxId := 4
 	cmd, err := prepareEvalCmd(args, TxId)
 
-	expected := "eval -i 4 -- $a = 2 + 2\x00"
+	expected := "eval -i 4 -- JGEgPSAyICsgMg==\x00"
 	if expected != cmd {
-		t.Error("Eval command preparation failed.")
+		t.Errorf("Eval command preparation failed: %q", cmd)
 	}
 
 	// Fail case.
@@ -11,6 +11,7 @@ package command
 import (
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 /**
@@ -24,6 +25,9 @@ func Validate(cmd string, args []string) (err error) {
 	default:
 		err = fmt.Errorf("Unknown command.")
 
+	case "break":
+		err = validateCmdWithNoArg("break", args)
+
 	case "breakpoint_set":
 		err = validateBreakpointArgs(args)
 
@@ -40,7 +44,7 @@ func Validate(cmd string, args []string) (err error) {
 		err = validateContextGetArgs(args)
 
 	case "eval":
-		err = validateCmdWithNoArg("eval", args)
+		err = validateEvalArgs(args)
 
 	case "run":
 		err = validateCmdWithNoArg("run", args)
@@ -48,6 +52,9 @@ func Validate(cmd string, args []string) (err error) {
 	case "dbgp":
 		err = validateRawDBGpArgs(args)
 
+	case "detach":
+		err = validateCmdWithNoArg("detach", args)
+
 	case "property_get":
 		err = validatePropertyGetArgs(args)
 
@@ -207,6 +214,20 @@ func validateRawDBGpArgs(args []string) (err error) {
 	return err
 }
 
+/**
+ * Validate the eval command.
+ *
+ * Format: eval PHP-EXPRESSION
+ */
+func validateEvalArgs(args []string) (err error) {
+
+	if len(args) < 1 || strings.TrimSpace(strings.Join(args, " ")) == "" {
+		err = fmt.Errorf("Usage: eval PHP-EXPRESSION")
+	}
+
+	return err
+}
+
 /**
  * Validate the property_get command.
  *
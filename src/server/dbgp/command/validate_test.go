@@ -26,6 +26,13 @@ func TestValidate(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+
+	// The "detach" command.
+	err = Validate("detach", []string{})
+
+	if err != nil {
+		t.Error(err)
+	}
 }
 
 /**
@@ -174,6 +181,26 @@ func TestValidateRawDBGpArgs(t *testing.T) {
 	}
 }
 
+/**
+ * Tests for validateEvalArgs().
+ */
+func TestValidateEvalArgs(t *testing.T) {
+
+	// Pass case.
+	err := validateEvalArgs([]string{"$user->name"})
+
+	if nil != err {
+		t.Error(err)
+	}
+
+	// Fail case.  There is nothing to evaluate.
+	err = validateEvalArgs([]string{"", ""})
+
+	if nil == err {
+		t.Error("Failed to spot lack of an expression.")
+	}
+}
+
 /**
  * Tests for validatePropertyGetArgs().
  *
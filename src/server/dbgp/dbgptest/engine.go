@@ -0,0 +1,458 @@
+/**
+ * @file
+ * A programmable DBGp engine for tests.
+ *
+ * Plays the part of Xdebug inside the test process.  It connects to Footle's
+ * DBGp port, introduces itself with an init packet, and then answers Footle's
+ * commands.  Tests decide where the script breaks and how each command is
+ * answered.  Stream and notify packets can be sent at any time.
+ *
+ * Example:
+ *   engine := dbgptest.New("file:///var/www/index.php")
+ *   engine.BreakAt("file:///var/www/index.php", 3)
+ *   engine.On("eval", func(cmd dbgptest.Cmd) []string {
+ *     return []string{dbgptest.ErrorResponse(cmd, 206, "Error evaluating code")}
+ *   })
+ *   engine.Connect(footleAddress)
+ *   runCmd, err := engine.Expect("run", time.Second)
+ */
+
+package dbgptest
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * Number of received commands kept for Expect().
+ */
+const receivedCmdsSize = 256
+
+/**
+ * A DBGp command as received by the engine.
+ *
+ * Example: "breakpoint_set -i 4 -t line -f file:///foo.php -n 3"
+ *   Name: breakpoint_set
+ *   TxId: 4
+ *   Args: {"t": "line", "f": "file:///foo.php", "n": "3"}
+ */
+type Cmd struct {
+	Name string
+	TxId int
+	Args map[string]string
+	Data string // Base64 encoded data after "--".
+	Raw  string
+}
+
+/**
+ * Custom answer to a command.  Returns whole DBGp packets.
+ */
+type Handler func(cmd Cmd) (packets []string)
+
+/**
+ * A place in a script.
+ */
+type Location struct {
+	FileURI string
+	LineNo  int
+}
+
+type breakpoint struct {
+	Location
+	Id int
+}
+
+type Engine struct {
+	FileURI string
+	AppId   string
+
+	lock             sync.Mutex
+	handlers         map[string]Handler
+	breaks           []Location
+	current          Location
+	status           string
+	breakpoints      map[int]breakpoint
+	lastBreakpointId int
+
+	conn      net.Conn
+	writeLock sync.Mutex
+	received  chan Cmd
+	done      chan struct{}
+}
+
+/**
+ * Prepare an engine that is about to run the given script.
+ */
+func New(fileURI string) *Engine {
+
+	return &Engine{
+		FileURI:     fileURI,
+		AppId:       "1",
+		handlers:    make(map[string]Handler),
+		status:      "starting",
+		breakpoints: make(map[int]breakpoint),
+		received:    make(chan Cmd, receivedCmdsSize),
+		done:        make(chan struct{}),
+	}
+}
+
+/**
+ * Answer a command in our own way.
+ *
+ * Overrides the default answer.
+ */
+func (e *Engine) On(cmdName string, handler Handler) {
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.handlers[cmdName] = handler
+}
+
+/**
+ * Add a break to the script.
+ *
+ * Each "run" or step command leads to the next break.  The script stops once
+ * there are no more breaks.
+ */
+func (e *Engine) BreakAt(fileURI string, lineNo int) {
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.breaks = append(e.breaks, Location{FileURI: fileURI, LineNo: lineNo})
+}
+
+/**
+ * Connect to Footle and introduce ourselves.
+ *
+ * Commands are answered in the background until either side hangs up.
+ */
+func (e *Engine) Connect(address string) (err error) {
+
+	e.conn, err = net.Dial("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	initPacket := fmt.Sprintf(`<?xml version="1.0" encoding="iso-8859-1"?>
+<init xmlns="urn:debugger_protocol_v1" xmlns:xdebug="https://xdebug.org/dbgp/xdebug" fileuri="%s" language="PHP" protocol_version="1.0" appid="%s"><engine version="0.0.1"><![CDATA[dbgptest]]></engine></init>`, e.FileURI, e.AppId)
+
+	if err = e.write(initPacket); err != nil {
+		return err
+	}
+
+	go e.serve()
+
+	return nil
+}
+
+/**
+ * Send a stream packet, e.g. for the script's output.
+ *
+ * @param string streamType
+ *   stdout or stderr.
+ */
+func (e *Engine) Stream(streamType, data string) error {
+
+	return e.write(fmt.Sprintf(`<?xml version="1.0" encoding="iso-8859-1"?>
+<stream xmlns="urn:debugger_protocol_v1" type="%s" encoding="base64">%s</stream>`, streamType, base64.StdEncoding.EncodeToString([]byte(data))))
+}
+
+/**
+ * Send a notify packet.
+ */
+func (e *Engine) Notify(name, body string) error {
+
+	return e.write(fmt.Sprintf(`<?xml version="1.0" encoding="iso-8859-1"?>
+<notify xmlns="urn:debugger_protocol_v1" name="%s">%s</notify>`, name, body))
+}
+
+/**
+ * Wait for Footle to send the given command.
+ *
+ * Other commands received in the meantime are skipped.
+ */
+func (e *Engine) Expect(cmdName string, timeout time.Duration) (cmd Cmd, err error) {
+
+	deadline := time.After(timeout)
+
+	for {
+		select {
+		case cmd = <-e.received:
+			if cmd.Name == cmdName {
+				return cmd, nil
+			}
+		case <-deadline:
+			return cmd, fmt.Errorf("The %s command has not arrived within %s.", cmdName, timeout)
+		}
+	}
+}
+
+/**
+ * Closed once the connection with Footle is over.
+ */
+func (e *Engine) Done() <-chan struct{} {
+
+	return e.done
+}
+
+/**
+ * Hang up.
+ */
+func (e *Engine) Close() error {
+
+	if e.conn == nil {
+		return nil
+	}
+
+	return e.conn.Close()
+}
+
+/**
+ * Answer commands until the connection is over.
+ */
+func (e *Engine) serve() {
+
+	defer close(e.done)
+	defer e.conn.Close()
+
+	reader := bufio.NewReader(e.conn)
+
+	for {
+		rawCmd, err := reader.ReadString('\x00')
+		if err != nil {
+			return
+		}
+
+		cmd := ParseCmd(rawCmd)
+
+		select {
+		case e.received <- cmd:
+		default:
+			// Nobody is expecting commands.
+		}
+
+		packets, hangUp := e.answer(cmd)
+		for _, packet := range packets {
+			if err := e.write(packet); err != nil {
+				return
+			}
+		}
+
+		if hangUp {
+			return
+		}
+	}
+}
+
+/**
+ * Prepare the answer to a command.
+ *
+ * The engine hangs up after stopping or detaching.
+ */
+func (e *Engine) answer(cmd Cmd) (packets []string, hangUp bool) {
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	hangUp = cmd.Name == "stop" || cmd.Name == "detach"
+
+	if handler, exists := e.handlers[cmd.Name]; exists {
+		return handler(cmd), hangUp
+	}
+
+	switch cmd.Name {
+	case "status":
+		return []string{Response(cmd, fmt.Sprintf(`status="%s" reason="ok"`, e.status), "")}, false
+	case "feature_set":
+		return []string{Response(cmd, `feature="`+cmd.Args["n"]+`" success="1"`, "")}, false
+	case "run", "step_into", "step_over", "step_out":
+		return []string{e.proceed(cmd)}, false
+	case "break":
+		e.status = "break"
+		return []string{Response(cmd, `status="break" reason="ok" success="1"`, "")}, false
+	case "stop":
+		e.status = "stopped"
+		return []string{Response(cmd, `status="stopped" reason="ok"`, "")}, true
+	case "detach":
+		e.status = "stopping"
+		return []string{Response(cmd, `status="stopping" reason="ok"`, "")}, true
+	case "breakpoint_set":
+		return []string{e.setBreakpoint(cmd)}, false
+	case "breakpoint_remove":
+		return []string{e.removeBreakpoint(cmd)}, false
+	case "breakpoint_list":
+		return []string{Response(cmd, "", e.listBreakpoints())}, false
+	case "stack_get":
+		return []string{Response(cmd, "", e.describeStack())}, false
+	case "context_names":
+		return []string{Response(cmd, "", `<context name="Locals" id="0"/><context name="Superglobals" id="1"/>`)}, false
+	case "context_get":
+		return []string{Response(cmd, `context="`+cmd.Args["c"]+`"`, "")}, false
+	case "property_get":
+		return []string{ErrorResponse(cmd, 300, "Can not get property")}, false
+	}
+
+	return []string{ErrorResponse(cmd, 4, "Unimplemented command")}, false
+}
+
+/**
+ * Carry on until the next break.
+ */
+func (e *Engine) proceed(cmd Cmd) (packet string) {
+
+	if len(e.breaks) == 0 {
+		e.status = "stopping"
+		return Response(cmd, `status="stopping" reason="ok"`, "")
+	}
+
+	e.current, e.breaks = e.breaks[0], e.breaks[1:]
+	e.status = "break"
+
+	location := fmt.Sprintf(`<xdebug:message filename="%s" lineno="%d"></xdebug:message>`, e.current.FileURI, e.current.LineNo)
+
+	return Response(cmd, `status="break" reason="ok"`, location)
+}
+
+func (e *Engine) setBreakpoint(cmd Cmd) (packet string) {
+
+	lineNo, err := strconv.Atoi(cmd.Args["n"])
+	if err != nil || cmd.Args["f"] == "" {
+		return ErrorResponse(cmd, 200, "Breakpoint could not be set")
+	}
+
+	e.lastBreakpointId++
+	e.breakpoints[e.lastBreakpointId] = breakpoint{
+		Location: Location{FileURI: cmd.Args["f"], LineNo: lineNo},
+		Id:       e.lastBreakpointId,
+	}
+
+	return Response(cmd, fmt.Sprintf(`id="%d" state="enabled"`, e.lastBreakpointId), "")
+}
+
+func (e *Engine) removeBreakpoint(cmd Cmd) (packet string) {
+
+	breakpointId, _ := strconv.Atoi(cmd.Args["d"])
+
+	if _, exists := e.breakpoints[breakpointId]; !exists {
+		return ErrorResponse(cmd, 205, "No such breakpoint")
+	}
+
+	delete(e.breakpoints, breakpointId)
+
+	return Response(cmd, "", "")
+}
+
+func (e *Engine) listBreakpoints() (breakpointList string) {
+
+	breakpointIds := []int{}
+	for breakpointId := range e.breakpoints {
+		breakpointIds = append(breakpointIds, breakpointId)
+	}
+	sort.Ints(breakpointIds)
+
+	for _, breakpointId := range breakpointIds {
+		b := e.breakpoints[breakpointId]
+		breakpointList += fmt.Sprintf(`<breakpoint type="line" filename="%s" lineno="%d" state="enabled" hit_count="0" hit_value="0" id="%d"></breakpoint>`, b.FileURI, b.LineNo, b.Id)
+	}
+
+	return breakpointList
+}
+
+/**
+ * Single level stack trace for the current break.
+ */
+func (e *Engine) describeStack() (stack string) {
+
+	if e.status != "break" {
+		return ""
+	}
+
+	return fmt.Sprintf(`<stack where="{main}" level="0" type="file" filename="%s" lineno="%d"></stack>`, e.current.FileURI, e.current.LineNo)
+}
+
+/**
+ * Write a DBGp packet.
+ *
+ * Format: DATA-LENGTH NULL XML NULL
+ */
+func (e *Engine) write(packet string) (err error) {
+
+	e.writeLock.Lock()
+	defer e.writeLock.Unlock()
+
+	return writePacket(e.conn, packet)
+}
+
+func writePacket(w io.Writer, packet string) (err error) {
+
+	_, err = fmt.Fprintf(w, "%d\x00%s\x00", len(packet), packet)
+
+	return err
+}
+
+/**
+ * Prepare a response packet for a command.
+ *
+ * @param string attrs
+ *   Extra attributes of the response element.  Example: status="break"
+ * @param string body
+ *   Content of the response element.
+ */
+func Response(cmd Cmd, attrs, body string) (packet string) {
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="iso-8859-1"?>
+<response xmlns="urn:debugger_protocol_v1" xmlns:xdebug="https://xdebug.org/dbgp/xdebug" command="%s" transaction_id="%d" %s>%s</response>`, cmd.Name, cmd.TxId, attrs, body)
+}
+
+/**
+ * Prepare an error response packet for a command.
+ */
+func ErrorResponse(cmd Cmd, errorCode int, errorMessage string) (packet string) {
+
+	return Response(cmd, "", fmt.Sprintf(`<error code="%d"><message><![CDATA[%s]]></message></error>`, errorCode, errorMessage))
+}
+
+/**
+ * Break a raw DBGp command into its parts.
+ */
+func ParseCmd(rawCmd string) (cmd Cmd) {
+
+	cmd.Raw = strings.TrimRight(rawCmd, "\x00")
+	cmd.Args = make(map[string]string)
+
+	cmdParts := strings.Fields(cmd.Raw)
+	if len(cmdParts) == 0 {
+		return cmd
+	}
+
+	cmd.Name = cmdParts[0]
+
+	for i := 1; i < len(cmdParts); i++ {
+		if cmdParts[i] == "--" {
+			cmd.Data = strings.Join(cmdParts[i+1:], " ")
+			break
+		}
+
+		if !strings.HasPrefix(cmdParts[i], "-") || i+1 >= len(cmdParts) {
+			continue
+		}
+
+		cmd.Args[strings.TrimPrefix(cmdParts[i], "-")] = cmdParts[i+1]
+		i++
+	}
+
+	cmd.TxId, _ = strconv.Atoi(cmd.Args["i"])
+
+	return cmd
+}
@@ -0,0 +1,21 @@
+package dbgptest
+
+import "testing"
+
+/**
+ * Tests for ParseCmd().
+ */
+func TestParseCmd(t *testing.T) {
+
+	cmd := ParseCmd("breakpoint_set -i 4 -t line -f file:///foo.php -n 3\x00")
+
+	if cmd.Name != "breakpoint_set" || cmd.TxId != 4 || cmd.Args["f"] != "file:///foo.php" || cmd.Args["n"] != "3" {
+		t.Errorf("Misparsed breakpoint_set: %v", cmd)
+	}
+
+	cmd = ParseCmd("eval -i 9 -- Zm9v\x00")
+
+	if cmd.Name != "eval" || cmd.TxId != 9 || cmd.Data != "Zm9v" {
+		t.Errorf("Misparsed eval: %v", cmd)
+	}
+}
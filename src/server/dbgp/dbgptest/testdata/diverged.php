@@ -0,0 +1,3 @@
+<?php
+// The local copy.
+echo "Hello";
@@ -0,0 +1,35 @@
+/**
+ * @file
+ * Error messages originating from Footle rather than the DBGp engine.
+ */
+
+package message
+
+/**
+ * Error codes for Footle's own errors.
+ *
+ * DBGp error codes are positive numbers.  Footle's error codes are negative to
+ * avoid any clash.
+ */
+const InvalidCmdErrorCode = -1
+const NoSessionErrorCode = -2
+const TimeoutErrorCode = -3
+const SessionEndErrorCode = -4
+const FileErrorCode = -5
+
+/**
+ * Prepare a response message that carries an error.
+ *
+ * These resemble DBGp error responses so that UIs can deal with both in the
+ * same way.
+ */
+func PrepareErrorMsg(cmd string, TxId, errorCode int, errorMessage string) (msg Message) {
+
+	msg.MessageType = "response"
+	msg.Properties.Command = cmd
+	msg.Properties.TxId = TxId
+	msg.Properties.ErrorCode = errorCode
+	msg.Properties.ErrorMessage = errorMessage
+
+	return msg
+}
@@ -57,6 +57,13 @@ func prepareInitMessage(init Init) (message Message) {
 	message.MessageType = "init"
 	message.State = "starting"
 	message.Properties.Filename = init.FileURI
+	message.Session = Session{
+		Language:        init.Language,
+		ProtocolVersion: init.Protocol,
+		AppId:           init.AppID,
+		Engine:          init.Engine.Name,
+		EngineVersion:   init.Engine.Version,
+	}
 
 	return message
 }
@@ -33,6 +33,10 @@ func TestDecode(t *testing.T) {
 		t.Error("Missed Init message.")
 	}
 
+	if "Xdebug" != message.Session.Engine || "2.2.5" != message.Session.EngineVersion || "27891" != message.Session.AppId {
+		t.Errorf("Missed session details of Init message: %v", message.Session)
+	}
+
 	xml =
 		`<?xml version="1.0" encoding="iso-8859-1"?>
 <response xmlns="urn:debugger_protocol_v1" xmlns:xdebug="https://xdebug.org/dbgp/xdebug"
@@ -16,12 +16,15 @@ type Message struct {
 	Content     string
 	Breakpoints map[int]Breakpoint
 	Stacktrace  []StackLevel
+	Session     Session
+	Origin      string // The UI that has issued the command behind this message.
 }
 
 type Properties struct {
 	Command      string
 	ErrorCode    int
 	ErrorMessage string
+	Notice       string // Something the user should know about, e.g. a moved breakpoint.
 	Filename     string
 	LineNumber   int
 	BreakpointId int
@@ -47,18 +50,34 @@ type Variable struct {
 	IsBase64          bool
 }
 
+/**
+ * Details of a debugging session.  Only filled in for init messages.
+ */
+type Session struct {
+	Language        string
+	ProtocolVersion string
+	AppId           string
+	Engine          string
+	EngineVersion   string
+}
+
 type Init struct {
 	XMLName   xml.Name `xml:"init"`
 	FileURI   string   `xml:"fileuri,attr"`
 	Language  string   `xml:"language,attr"`
 	Protocol  string   `xml:"protocol_version,attr"`
 	AppID     string   `xml:"appid,attr"`
-	Engine    string   `xml:"engine"`
+	Engine    Engine   `xml:"engine"`
 	Author    string   `xml:"author"`
 	URL       string   `xml:"url"`
 	Copyright string   `xml:"copyright"`
 }
 
+type Engine struct {
+	Name    string `xml:",chardata"`
+	Version string `xml:"version,attr"`
+}
+
 type Response struct {
 	XMLName       xml.Name `xml:"response"`
 	Command       string   `xml:"command,attr"`
@@ -1,17 +1,23 @@
 /**
  * Footle the DBGp debugger.
  *
- * Here we launch go routines for command line UI, HTTP UI, receiving
+ * Here we launch go routines for command line UI, HTTP UI, DAP UI, receiving
  * messages from DBGp engine and sending DBGp commands to DBGp engine.
  */
 
 package main
 
 import (
+	"fmt"
+	"log"
 	"server/cli"
 	"server/config"
 	"server/core"
 	conn "server/core/connection"
+	"server/core/recorder"
+	"server/core/tracker"
+	"server/core/watcher"
+	"server/dap"
 	"server/dbgp/message"
 	"server/http"
 )
@@ -29,19 +35,45 @@ func main() {
 	config := config.Get()
 
 	// Initializations.
-	var MsgsForCmdLineUI, MsgsForHTTPUI chan message.Message
+	var MsgsForCmdLineUI, MsgsForHTTPUI, MsgsForDAPUI chan message.Message
 
-	CmdsFromUI := make(chan string)
-	DBGpCmds := make(chan string)
-	DBGpMessages := make(chan message.Message)
+	CmdsFromUI := make(chan tracker.Request)
 	bye := make(chan struct{})
 
-	launchUIs(config, &MsgsForCmdLineUI, &MsgsForHTTPUI, CmdsFromUI, bye)
+	if recordFile := config.GetRecordFile(); recordFile != "" {
+		if err := recorder.Start(recordFile); err != nil {
+			log.Fatal(err)
+		}
+		defer recorder.Stop()
+	}
+
+	launchUIs(config, &MsgsForCmdLineUI, &MsgsForHTTPUI, &MsgsForDAPUI, CmdsFromUI, bye)
 
 	// Talk to DBGp engine.
 	DBGpConnection := conn.GetConnection()
 	DBGpConnection.Activate()
 
+	launchDebugger(DBGpConnection, CmdsFromUI, MsgsForCmdLineUI, MsgsForHTTPUI, MsgsForDAPUI)
+	go replayRecording(config)
+
+	// Reload files in the UIs when they are saved.
+	if config.HasWatcher() {
+		go watcher.Watch(config.GetCodebase(), CmdsFromUI)
+	}
+
+	<-bye
+}
+
+/**
+ * Launch the go routines that sit between the UIs and the DBGp engine.
+ *
+ * UI message channels may be nil for absent UIs.
+ */
+func launchDebugger(DBGpConnection *conn.Connection, CmdsFromUI chan tracker.Request, MsgsForCmdLineUI, MsgsForHTTPUI, MsgsForDAPUI chan message.Message) {
+
+	DBGpCmds := make(chan string)
+	DBGpMessages := make(chan message.Message)
+
 	go core.RecvMsgsFromDBGpEngine(DBGpConnection, DBGpMessages)
 	go core.SendCmdsToDBGpEngine(DBGpConnection, DBGpCmds)
 
@@ -50,17 +82,18 @@ func main() {
 	go core.ProcessUICmds(CmdsFromUI, DBGpCmds, DBGpMessages, DBGpConnection)
 
 	// Process incoming DBGP messages before selectively passing them to the UIs.
-	go core.ProcessDBGpMessages(DBGpCmds, DBGpMessages, MsgsForCmdLineUI, MsgsForHTTPUI)
+	go core.ProcessDBGpMessages(DBGpCmds, DBGpMessages, MsgsForCmdLineUI, MsgsForHTTPUI, MsgsForDAPUI, DBGpConnection)
 
-	<-bye
+	// Tell UIs about DBGp commands that never receive a response.
+	go tracker.WatchTimeouts(DBGpMessages)
 }
 
 /**
  * Launch all user interfaces.
  *
- * Start the HTTP and/or the Cli interfaces depending on user preferences.
+ * Start the HTTP, DAP, and/or the Cli interfaces depending on user preferences.
  */
-func launchUIs(config config.Config, MsgsForCmdLineUI, MsgsForHTTPUI *chan message.Message, CmdsFromUI chan string, bye chan struct{}) {
+func launchUIs(config config.Config, MsgsForCmdLineUI, MsgsForHTTPUI, MsgsForDAPUI *chan message.Message, CmdsFromUI chan tracker.Request, bye chan struct{}) {
 
 	if config.HasCmdLine() {
 		*MsgsForCmdLineUI = make(chan message.Message)
@@ -75,4 +108,30 @@ func launchUIs(config config.Config, MsgsForCmdLineUI, MsgsForHTTPUI *chan messa
 		go http.Listen(CmdsFromUI, config)
 		go http.TellBrowsers(*MsgsForHTTPUI, config)
 	}
+
+	if config.HasDAP() {
+		*MsgsForDAPUI = make(chan message.Message)
+
+		go dap.Listen(CmdsFromUI, config)
+		go dap.TellDAPClient(*MsgsForDAPUI)
+	}
+}
+
+/**
+ * Pretend to be a DBGp engine by replaying a recorded session.
+ *
+ * @see recorder.Replay()
+ */
+func replayRecording(config config.Config) {
+
+	replayFile := config.GetReplayFile()
+	if replayFile == "" {
+		return
+	}
+
+	address := fmt.Sprintf("localhost:%d", config.GetDBGpPort())
+
+	if err := recorder.Replay(replayFile, address); err != nil {
+		log.Println(err)
+	}
 }
@@ -0,0 +1,455 @@
+/**
+ * @file
+ * End-to-end tests.
+ *
+ * Footle is launched in-process with its HTTP interface.  A fake DBGp engine
+ * then connects to it.  Commands are issued over the HTTP API and the same
+ * channels that the command line interface uses.
+ *
+ * We *must* run "go generate" for the HTTP package before running these tests.
+ */
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"server/cli"
+	"server/config"
+	conn "server/core/connection"
+	"server/core/tracker"
+	"server/dbgp/dbgptest"
+	"server/dbgp/message"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const e2eTimeout = 3 * time.Second
+
+/**
+ * Footle running inside the test process.
+ */
+type testFootle struct {
+	commands    chan tracker.Request
+	cliMsgs     chan message.Message
+	httpURL     string
+	DBGpAddress string
+	codebase    string
+}
+
+var footle testFootle
+var footleOnce sync.Once
+
+/**
+ * Launch Footle once for all tests.
+ *
+ * Footle keeps its state in package level variables.  So the tests share a
+ * single instance.  Each test still starts afresh.
+ *
+ * @see resetFootle()
+ */
+func startFootle(t *testing.T) testFootle {
+
+	footleOnce.Do(func() {
+		conf := config.Get()
+
+		httpPort, err := findFreePort()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conf.SetArg("http-port", fmt.Sprintf("%d", httpPort))
+		conf.SetArg("dbgp-port", "0")
+		conf.SetFlag("has-http")
+		conf.UnsetFlag("has-cmdline")
+
+		// The command line UI reads from the terminal.  So we stand in for it.
+		var MsgsForHTTPUI, MsgsForDAPUI, unused chan message.Message
+		MsgsForCmdLineUI := make(chan message.Message, 1024)
+		CmdsFromUI := make(chan tracker.Request)
+
+		launchUIs(conf, &unused, &MsgsForHTTPUI, &MsgsForDAPUI, CmdsFromUI, make(chan struct{}))
+
+		DBGpConnection := conn.GetConnection()
+		DBGpConnection.Activate()
+
+		launchDebugger(DBGpConnection, CmdsFromUI, MsgsForCmdLineUI, MsgsForHTTPUI, MsgsForDAPUI)
+
+		footle = testFootle{
+			commands:    CmdsFromUI,
+			cliMsgs:     MsgsForCmdLineUI,
+			httpURL:     fmt.Sprintf("http://localhost:%d", httpPort),
+			DBGpAddress: DBGpConnection.Addr().String(),
+			codebase:    conf.GetCodebase(),
+		}
+
+		waitForHTTP(t, footle.httpURL)
+	})
+
+	resetFootle(t, footle)
+
+	return footle
+}
+
+/**
+ * Clear what earlier tests have left behind.
+ *
+ * Waits for any earlier debugging session to end.  Then removes all
+ * breakpoints and skips unread messages for the command line interface.
+ */
+func resetFootle(t *testing.T, footle testFootle) {
+
+	DBGpConnection := conn.GetConnection()
+	for attempt := 0; DBGpConnection.IsOnAir(); attempt++ {
+		if attempt == 100 {
+			t.Fatal("The previous debugging session has not ended.")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	reply, err := callAPI("GET", footle.httpURL+"/api/v1/breakpoints")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, breakpoint := range reply.Breakpoints {
+		if _, err := callAPI("DELETE", fmt.Sprintf("%s/api/v1/breakpoints/%d", footle.httpURL, breakpoint.Id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for {
+		select {
+		case <-footle.cliMsgs:
+		default:
+			return
+		}
+	}
+}
+
+/**
+ * Break at a breakpoint and step through a script over the HTTP API.
+ */
+func TestHTTPDebuggingSession(t *testing.T) {
+
+	footle := startFootle(t)
+	fileURI := "file://" + filepath.Join(footle.codebase, "index.php")
+
+	// Breakpoints can be set before the session.
+	if reply, err := callAPI("POST", footle.httpURL+"/api/v1/breakpoints?file=index.php&line=3"); err != nil {
+		t.Fatalf("Failed to set breakpoint: %v, %v", reply, err)
+	}
+
+	engine := dbgptest.New(fileURI)
+	engine.BreakAt(fileURI, 3)
+	engine.BreakAt(fileURI, 4)
+
+	if err := engine.Connect(footle.DBGpAddress); err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	breakpointCmd, err := engine.Expect("breakpoint_set", e2eTimeout)
+	if err != nil || breakpointCmd.Args["n"] != "3" || breakpointCmd.Args["f"] != fileURI {
+		t.Errorf("Pending breakpoint has not reached the engine: %v, %v", breakpointCmd, err)
+	}
+
+	if _, err := engine.Expect("run", e2eTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	if reply, err := callAPI("GET", footle.httpURL+"/api/v1/status"); err != nil || reply.State != "break" {
+		t.Errorf("Expected break, got: %v, %v", reply, err)
+	}
+
+	reply, err := callAPI("POST", footle.httpURL+"/api/v1/step_over")
+	if err != nil || reply.State != "break" || reply.Properties.Filename != "index.php" || reply.Properties.LineNumber != 4 {
+		t.Errorf("Expected break at index.php:4, got: %v, %v", reply, err)
+	}
+
+	reply, err = callAPI("GET", footle.httpURL+"/api/v1/stack")
+	if err != nil || len(reply.Stacktrace) != 1 || reply.Stacktrace[0].LineNo != 4 {
+		t.Errorf("Unexpected stack trace: %v, %v", reply, err)
+	}
+
+	// No more breaks.  The script ends.
+	if reply, err := callAPI("POST", footle.httpURL+"/api/v1/run"); err != nil || reply.State != "stopping" {
+		t.Errorf("Expected the script to end, got: %v, %v", reply, err)
+	}
+
+	if _, err := engine.Expect("stop", e2eTimeout); err != nil {
+		t.Error(err)
+	}
+
+	awaitHangUp(t, engine)
+}
+
+/**
+ * Inspect a break and detach the way the command line interface does.
+ */
+func TestCmdLineDebuggingSession(t *testing.T) {
+
+	footle := startFootle(t)
+	fileURI := "file://" + filepath.Join(footle.codebase, "foo.php")
+
+	engine := dbgptest.New(fileURI)
+	engine.BreakAt(fileURI, 7)
+
+	if err := engine.Connect(footle.DBGpAddress); err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	if _, err := engine.Expect("run", e2eTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	// Script output should not upset Footle.
+	if err := engine.Stream("stdout", "Hello world"); err != nil {
+		t.Error(err)
+	}
+
+	footle.commands <- tracker.New("status", cli.Origin)
+
+	awaitCmdLineMsg(t, footle, func(msg message.Message) bool {
+		return msg.Origin == cli.Origin && msg.Properties.Command == "status" && msg.State == "break"
+	})
+
+	footle.commands <- tracker.New("continue", cli.Origin)
+
+	if _, err := engine.Expect("detach", e2eTimeout); err != nil {
+		t.Error(err)
+	}
+
+	awaitCmdLineMsg(t, footle, func(msg message.Message) bool {
+		return msg.State == "detached"
+	})
+
+	awaitHangUp(t, engine)
+}
+
+/**
+ * Detach from a script that is still running.
+ *
+ * The engine ignores "detach" while the script runs.  So Footle hangs up.
+ */
+func TestContinueWhileRunning(t *testing.T) {
+
+	footle := startFootle(t)
+	fileURI := "file://" + filepath.Join(footle.codebase, "bar.php")
+
+	engine := dbgptest.New(fileURI)
+	engine.On("run", func(cmd dbgptest.Cmd) []string {
+		// The script carries on without breaking.
+		return nil
+	})
+
+	if err := engine.Connect(footle.DBGpAddress); err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	if _, err := engine.Expect("run", e2eTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	footle.commands <- tracker.New("continue", cli.Origin)
+
+	awaitCmdLineMsg(t, footle, func(msg message.Message) bool {
+		return msg.Properties.Command == "continue" && msg.State == "detached"
+	})
+
+	awaitHangUp(t, engine)
+}
+
+/**
+ * Only the command line can start recording.
+ *
+ * Otherwise any HTTP client could write to any file.
+ */
+func TestRecordOnlyFromCmdLine(t *testing.T) {
+
+	footle := startFootle(t)
+
+	tmpDir, err := ioutil.TempDir("", "footle-record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sessionFile := filepath.Join(tmpDir, "session.jsonl")
+
+	request := tracker.NewSync("record "+sessionFile, "http:1")
+	footle.commands <- request
+
+	if reply := <-request.Reply; reply.Properties.ErrorCode == 0 {
+		t.Errorf("Expected an error for recording over HTTP: %v", reply)
+	}
+
+	if _, err := os.Stat(sessionFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no session file: %v", err)
+	}
+
+	request = tracker.NewSync("record "+sessionFile, cli.Origin)
+	footle.commands <- request
+
+	if reply := <-request.Reply; reply.Properties.ErrorCode != 0 {
+		t.Errorf("Unexpected error for recording from the command line: %v", reply)
+	}
+
+	request = tracker.NewSync("stop_recording", cli.Origin)
+	footle.commands <- request
+	<-request.Reply
+}
+
+/**
+ * Warn when the engine runs a different copy of a file.
+ *
+ * The engine's copy should then be available over HTTP.
+ */
+func TestDivergedSource(t *testing.T) {
+
+	footle := startFootle(t)
+	relativePath := "dbgp/dbgptest/testdata/diverged.php"
+	fileURI := "file://" + filepath.Join(footle.codebase, filepath.FromSlash(relativePath))
+
+	engine := dbgptest.New(fileURI)
+	engine.BreakAt(fileURI, 2)
+	engine.On("source", func(cmd dbgptest.Cmd) []string {
+		src := base64.StdEncoding.EncodeToString([]byte("<?php\n// The engine's copy.\necho \"Hello\";\n"))
+		return []string{dbgptest.Response(cmd, `encoding="base64"`, src)}
+	})
+
+	if err := engine.Connect(footle.DBGpAddress); err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	if sourceCmd, err := engine.Expect("source", e2eTimeout); err != nil || sourceCmd.Args["f"] != fileURI {
+		t.Fatalf("The engine's copy has not been asked for: %v, %v", sourceCmd, err)
+	}
+
+	awaitCmdLineMsg(t, footle, func(msg message.Message) bool {
+		return msg.Properties.Command == "diverged" && msg.Properties.Filename == fileURI && msg.Properties.Notice != ""
+	})
+
+	response, err := http.Get(footle.httpURL + "/engine-file/" + relativePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK || !strings.Contains(string(body), "The engine&#39;s copy.") {
+		t.Errorf("Unexpected engine copy: %d, %s", response.StatusCode, body)
+	}
+
+	footle.commands <- tracker.New("continue", cli.Origin)
+
+	if _, err := engine.Expect("detach", e2eTimeout); err != nil {
+		t.Error(err)
+	}
+
+	awaitHangUp(t, engine)
+}
+
+/**
+ * Wait for a message for the command line interface.
+ *
+ * Other messages are skipped.
+ */
+func awaitCmdLineMsg(t *testing.T, footle testFootle, isExpected func(message.Message) bool) {
+
+	deadline := time.After(e2eTimeout)
+
+	for {
+		select {
+		case msg := <-footle.cliMsgs:
+			if isExpected(msg) {
+				return
+			}
+		case <-deadline:
+			t.Fatal("Expected message has not reached the command line interface.")
+		}
+	}
+}
+
+/**
+ * Wait for the fake engine's connection to end.
+ */
+func awaitHangUp(t *testing.T, engine *dbgptest.Engine) {
+
+	select {
+	case <-engine.Done():
+	case <-time.After(e2eTimeout):
+		t.Error("The debugging session has not ended.")
+	}
+}
+
+/**
+ * Make an API call and decode its reply.
+ */
+func callAPI(method, url string) (reply message.Message, err error) {
+
+	request, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return reply, err
+	}
+
+	client := http.Client{Timeout: e2eTimeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return reply, err
+	}
+	defer response.Body.Close()
+
+	if err = json.NewDecoder(response.Body).Decode(&reply); err != nil {
+		return reply, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return reply, fmt.Errorf("HTTP status %d", response.StatusCode)
+	}
+
+	return reply, nil
+}
+
+/**
+ * Wait until the HTTP interface is up.
+ */
+func waitForHTTP(t *testing.T, httpURL string) {
+
+	for attempt := 0; attempt < 100; attempt++ {
+		if response, err := http.Get(httpURL + "/current-state"); err == nil {
+			response.Body.Close()
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("The HTTP interface has not come up.")
+}
+
+/**
+ * Find a network port nobody is listening on.
+ */
+func findFreePort() (port int, err error) {
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
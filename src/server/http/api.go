@@ -0,0 +1,295 @@
+/**
+ * @file
+ * JSON REST API for driving the debugger.
+ *
+ * Unlike "/steering-wheel", every API call waits for the DBGp engine's
+ * response to its command and returns that response as JSON.  DBGp errors are
+ * reflected in the HTTP status code.
+ *
+ * Examples:
+ *   - POST /api/v1/step_over
+ *   - GET /api/v1/stack
+ *   - GET /api/v1/context/local?depth=1
+ *   - POST /api/v1/breakpoints with file=index.php&line=18
+ *   - DELETE /api/v1/breakpoints/3
+ */
+
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"server/core/tracker"
+	"server/dbgp/message"
+	"strconv"
+	"strings"
+)
+
+/**
+ * All API paths start with this.
+ */
+const API_PREFIX = "/api/v1/"
+
+/**
+ * Identifies commands issued over the REST API.
+ */
+const APIOrigin = "api"
+
+/**
+ * Commands that change the course of execution.  These only accept POST.
+ */
+var apiExecutionCmds = map[string]bool{
+	"run":       true,
+	"step_into": true,
+	"step_over": true,
+	"step_out":  true,
+	"break":     true,
+	"stop":      true,
+	"continue":  true,
+}
+
+/**
+ * HTTP status codes for DBGp error codes.
+ *
+ * Error codes missing here are treated as the DBGp engine's failure.
+ *
+ * @see https://xdebug.org/docs/dbgp#error-codes
+ */
+var apiErrorStatuses = map[int]int{
+	message.InvalidCmdErrorCode: http.StatusBadRequest,
+	message.NoSessionErrorCode:  http.StatusConflict,
+	message.TimeoutErrorCode:    http.StatusGatewayTimeout,
+	message.SessionEndErrorCode: http.StatusConflict,
+	message.FileErrorCode:       http.StatusInternalServerError,
+
+	// DBGp engine's own errors.
+	1:   http.StatusBadRequest,     // Parse error in command.
+	3:   http.StatusBadRequest,     // Invalid options.
+	4:   http.StatusNotImplemented, // Unimplemented command.
+	5:   http.StatusConflict,       // Command not available.
+	100: http.StatusNotFound,       // Can not open file.
+	200: http.StatusBadRequest,     // Breakpoint could not be set.
+	201: http.StatusBadRequest,     // Breakpoint type not supported.
+	202: http.StatusBadRequest,     // Invalid breakpoint.
+	203: http.StatusBadRequest,     // No code on breakpoint line.
+	205: http.StatusNotFound,       // No such breakpoint.
+	206: http.StatusBadRequest,     // Error evaluating code.
+	300: http.StatusNotFound,       // Can not get property.
+	301: http.StatusBadRequest,     // Stack depth invalid.
+	302: http.StatusBadRequest,     // Context invalid.
+}
+
+/**
+ * Wrapper for the "api" handler.
+ */
+func makeAPIHandler(out chan tracker.Request, codeDir string) http.HandlerFunc {
+
+	return func(writeStream http.ResponseWriter, request *http.Request) {
+
+		serveAPI(writeStream, request, out, codeDir)
+	}
+}
+
+/**
+ * Serves the paths under API_PREFIX.
+ *
+ * Turns the API call into a Footle command, waits for the response, and then
+ * writes it as JSON.
+ */
+func serveAPI(writeStream http.ResponseWriter, request *http.Request, debugger chan tracker.Request, codeDir string) {
+
+	cmd, status, err := prepareAPICmd(request)
+	if err != nil {
+		writeAPIError(writeStream, status, err)
+		return
+	}
+
+	apiRequest := tracker.NewSync(cmd, APIOrigin)
+	debugger <- apiRequest
+
+	select {
+	case reply := <-apiRequest.Reply:
+		writeAPIResponse(writeStream, adjustFilepath(reply, codeDir))
+	case <-request.Context().Done():
+		// The HTTP client has given up.
+	}
+}
+
+/**
+ * Turn an API call into a Footle command.
+ *
+ * Returns the HTTP status code to use when the call cannot be turned into a
+ * command.
+ */
+func prepareAPICmd(request *http.Request) (cmd string, status int, err error) {
+
+	path := strings.Trim(strings.TrimPrefix(request.URL.Path, API_PREFIX), "/")
+	pathParts := strings.Split(path, "/")
+	resource := pathParts[0]
+	method := request.Method
+
+	if apiExecutionCmds[resource] && len(pathParts) == 1 {
+		if method != http.MethodPost {
+			return cmd, http.StatusMethodNotAllowed, fmt.Errorf("Use POST for %s.", resource)
+		}
+
+		return resource, status, err
+	}
+
+	switch {
+	case resource == "run_to" && len(pathParts) == 1 && method == http.MethodPost:
+		return prepareLocationCmd("run_to", request)
+
+	case resource == "status" && len(pathParts) == 1 && method == http.MethodGet:
+		return "status", status, err
+
+	case resource == "stack" && len(pathParts) == 1 && method == http.MethodGet:
+		return "stack_get", status, err
+
+	case resource == "context" && len(pathParts) == 2 && method == http.MethodGet:
+		return prepareContextCmd(pathParts[1], request.FormValue("depth"))
+
+	case resource == "property" && len(pathParts) == 2 && method == http.MethodGet:
+		return preparePropertyCmd(pathParts[1], request.FormValue("name"))
+
+	case resource == "breakpoints" && len(pathParts) == 1 && method == http.MethodGet:
+		return "breakpoint_list", status, err
+
+	case resource == "breakpoints" && len(pathParts) == 1 && method == http.MethodPost:
+		return prepareLocationCmd("breakpoint_set", request)
+
+	case resource == "breakpoints" && len(pathParts) == 2 && method == http.MethodGet:
+		return prepareBreakpointIdCmd("breakpoint_get", pathParts[1])
+
+	case resource == "breakpoints" && len(pathParts) == 2 && method == http.MethodDelete:
+		return prepareBreakpointIdCmd("breakpoint_remove", pathParts[1])
+	}
+
+	return cmd, http.StatusNotFound, fmt.Errorf("Unknown API call: %s %s", method, request.URL.Path)
+}
+
+/**
+ * The context_get command for a context name and optional stack depth.
+ */
+func prepareContextCmd(contextName, depth string) (cmd string, status int, err error) {
+
+	if contextName != "local" && contextName != "global" {
+		return cmd, http.StatusNotFound, fmt.Errorf("Unknown context: %s", contextName)
+	}
+
+	if depth == "" {
+		return "context_get " + contextName, status, err
+	}
+
+	if depthNum, err := strconv.Atoi(depth); err != nil || depthNum < 0 {
+		return cmd, http.StatusBadRequest, fmt.Errorf("Invalid stack depth: %s", depth)
+	}
+
+	return fmt.Sprintf("context_get %s %s", contextName, depth), status, err
+}
+
+/**
+ * The property_get command for a variable in the given context.
+ */
+func preparePropertyCmd(contextName, variableName string) (cmd string, status int, err error) {
+
+	if contextName != "local" && contextName != "global" {
+		return cmd, http.StatusNotFound, fmt.Errorf("Unknown context: %s", contextName)
+	}
+
+	if variableName == "" {
+		return cmd, http.StatusBadRequest, fmt.Errorf("Variable name is missing.")
+	}
+
+	return fmt.Sprintf("property_get %s %s", contextName, variableName), status, err
+}
+
+/**
+ * A command that takes a file location such as breakpoint_set or run_to.
+ */
+func prepareLocationCmd(cmdName string, request *http.Request) (cmd string, status int, err error) {
+
+	filename, lineNo, err := extractLocation(request)
+	if err != nil {
+		return cmd, http.StatusBadRequest, err
+	}
+
+	return fmt.Sprintf("%s %s %s", cmdName, filename, lineNo), status, err
+}
+
+/**
+ * A breakpoint command that takes a breakpoint ID.
+ */
+func prepareBreakpointIdCmd(cmdName, breakpointId string) (cmd string, status int, err error) {
+
+	if _, err = strconv.Atoi(breakpointId); err != nil {
+		return cmd, http.StatusBadRequest, fmt.Errorf("Invalid breakpoint ID: %s", breakpointId)
+	}
+
+	return fmt.Sprintf("%s %s", cmdName, breakpointId), status, err
+}
+
+/**
+ * Extract a file location from the "file" and "line" form values.
+ *
+ * Footle commands are space separated.  So filenames with spaces are rejected.
+ */
+func extractLocation(request *http.Request) (filename, lineNo string, err error) {
+
+	filename = request.FormValue("file")
+	lineNo = request.FormValue("line")
+
+	if filename == "" || strings.ContainsAny(filename, " \t\n") {
+		return filename, lineNo, fmt.Errorf("Invalid filename: %q", filename)
+	}
+
+	if lineNum, err := strconv.Atoi(lineNo); err != nil || lineNum < 1 {
+		return filename, lineNo, fmt.Errorf("Invalid line number: %q", lineNo)
+	}
+
+	return filename, lineNo, err
+}
+
+/**
+ * Determine the HTTP status code for a response.
+ */
+func determineAPIStatus(msg message.Message) (status int) {
+
+	errorCode := msg.Properties.ErrorCode
+	if errorCode == 0 {
+		return http.StatusOK
+	}
+
+	if status, exists := apiErrorStatuses[errorCode]; exists {
+		return status
+	}
+
+	return http.StatusBadGateway
+}
+
+/**
+ * Write a response as JSON.
+ */
+func writeAPIResponse(writeStream http.ResponseWriter, msg message.Message) {
+
+	writeStream.Header().Set("Content-Type", "application/json")
+	writeStream.Header().Set("Cache-control", "no-cache")
+	writeStream.WriteHeader(determineAPIStatus(msg))
+
+	json.NewEncoder(writeStream).Encode(msg)
+}
+
+/**
+ * Write an error in the same shape as an error response from the DBGp engine.
+ */
+func writeAPIError(writeStream http.ResponseWriter, status int, err error) {
+
+	errorMsg := message.PrepareErrorMsg("", 0, message.InvalidCmdErrorCode, err.Error())
+	errorMsg.Origin = APIOrigin
+
+	writeStream.Header().Set("Content-Type", "application/json")
+	writeStream.WriteHeader(status)
+
+	json.NewEncoder(writeStream).Encode(errorMsg)
+}
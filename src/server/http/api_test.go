@@ -0,0 +1,124 @@
+/**
+ * @file
+ * Tests for the REST API.
+ */
+
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"server/core/tracker"
+	"server/dbgp/message"
+	"strings"
+	"testing"
+)
+
+/**
+ * Tests for prepareAPICmd().
+ */
+func TestPrepareAPICmd(t *testing.T) {
+
+	passCases := []struct {
+		method string
+		target string
+		cmd    string
+	}{
+		// {Input method, input URL, expected command}
+		{"POST", "/api/v1/step_over", "step_over"},
+		{"POST", "/api/v1/break", "break"},
+		{"GET", "/api/v1/stack", "stack_get"},
+		{"GET", "/api/v1/context/local", "context_get local"},
+		{"GET", "/api/v1/context/global?depth=1", "context_get global 1"},
+		{"GET", "/api/v1/property/local?name=foo", "property_get local foo"},
+		{"GET", "/api/v1/breakpoints", "breakpoint_list"},
+		{"POST", "/api/v1/breakpoints?file=index.php&line=18", "breakpoint_set index.php 18"},
+		{"POST", "/api/v1/run_to?file=index.php&line=20", "run_to index.php 20"},
+		{"GET", "/api/v1/breakpoints/3", "breakpoint_get 3"},
+		{"DELETE", "/api/v1/breakpoints/3", "breakpoint_remove 3"},
+	}
+
+	for _, test := range passCases {
+		request := httptest.NewRequest(test.method, test.target, nil)
+
+		if cmd, _, err := prepareAPICmd(request); err != nil || cmd != test.cmd {
+			t.Errorf("prepareAPICmd(%s %s) = %q, %v", test.method, test.target, cmd, err)
+		}
+	}
+
+	failCases := []struct {
+		method string
+		target string
+		status int
+	}{
+		// {Input method, input URL, expected HTTP status}
+		{"GET", "/api/v1/step_over", http.StatusMethodNotAllowed},
+		{"GET", "/api/v1/foo", http.StatusNotFound},
+		{"GET", "/api/v1/context/foo", http.StatusNotFound},
+		{"GET", "/api/v1/context/local?depth=-1", http.StatusBadRequest},
+		{"POST", "/api/v1/breakpoints?file=index.php&line=foo", http.StatusBadRequest},
+		{"POST", "/api/v1/breakpoints?file=my+index.php&line=3", http.StatusBadRequest},
+		{"DELETE", "/api/v1/breakpoints/foo", http.StatusBadRequest},
+	}
+
+	for _, test := range failCases {
+		request := httptest.NewRequest(test.method, test.target, nil)
+
+		if cmd, status, err := prepareAPICmd(request); err == nil || status != test.status {
+			t.Errorf("prepareAPICmd(%s %s) = %q, %d", test.method, test.target, cmd, status)
+		}
+	}
+}
+
+/**
+ * Tests for serveAPI().
+ *
+ * Plays the role of the debugger by answering API requests.
+ */
+func TestServeAPI(t *testing.T) {
+
+	commands := make(chan tracker.Request)
+	go func() {
+		for request := range commands {
+			response := message.Message{MessageType: "response", State: "break"}
+			response.Properties.Command = request.Cmd
+
+			if request.Cmd == "breakpoint_remove 99" {
+				response.Properties.ErrorCode = 205
+				response.Properties.ErrorMessage = "No such breakpoint"
+			}
+
+			request.Answer(response)
+		}
+	}()
+	defer close(commands)
+
+	testCases := []struct {
+		method string
+		target string
+		status int
+	}{
+		// {Input method, input URL, expected HTTP status}
+		{"POST", "/api/v1/step_over", http.StatusOK},
+		{"DELETE", "/api/v1/breakpoints/99", http.StatusNotFound},
+		{"PUT", "/api/v1/breakpoints", http.StatusNotFound},
+	}
+
+	for _, test := range testCases {
+		request := httptest.NewRequest(test.method, test.target, strings.NewReader(url.Values{}.Encode()))
+		writer := httptest.NewRecorder()
+
+		serveAPI(writer, request, commands, "/foo")
+
+		if writer.Code != test.status {
+			t.Errorf("%s %s returned HTTP status %d", test.method, test.target, writer.Code)
+		}
+
+		var reply message.Message
+		if err := json.Unmarshal(writer.Body.Bytes(), &reply); err != nil || reply.Origin != APIOrigin {
+			t.Errorf("%s %s returned: %s", test.method, test.target, writer.Body.String())
+		}
+	}
+}
@@ -0,0 +1,255 @@
+/**
+ * @file
+ * Registry of HTTP clients listening for DBGp messages.
+ *
+ * Each client has a bounded message queue.  Broadcasts never wait for a
+ * client.  When a client's queue is full, the message is dropped for that
+ * client.  The client is then asked to resync once it catches up.  A client
+ * that keeps falling behind is disconnected.  Disconnected browsers reconnect
+ * by themselves and catch up.
+ *
+ * Every message is numbered.  Recent messages are kept so that a reconnecting
+ * browser receives whatever it has missed since the last message it has seen.
+ */
+
+package http
+
+import (
+	"log"
+	"sync"
+)
+
+/**
+ * Number of messages a client can fall behind before messages are dropped.
+ */
+const clientQueueSize = 64
+
+/**
+ * Consecutive dropped messages before a client is disconnected.
+ */
+const maxDroppedMsgs = 16
+
+/**
+ * Number of recent messages kept for reconnecting clients.
+ *
+ * Leaves room in a new client's queue for the "resync" event.
+ */
+const historySize = clientQueueSize - 1
+
+/**
+ * Event names.
+ *
+ * The "resync" event tells a reconnecting client that it has missed more
+ * messages than we can replay.  It should then start afresh.
+ */
+const dbgpEventName = "dbgp"
+const resyncEventName = "resync"
+
+/**
+ * A numbered message for HTTP clients.
+ */
+type streamEvent struct {
+	Id   int64
+	Name string
+	Data string
+}
+
+/**
+ * Message queue of an HTTP client.  Closed when the client is disconnected.
+ */
+type client chan<- streamEvent
+
+/**
+ * A newly arrived client.
+ *
+ * Reconnecting clients tell the ID of the last event they have seen.  Zero for
+ * new clients.
+ */
+type clientArrival struct {
+	queue       client
+	lastEventId int64
+}
+
+type clientRegistry struct {
+	sync.Mutex
+
+	droppedMsgs map[client]int // Consecutive drops per client.
+	history     []streamEvent
+	lastEventId int64
+}
+
+/**
+ * List of HTTP clients that are currently listening for Server sent events or
+ * WebSocket messages.
+ */
+var clientList = newClientRegistry()
+
+/**
+ * Prepare an empty client registry.
+ */
+func newClientRegistry() *clientRegistry {
+
+	return &clientRegistry{droppedMsgs: make(map[client]int)}
+}
+
+/**
+ * Prepare the message queue for a newly arrived client.
+ */
+func newClientQueue() chan streamEvent {
+
+	return make(chan streamEvent, clientQueueSize)
+}
+
+/**
+ * Start sending messages to a client.
+ *
+ * A reconnecting client first receives the messages it has missed.  The
+ * client's queue is fresh, so these always fit.  A client that has last heard
+ * from us before a restart has to resync as our event IDs have started afresh.
+ */
+func (r *clientRegistry) add(arrival clientArrival) {
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.droppedMsgs[arrival.queue] = 0
+
+	if arrival.lastEventId <= 0 {
+		return
+	}
+
+	isFromBeforeRestart := arrival.lastEventId > r.lastEventId
+	if isFromBeforeRestart {
+		arrival.queue <- streamEvent{Name: resyncEventName}
+		return
+	}
+
+	hasMissedTooMany := len(r.history) > 0 && r.history[0].Id > arrival.lastEventId+1
+	if hasMissedTooMany {
+		arrival.queue <- streamEvent{Name: resyncEventName}
+	}
+
+	for _, missed := range r.history {
+		if missed.Id > arrival.lastEventId {
+			arrival.queue <- missed
+		}
+	}
+}
+
+/**
+ * Stop sending messages to a client and close its queue.
+ *
+ * A client may already have been removed for being slow.  So removing an
+ * unknown client is harmless.
+ */
+func (r *clientRegistry) remove(clientChannel client) {
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.removeUnlocked(clientChannel)
+}
+
+/**
+ * Same as remove() for callers holding the lock.
+ */
+func (r *clientRegistry) removeUnlocked(clientChannel client) {
+
+	if _, exists := r.droppedMsgs[clientChannel]; !exists {
+		return
+	}
+
+	delete(r.droppedMsgs, clientChannel)
+	close(clientChannel)
+}
+
+/**
+ * Number of clients currently listening.
+ */
+func (r *clientRegistry) count() int {
+
+	r.Lock()
+	defer r.Unlock()
+
+	return len(r.droppedMsgs)
+}
+
+/**
+ * Queue a message for every client without waiting for any of them.
+ *
+ * Clients with a full queue miss the message.  Those that have missed too many
+ * in a row are disconnected.  Others are asked to resync as soon as there is
+ * room in their queue.
+ */
+func (r *clientRegistry) broadcast(msg string) {
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.lastEventId++
+	event := streamEvent{Id: r.lastEventId, Name: dbgpEventName, Data: msg}
+
+	r.history = append(r.history, event)
+	if len(r.history) > historySize {
+		r.history = r.history[len(r.history)-historySize:]
+	}
+
+	for clientChannel, droppedMsgs := range r.droppedMsgs {
+		hasMissedMsgs := droppedMsgs > 0
+		if hasMissedMsgs && !r.queueUnlocked(clientChannel, streamEvent{Name: resyncEventName}) {
+			continue
+		}
+
+		r.queueUnlocked(clientChannel, event)
+	}
+}
+
+/**
+ * Queue an event for a client if there is room.
+ *
+ * Returns false when the client misses the event.
+ */
+func (r *clientRegistry) queueUnlocked(clientChannel client, event streamEvent) bool {
+
+	select {
+	case clientChannel <- event:
+		r.droppedMsgs[clientChannel] = 0
+		return true
+	default:
+		r.droppedMsgs[clientChannel]++
+
+		if r.droppedMsgs[clientChannel] >= maxDroppedMsgs {
+			log.Println("Disconnecting an HTTP client that has stopped listening.")
+			r.removeUnlocked(clientChannel)
+		}
+
+		return false
+	}
+}
+
+/**
+ * Writes a string message to all current client channels.
+ */
+func broadcast(msg string, httpClientList *clientRegistry) {
+
+	httpClientList.broadcast(msg)
+}
+
+/**
+ * Update the list of HTTP clients currently listening.
+ *
+ * When an HTTP client first starts listening for Server sent events, we
+ * add it as a new client and vice-versa.
+ */
+func manageClients(httpClientList *clientRegistry, arrival <-chan clientArrival, departure <-chan client) {
+
+	for {
+		select {
+		case newClient := <-arrival:
+			httpClientList.add(newClient)
+
+		case clientChannel := <-departure:
+			httpClientList.remove(clientChannel)
+		}
+	}
+}
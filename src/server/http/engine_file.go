@@ -0,0 +1,55 @@
+/**
+ * @file
+ * The DBGp engine's copy of a file as HTML.
+ *
+ * Useful when the engine runs code that differs from the local copy.  Then
+ * breaks make sense only in the engine's copy.  Formatted like the local copy
+ * at /formatted-file/.  Only files the engine has broken in during the current
+ * session are available.
+ *
+ * Example: GET /engine-file/src/User.php
+ *
+ * @see divergence
+ */
+
+package http
+
+import (
+	"io"
+	"net/http"
+	"path/filepath"
+	"server/codebase"
+	"server/core/divergence"
+	"server/http/file"
+)
+
+/**
+ * Wrapper for the engine file handler.
+ *
+ * Relative paths are resolved against the engine's codebase.
+ *
+ * @see config.DetermineCodeDir()
+ */
+func makeEngineFileHandler(engineCodeDir string, encodings *codebase.Encodings) http.HandlerFunc {
+
+	return func(writeStream http.ResponseWriter, request *http.Request) {
+
+		filePath := request.URL.Path[len("/engine-file/"):]
+		fileUri := "file://" + filepath.Join(engineCodeDir, filepath.FromSlash(filePath))
+
+		src, isAvailable := divergence.EngineCopy(fileUri)
+		if !isAvailable {
+			http.Error(writeStream, "The DBGp engine's copy of this file is unavailable.", http.StatusNotFound)
+			return
+		}
+
+		output, err := file.Format(filePath, src, encodings)
+		if err != nil {
+			http.Error(writeStream, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeStream.Header().Set("Content-Type", "text/html")
+		io.WriteString(writeStream, output)
+	}
+}
@@ -0,0 +1,32 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"server/core/divergence"
+	"strings"
+	"testing"
+)
+
+/**
+ * Tests for the engine file handler.
+ */
+func TestEngineFileHandler(t *testing.T) {
+
+	defer divergence.Forget()
+
+	divergence.Remember("file:///var/www/src/index.php", []byte("<?php\necho 'engine';\n"))
+	handler := makeEngineFileHandler("/var/www", nil)
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("GET", "/engine-file/src/index.php", nil))
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "engine") || !strings.Contains(recorder.Body.String(), "line__2") {
+		t.Errorf("Unexpected engine copy: %d, %s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("GET", "/engine-file/src/other.php", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown file, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,96 @@
+/**
+ * @file
+ * Cache of formatted files.
+ *
+ * Tokenizing a large file takes time.  So formatted files are kept until the
+ * file is modified.  The highlighted lines are kept too so that any range of
+ * lines can be formatted quickly.
+ */
+
+package file
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+/**
+ * Number of formatted files to keep.  The least recently used goes first.
+ */
+const maxCachedFiles = 64
+
+/**
+ * Content of a file ready for display.  Never modified once prepared.
+ */
+type highlightedFile struct {
+	lines         []sourceLine
+	formattedFile string
+}
+
+type cachedFile struct {
+	modTime     time.Time
+	size        int64
+	highlighted *highlightedFile
+	usedAt      time.Time
+}
+
+type fileCache struct {
+	sync.Mutex
+
+	files map[string]*cachedFile
+}
+
+var formattedFiles = fileCache{files: make(map[string]*cachedFile)}
+
+/**
+ * Fetch a formatted file unless the file has changed since.
+ */
+func (c *fileCache) get(path string, fileInfo os.FileInfo) (highlighted *highlightedFile, isCached bool) {
+
+	c.Lock()
+	defer c.Unlock()
+
+	cached, exists := c.files[path]
+	if !exists || !cached.modTime.Equal(fileInfo.ModTime()) || cached.size != fileInfo.Size() {
+		return highlighted, false
+	}
+
+	cached.usedAt = time.Now()
+
+	return cached.highlighted, true
+}
+
+/**
+ * Keep a formatted file.
+ */
+func (c *fileCache) put(path string, fileInfo os.FileInfo, highlighted *highlightedFile) {
+
+	c.Lock()
+	defer c.Unlock()
+
+	if _, exists := c.files[path]; !exists && len(c.files) >= maxCachedFiles {
+		c.evictLeastRecentlyUsed()
+	}
+
+	c.files[path] = &cachedFile{
+		modTime:     fileInfo.ModTime(),
+		size:        fileInfo.Size(),
+		highlighted: highlighted,
+		usedAt:      time.Now(),
+	}
+}
+
+func (c *fileCache) evictLeastRecentlyUsed() {
+
+	var leastRecentPath string
+	var leastRecentUse time.Time
+
+	for path, cached := range c.files {
+		if leastRecentPath == "" || cached.usedAt.Before(leastRecentUse) {
+			leastRecentPath, leastRecentUse = path, cached.usedAt
+		}
+	}
+
+	delete(c.files, leastRecentPath)
+}
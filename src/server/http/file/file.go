@@ -1,5 +1,18 @@
 /**
  * Package for formatting a file as HTML.
+ *
+ * PHP files are syntax highlighted.  Each token is wrapped in a span whose
+ * class tells the token type.  Example:
+ *   <span class="token token--keyword">echo</span>
+ *
+ * Lines that cannot carry a breakpoint have the "line--not-executable" class.
+ *
+ * Large files can be formatted in parts, a range of lines at a time.  Lines can
+ * be of any length.
+ *
+ * Files that are not in UTF-8 are transcoded to UTF-8 first.
+ *
+ * Formatted files are cached until the file changes.
  */
 
 package file
@@ -9,7 +22,12 @@ import (
 	"bytes"
 	"html/template"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"path/filepath"
+	"server/codebase"
+	"server/php"
+	"strings"
 )
 
 var fileTpl *template.Template
@@ -18,75 +36,199 @@ const FILE_TEMPLATE = "file.html"
 
 func init() {
 
-	// In DBGp, the line number for the first line is one rather than zero.
-	funcMap := template.FuncMap{
-		"plusone": func(arg int) int {
-			return arg + 1
-		},
-	}
-
-	fileTpl = template.Must(template.New(FILE_TEMPLATE).Funcs(funcMap).Parse(fileTemplate))
+	fileTpl = template.Must(template.New(FILE_TEMPLATE).Parse(fileTemplate))
 }
 
 /**
  * HTML-ify the given file.
  *
  * Prepare HTML markup for the given file.  The file content is HTML escaped.
+ * The encodings decide how to transcode the file to UTF-8.  Nil means
+ * auto-detection only.
  */
-func GrabIt(codebase http.Dir, path string) (formattedFile string, err error) {
+func GrabIt(codebase http.Dir, path string, encodings *codebase.Encodings) (formattedFile string, err error) {
 
-	fileDesc, err := codebase.Open(path)
+	highlighted, err := grabHighlighted(codebase, path, encodings)
 	if err != nil {
 		return formattedFile, err
 	}
-	defer fileDesc.Close()
 
-	lines, err := readFileLines(fileDesc)
-	formattedFile = formatFile(lines)
+	return highlighted.formattedFile, nil
+}
 
-	return formattedFile, err
+/**
+ * HTML-ify a range of lines of the given file.
+ *
+ * Line numbers start at 1.  Both ends of the range are included.  A "to" of
+ * zero means the last line.  The range is trimmed to fit the file.  Also
+ * returns the number of lines in the whole file.
+ */
+func GrabLines(codebase http.Dir, path string, from, to int, encodings *codebase.Encodings) (formattedLines string, lineCount int, err error) {
+
+	highlighted, err := grabHighlighted(codebase, path, encodings)
+	if err != nil {
+		return formattedLines, lineCount, err
+	}
+
+	lineCount = len(highlighted.lines)
+
+	if from < 1 {
+		from = 1
+	}
+
+	if to == 0 || to > lineCount {
+		to = lineCount
+	}
+
+	if from == 1 && to == lineCount {
+		return highlighted.formattedFile, lineCount, nil
+	} else if from > to {
+		return formatFile(nil), lineCount, nil
+	}
+
+	return formatFile(highlighted.lines[from-1 : to]), lineCount, nil
 }
 
 /**
- * Prepare a list of lines for the given file.
+ * Highlighted and formatted content of the given file.
+ *
+ * Reused until the file changes.
  */
-func grabFileLines(codebase http.Dir, path string) (lines []string, err error) {
+func grabHighlighted(codebase http.Dir, path string, encodings *codebase.Encodings) (highlighted *highlightedFile, err error) {
 
 	fileDesc, err := codebase.Open(path)
 	if err != nil {
-		return lines, err
+		return highlighted, err
 	}
 	defer fileDesc.Close()
 
-	lines, err = readFileLines(fileDesc)
+	fileInfo, err := fileDesc.Stat()
+	if err != nil {
+		return highlighted, err
+	}
+
+	cacheKey := filepath.Join(string(codebase), path)
+	if highlighted, isCached := formattedFiles.get(cacheKey, fileInfo); isCached {
+		return highlighted, nil
+	}
 
-	return lines, err
+	src, err := ioutil.ReadAll(fileDesc)
+	if err != nil {
+		return highlighted, err
+	}
+
+	if highlighted, err = prepareHighlighted(path, src, encodings); err != nil {
+		return highlighted, err
+	}
+
+	formattedFiles.put(cacheKey, fileInfo, highlighted)
+
+	return highlighted, nil
+}
+
+/**
+ * HTML-ify the given content of a file.
+ *
+ * Same as GrabIt() but for content that is not on disk, e.g. the DBGp engine's
+ * copy of a file.  Not cached.
+ */
+func Format(path string, src []byte, encodings *codebase.Encodings) (formattedFile string, err error) {
+
+	highlighted, err := prepareHighlighted(path, src, encodings)
+	if err != nil {
+		return formattedFile, err
+	}
+
+	return highlighted.formattedFile, nil
+}
+
+/**
+ * Highlight and format the content of a file.
+ */
+func prepareHighlighted(path string, src []byte, encodings *codebase.Encodings) (highlighted *highlightedFile, err error) {
+
+	src, err = encodings.Decode(path, src)
+	if err != nil {
+		return highlighted, err
+	}
+
+	lines, err := readFileLines(bytes.NewReader(src))
+	if err != nil {
+		return highlighted, err
+	}
+
+	sourceLines := highlight(lines, php.IsPHPFile(path))
+
+	return &highlightedFile{lines: sourceLines, formattedFile: formatFile(sourceLines)}, nil
 }
 
 /**
  * Read and split a file into its lines.
+ *
+ * Lines can be of any length; think minified code.  Line endings are dropped.
  */
 func readFileLines(in io.Reader) (lines []string, err error) {
 
-	scanner := bufio.NewScanner(in)
+	reader := bufio.NewReader(in)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break
+		} else if err != nil && err != io.EOF {
+			return lines, err
+		}
+
+		lines = append(lines, strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"))
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	return lines, nil
+}
+
+/**
+ * A line of source code.
+ */
+type sourceLine struct {
+	LineNo       int // The first line is 1.
+	Tokens       []php.Token
+	IsExecutable bool // Can it carry a breakpoint?
+}
+
+/**
+ * Break each line into tokens.
+ *
+ * Non-PHP files have one plain token per line.  Lines of PHP files that cannot
+ * carry a breakpoint are marked as such.
+ */
+func highlight(lines []string, isPHP bool) (sourceLines []sourceLine) {
+
+	if isPHP {
+		tokens := php.Tokenize(strings.Join(lines, "\n"))
+		executableLines := php.ExecutableLines(tokens)
 
-	for scanner.Scan() {
-		line := scanner.Text()
+		for lineIndex, lineTokens := range php.SplitLines(tokens) {
+			sourceLines = append(sourceLines, sourceLine{LineNo: lineIndex + 1, Tokens: lineTokens, IsExecutable: executableLines[lineIndex+1]})
+		}
 
-		lines = append(lines, line)
+		return sourceLines
 	}
 
-	if err := scanner.Err(); err != nil {
-		return lines, err
+	for lineNo, line := range lines {
+		lineTokens := []php.Token{{Type: php.Plain, Text: line, Line: lineNo + 1}}
+		sourceLines = append(sourceLines, sourceLine{LineNo: lineNo + 1, Tokens: lineTokens, IsExecutable: true})
 	}
 
-	return lines, err
+	return sourceLines
 }
 
 /**
  * Prepare HTML markup for a file's content.
  */
-func formatFile(lines []string) string {
+func formatFile(lines []sourceLine) string {
 
 	var buffer bytes.Buffer
 
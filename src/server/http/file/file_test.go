@@ -4,7 +4,15 @@
 
 package file
 
-import "testing"
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
 
 /**
  * Tests for formatFile().
@@ -18,7 +26,7 @@ func TestFormatFile(t *testing.T) {
 		"qux",
 	}
 
-	formattedFile := formatFile(lines)
+	formattedFile := formatFile(highlight(lines, false))
 
 	expectedOutput :=
 		`<table class="lines">
@@ -44,3 +52,138 @@ func TestFormatFile(t *testing.T) {
 		t.Error("Output mismatch for formatFile().")
 	}
 }
+
+/**
+ * Tests for highlighting PHP files.
+ */
+func TestFormatPHPFile(t *testing.T) {
+
+	lines := []string{
+		"<?php",
+		"/* Foo",
+		"   bar */ echo '<b>';",
+	}
+
+	formattedFile := formatFile(highlight(lines, true))
+
+	expectedLine := `<td class="line__code"><span class="token token--comment">   bar */</span> <span class="token token--keyword">echo</span> <span class="token token--string">&#39;&lt;b&gt;&#39;</span>;</td>`
+	if !strings.Contains(formattedFile, expectedLine) {
+		t.Errorf("PHP file not highlighted as expected: %s", formattedFile)
+	}
+
+	// Only the echo statement can carry a breakpoint.
+	if !strings.Contains(formattedFile, `<tr class="line line__2 line--not-executable">`) || strings.Contains(formattedFile, `line__3 line--not-executable`) {
+		t.Errorf("Executable lines not marked as expected: %s", formattedFile)
+	}
+}
+
+/**
+ * Tests for GrabIt().
+ *
+ * Formatted files should be reused until the file changes.
+ */
+func TestGrabIt(t *testing.T) {
+
+	tmpDir, err := ioutil.TempDir("", "footle-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "foo.php")
+	ioutil.WriteFile(path, []byte("<?php $foo;"), 0644)
+
+	formattedFile, err := GrabIt(http.Dir(tmpDir), "foo.php", nil)
+	if err != nil || !strings.Contains(formattedFile, "token--variable") {
+		t.Fatalf("Unexpected formatted file: %s, %v", formattedFile, err)
+	}
+
+	if _, isCached := formattedFiles.get(path, mustStat(t, path)); !isCached {
+		t.Error("Formatted file has not been cached.")
+	}
+
+	ioutil.WriteFile(path, []byte("<?php $barbaz;"), 0644)
+	later := time.Now().Add(time.Second)
+	os.Chtimes(path, later, later)
+
+	if formattedFile, _ = GrabIt(http.Dir(tmpDir), "foo.php", nil); !strings.Contains(formattedFile, "$barbaz") {
+		t.Errorf("Stale formatted file: %s", formattedFile)
+	}
+}
+
+/**
+ * Tests for GrabLines().
+ */
+func TestGrabLines(t *testing.T) {
+
+	tmpDir, err := ioutil.TempDir("", "footle-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ioutil.WriteFile(filepath.Join(tmpDir, "foo.txt"), []byte("one\ntwo\nthree\nfour\n"), 0644)
+
+	formattedLines, lineCount, err := GrabLines(http.Dir(tmpDir), "foo.txt", 2, 3, nil)
+	if err != nil || lineCount != 4 || !strings.Contains(formattedLines, `<td class="line__number">2</td>`) || !strings.Contains(formattedLines, "three") || strings.Contains(formattedLines, "four") {
+		t.Errorf("Unexpected range: %s, %d, %v", formattedLines, lineCount, err)
+	}
+
+	if formattedLines, _, _ = GrabLines(http.Dir(tmpDir), "foo.txt", 3, 0, nil); strings.Contains(formattedLines, "two") || !strings.Contains(formattedLines, "four") {
+		t.Errorf("Expected the rest of the file: %s", formattedLines)
+	}
+
+	if formattedLines, _, _ = GrabLines(http.Dir(tmpDir), "foo.txt", 10, 20, nil); strings.Contains(formattedLines, "<tr") {
+		t.Errorf("Expected no lines: %s", formattedLines)
+	}
+}
+
+/**
+ * Tests for transcoding in GrabIt().
+ *
+ * A Windows-1252 file should come out as UTF-8.
+ */
+func TestGrabItTranscodes(t *testing.T) {
+
+	tmpDir, err := ioutil.TempDir("", "footle-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ioutil.WriteFile(filepath.Join(tmpDir, "legacy.php"), []byte("<?php echo 'caf\xe9 \x80';"), 0644)
+
+	formattedFile, err := GrabIt(http.Dir(tmpDir), "legacy.php", nil)
+	if err != nil || !strings.Contains(formattedFile, "café €") {
+		t.Errorf("File not transcoded: %s, %v", formattedFile, err)
+	}
+}
+
+/**
+ * Tests for readFileLines().
+ *
+ * Lines longer than bufio.Scanner's limit should survive intact.
+ */
+func TestReadFileLines(t *testing.T) {
+
+	longLine := strings.Repeat("x", 200*1024)
+
+	lines, err := readFileLines(strings.NewReader("a\r\n" + longLine + "\n\nb"))
+	if err != nil || len(lines) != 4 || lines[0] != "a" || lines[1] != longLine || lines[2] != "" || lines[3] != "b" {
+		t.Errorf("Unexpected lines: %d, %v", len(lines), err)
+	}
+
+	if lines, _ = readFileLines(strings.NewReader("")); len(lines) != 0 {
+		t.Errorf("Expected no lines, got %d", len(lines))
+	}
+}
+
+func mustStat(t *testing.T, path string) os.FileInfo {
+
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fileInfo
+}
@@ -0,0 +1,46 @@
+/**
+ * @file
+ * Outline of a file.
+ *
+ * @see php/outline.go
+ */
+
+package file
+
+import (
+	"io/ioutil"
+	"net/http"
+	"server/codebase"
+	"server/php"
+)
+
+/**
+ * Classes, methods, functions, and constants of the given file.
+ *
+ * Non-PHP files have an empty outline.  So do PHP files without declarations.
+ * Broken code is outlined as well as possible.  Files that are not in UTF-8
+ * are transcoded first.
+ */
+func Outline(codebase http.Dir, path string, encodings *codebase.Encodings) (outline []php.OutlineItem, err error) {
+
+	fileDesc, err := codebase.Open(path)
+	if err != nil {
+		return outline, err
+	}
+	defer fileDesc.Close()
+
+	if !php.IsPHPFile(path) {
+		return []php.OutlineItem{}, nil
+	}
+
+	src, err := ioutil.ReadAll(fileDesc)
+	if err != nil {
+		return outline, err
+	}
+
+	if src, err = encodings.Decode(path, src); err != nil {
+		return outline, err
+	}
+
+	return php.Outline(php.Tokenize(string(src))), nil
+}
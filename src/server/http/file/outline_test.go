@@ -0,0 +1,49 @@
+/**
+ * Tests for file outlines.
+ */
+
+package file
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/**
+ * Tests for Outline().
+ */
+func TestOutline(t *testing.T) {
+
+	tmpDir, err := ioutil.TempDir("", "footle-outline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Mid-edit.
+	ioutil.WriteFile(filepath.Join(tmpDir, "foo.php"), []byte("<?php\nclass Foo {\n  function bar() {\n    if ("), 0644)
+	ioutil.WriteFile(filepath.Join(tmpDir, "foo.txt"), []byte("class Foo {}"), 0644)
+
+	// UTF-16 with a byte order mark.
+	ioutil.WriteFile(filepath.Join(tmpDir, "utf16.php"), []byte("\xfe\xff\x00<\x00?\x00p\x00h\x00p\x00\n\x00c\x00l\x00a\x00s\x00s\x00 \x00Q\x00u\x00x\x00 \x00{\x00}"), 0644)
+
+	outline, err := Outline(http.Dir(tmpDir), "foo.php", nil)
+	if err != nil || len(outline) != 1 || len(outline[0].Members) != 1 || outline[0].Members[0].EndLine != 4 {
+		t.Errorf("Unexpected outline: %v, %v", outline, err)
+	}
+
+	if outline, err = Outline(http.Dir(tmpDir), "utf16.php", nil); err != nil || len(outline) != 1 || outline[0].Name != "Qux" {
+		t.Errorf("Unexpected outline of a UTF-16 file: %v, %v", outline, err)
+	}
+
+	if outline, err = Outline(http.Dir(tmpDir), "foo.txt", nil); err != nil || outline == nil || len(outline) != 0 {
+		t.Errorf("Expected an empty outline: %v, %v", outline, err)
+	}
+
+	if _, err = Outline(http.Dir(tmpDir), "missing.php", nil); !os.IsNotExist(err) {
+		t.Errorf("Expected a missing file: %v", err)
+	}
+}
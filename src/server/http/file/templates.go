@@ -6,19 +6,22 @@
  * of a template is determined *relative* to the executable and the path of the
  * executable differs during tests. So we are keeping the templates in code.
  *
- * Custom functions:
- *  - plusone: Increments its sole argument by one.  Useful for treating
- *    zero-based indexes as one-based.  This helps with printing line
- *    numbers in source code where each line is an array element.
+ * Each line is a list of PHP tokens.  Plain tokens are not wrapped in spans.
+ * Lines that cannot carry a breakpoint are marked with a class.  Lines carry
+ * their own line numbers as only part of a file may be formatted.
  */
 
 package file
 
 var fileTemplate string = `<table class="lines">
-  {{- range $key, $value := . }}
-  <tr class="line line__{{ plusone $key }}">
-    <td class="line__number">{{ plusone $key }}</td>
-    <td class="line__code">{{ . }}</td>
+  {{- range . }}
+  <tr class="line line__{{ .LineNo }}{{ if not .IsExecutable }} line--not-executable{{ end }}">
+    <td class="line__number">{{ .LineNo }}</td>
+    <td class="line__code">
+      {{- range .Tokens }}
+        {{- if .Type }}<span class="token token--{{ .Type }}">{{ .Text }}</span>{{ else }}{{ .Text }}{{ end }}
+      {{- end -}}
+    </td>
   </tr>
   {{- end }}
 </table>`
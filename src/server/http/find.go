@@ -0,0 +1,68 @@
+/**
+ * @file
+ * Fuzzy file finder for the codebase.
+ *
+ * Returns the files whose path best matches the query, best first.  Works like
+ * Ctrl-P in editors.
+ *
+ * Examples:
+ *   - GET /api/find?q=usrctl
+ *   - GET /api/find?q=src/ctrl&limit=50
+ */
+
+package http
+
+import (
+	"errors"
+	"net/http"
+	"server/codebase"
+	"strconv"
+)
+
+const defaultFindLimit = 20
+const maxFindLimit = 200
+
+/**
+ * Wrapper for the "find" handler.
+ */
+func makeFindHandler(index func() *codebase.Index) http.HandlerFunc {
+
+	return func(writeStream http.ResponseWriter, request *http.Request) {
+
+		if request.Method != http.MethodGet {
+			writeJSONError(writeStream, http.StatusMethodNotAllowed, "Only GET is supported.")
+			return
+		}
+
+		query := request.URL.Query()
+
+		limit, err := parseLimit(query.Get("limit"), defaultFindLimit, maxFindLimit)
+		if err != nil {
+			writeJSONError(writeStream, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		writeJSON(writeStream, http.StatusOK, index().Find(query.Get("q"), limit))
+	}
+}
+
+/**
+ * Parse the "limit" query parameter.
+ *
+ * Falls back to the default when the parameter is missing.  Large values are
+ * capped.
+ */
+func parseLimit(limitParam string, defaultLimit, maxLimit int) (limit int, err error) {
+
+	if limitParam == "" {
+		return defaultLimit, nil
+	}
+
+	if limit, err = strconv.Atoi(limitParam); err != nil || limit < 1 {
+		return 0, errors.New("Invalid limit.")
+	} else if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	return limit, nil
+}
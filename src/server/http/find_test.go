@@ -0,0 +1,51 @@
+package http
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"server/codebase"
+	"testing"
+)
+
+/**
+ * Tests for the fuzzy file finder handler.
+ */
+func TestFindHandler(t *testing.T) {
+
+	codeDir, err := ioutil.TempDir("", "footle-find")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(codeDir)
+
+	os.Mkdir(filepath.Join(codeDir, "src"), 0755)
+	ioutil.WriteFile(filepath.Join(codeDir, "src", "UserController.php"), []byte("<?php"), 0644)
+	ioutil.WriteFile(filepath.Join(codeDir, "index.php"), []byte("<?php"), 0644)
+
+	index := codebase.NewIndex(codeDir, nil)
+	index.Build()
+	handler := makeFindHandler(func() *codebase.Index { return index })
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("GET", "/api/find?q=usrctl", nil))
+
+	var matches []codebase.Match
+	if err := json.NewDecoder(recorder.Body).Decode(&matches); err != nil || recorder.Code != http.StatusOK {
+		t.Fatalf("Unexpected response: %d, %v", recorder.Code, err)
+	}
+
+	if len(matches) != 1 || matches[0].Path != "src/UserController.php" {
+		t.Errorf("Unexpected matches: %v", matches)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("GET", "/api/find?q=php&limit=x", nil))
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected a bad request, got %d", recorder.Code)
+	}
+}
@@ -20,13 +20,18 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"server/codebase"
 	"server/config"
 	footlecmd "server/core/cmd"
 	"server/core/current-state"
+	"server/core/tracker"
+	"server/core/watcher"
 	"server/dbgp/command"
 	"server/dbgp/message"
 	"server/http/file"
 	"server/http/uibundle"
+	"strconv"
+	"time"
 
 	"github.com/elazarl/go-bindata-assetfs"
 )
@@ -39,35 +44,39 @@ import (
  */
 const EMBEDDED_UI_DIR = "ui"
 
-type client chan<- string
-
 /**
- * List of HTTP clients that are currently listening for Server sent events.
+ * Identifies commands issued over HTTP.
+ *
+ * HTTP clients can append their own ID to tell their commands apart.
+ * Example: "http:3f2a"
  */
-var clientList map[client]bool
+const Origin = "http"
 
 /**
- * Initializes current HTTP client list.
+ * How often idle Server sent event streams receive a heartbeat comment.
  */
-func init() {
-
-	clientList = make(map[client]bool)
-}
+const heartbeatInterval = 15 * time.Second
 
 /**
  * Setup HTTP server.
  *
- * Five handlers are used:
+ * Handlers used:
  *   - HTTP interface for Footle.
  *   - A file browser for selecting files that will be debugged.
  *   - File content rendered as HTML.
+ *   - The DBGp engine's copy of the same, when it differs.
+ *   - JSON file tree for the file browser.
  *   - Debugging command receiver.  This is supposed to be called over Ajax.
  *   - Debugging output sender.  This is supposed to be consumed using
  *     Server sent events.
+ *   - WebSocket carrying both debugging commands and output.  SSE and the
+ *     command receiver remain as a fallback.
+ *   - Current state of Footle.
+ *   - JSON REST API.  Each call waits for its response.
  *
  * Uses global variable "clientList."
  */
-func Listen(out chan string, conf config.Config) {
+func Listen(out chan tracker.Request, conf config.Config) {
 
 	codeDir := conf.GetCodebase()
 	port := conf.GetHTTPPort()
@@ -77,19 +86,33 @@ func Listen(out chan string, conf config.Config) {
 		log.Fatal(err)
 	}
 
-	arrival := make(chan client)
+	arrival := make(chan clientArrival)
 	departure := make(chan client)
 	go manageClients(clientList, arrival, departure)
 
 	http.Handle("/", http.FileServer(uiResource))
-	// Serve the files that will be debugged.
-	http.Handle("/files/", http.StripPrefix("/files/", http.FileServer(http.Dir(codeDir))))
+	// Serve the files that will be debugged.  Source files in UTF-8.
+	http.Handle("/files/", http.StripPrefix("/files/", makeSourceFileHandler(http.Dir(codeDir), codebase.GetEncodings())))
 	// HTML markup for the same files.
-	http.HandleFunc("/formatted-file/", makeFormattedFileHandler(http.Dir(codeDir)))
+	http.HandleFunc("/formatted-file/", makeFormattedFileHandler(http.Dir(codeDir), codebase.GetEncodings()))
+	// HTML markup for the DBGp engine's copy of the same files.
+	http.HandleFunc("/engine-file/", makeEngineFileHandler(conf.DetermineCodeDir(), codebase.GetEncodings()))
+	// Classes, functions, etc. of the same files as JSON.
+	http.HandleFunc("/outline/", makeOutlineHandler(http.Dir(codeDir), codebase.GetEncodings()))
+	// JSON listing of the same files for the file browser.
+	http.HandleFunc("/api/tree", makeTreeHandler(codeDir, conf.GetExcludes()))
+	// Fuzzy search over the paths of the same files.
+	http.HandleFunc("/api/find", makeFindHandler(codebase.GetIndex))
+	// Full-text search through the same files.
+	http.HandleFunc("/api/search", makeSearchHandler(codeDir, conf.GetExcludes()))
+	// PHP declarations in the same files.
+	http.HandleFunc("/api/symbols", makeSymbolsHandler(codebase.GetSymbolIndex))
 
 	http.HandleFunc("/steering-wheel", makeReceiveHandler(out))
 	http.HandleFunc("/message-stream", makeTransmitHandler(arrival, departure))
+	http.HandleFunc("/websocket", makeWebSocketHandler(out, arrival, departure, conf.DetermineCodeDir()))
 	http.HandleFunc("/current-state", makeCurrentStateHandler(codeDir))
+	http.HandleFunc(API_PREFIX, makeAPIHandler(out, conf.DetermineCodeDir()))
 
 	address := fmt.Sprintf(":%d", port)
 	http.ListenAndServe(address, nil)
@@ -105,10 +128,7 @@ func TellBrowsers(in <-chan message.Message, config config.Config) {
 	codeDir := config.DetermineCodeDir()
 
 	for msg := range in {
-		adjustedMsg := adjustFilepath(msg, codeDir)
-		adjustedMsg.Context.Local = escapeVarValue(msg.Context.Local)
-
-		jsonMsg, err := json.Marshal(adjustedMsg)
+		jsonMsg, err := prepareMsgForBrowsers(msg, codeDir)
 
 		if nil == err {
 			broadcast(string(jsonMsg), clientList)
@@ -116,6 +136,19 @@ func TellBrowsers(in <-chan message.Message, config config.Config) {
 	}
 }
 
+/**
+ * Turn a message into JSON that suits browsers.
+ *
+ * Filepaths are made relative and variable values HTML escaped.
+ */
+func prepareMsgForBrowsers(msg message.Message, codeDir string) (jsonMsg []byte, err error) {
+
+	adjustedMsg := adjustFilepath(msg, codeDir)
+	adjustedMsg.Context.Local = escapeVarValue(msg.Context.Local)
+
+	return json.Marshal(adjustedMsg)
+}
+
 /**
  * Wrapper for the "receive" handler.
  *
@@ -123,7 +156,7 @@ func TellBrowsers(in <-chan message.Message, config config.Config) {
  * to receiver().  This channel can be used to write whatever is received
  * by receive().
  */
-func makeReceiveHandler(out chan string) http.HandlerFunc {
+func makeReceiveHandler(out chan tracker.Request) http.HandlerFunc {
 
 	return func(writeStream http.ResponseWriter, request *http.Request) {
 
@@ -137,32 +170,66 @@ func makeReceiveHandler(out chan string) http.HandlerFunc {
  * Extracts whatever is sent by HTTP clients and tries to prepare a DBGp
  * command out of it.  This command is then written to the output channel so
  * that it can be sent to the DBGp engine.
+ *
+ * Optional form fields:
+ *   - client: ID of the HTTP client.  Responses are tagged with this ID.
+ *   - wait: When present, wait for the response and return it as JSON instead
+ *     of the usual "Got it."
  */
-func receive(writeStream http.ResponseWriter, request *http.Request, debugger chan string) {
+func receive(writeStream http.ResponseWriter, request *http.Request, debugger chan tracker.Request) {
 
 	cmd := request.FormValue("cmd")
 
-	cmdAlias, cmdArgs, err := command.Break(cmd)
-	if nil != err {
+	if err := validateCmd(cmd); err != nil {
 		fmt.Fprintf(writeStream, "%s", err)
 
 		return
 	}
 
+	origin := Origin
+	if clientId := request.FormValue("client"); clientId != "" {
+		origin = Origin + ":" + clientId
+	}
+
+	if request.FormValue("wait") == "" {
+		fmt.Fprintf(writeStream, "Got it.")
+
+		debugger <- tracker.New(cmd, origin)
+		return
+	}
+
+	syncRequest := tracker.NewSync(cmd, origin)
+	debugger <- syncRequest
+
+	select {
+	case reply := <-syncRequest.Reply:
+		writeStream.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(writeStream).Encode(reply)
+	case <-request.Context().Done():
+		// The HTTP client has given up.
+	}
+}
+
+/**
+ * Is the given command fit for Footle or the DBGp engine?
+ */
+func validateCmd(cmd string) (err error) {
+
+	cmdAlias, cmdArgs, err := command.Break(cmd)
+	if nil != err {
+		return err
+	}
+
 	isFootleCmd := footlecmd.Is(cmdAlias)
 
 	err = command.Validate(cmdAlias, cmdArgs)
 	isInvalidDBGpCmd := !isFootleCmd && err != nil
 
 	if isInvalidDBGpCmd {
-		fmt.Fprintf(writeStream, "%s", err)
-
-		return
+		return err
 	}
 
-	fmt.Fprintf(writeStream, "Got it.")
-
-	debugger <- cmd
+	return nil
 }
 
 /**
@@ -171,7 +238,7 @@ func receive(writeStream http.ResponseWriter, request *http.Request, debugger ch
  * In addition to the usual arguments for an HTTP handler, it passes two
  * channels to transmit().
  */
-func makeTransmitHandler(arrival, departure chan client) http.HandlerFunc {
+func makeTransmitHandler(arrival chan clientArrival, departure chan client) http.HandlerFunc {
 
 	return func(writeStream http.ResponseWriter, request *http.Request) {
 
@@ -188,11 +255,16 @@ func makeTransmitHandler(arrival, departure chan client) http.HandlerFunc {
  * For each client, a new channel is created.  This channel is then passed to
  * the other parts of Footle that writes the output of DBGp commands to this
  * channel.
+ *
+ * Each event carries an ID.  When the browser reconnects, it sends back the
+ * last ID it has seen in the Last-Event-ID header and receives whatever it has
+ * missed in the meantime.  Heartbeat comments keep idle streams alive through
+ * proxies.
  */
-func transmit(writeStream http.ResponseWriter, request *http.Request, arrival, departure chan client) {
+func transmit(writeStream http.ResponseWriter, request *http.Request, arrival chan clientArrival, departure chan client) {
 
-	myEar := make(chan string)
-	arrival <- myEar
+	myEar := newClientQueue()
+	arrival <- clientArrival{queue: myEar, lastEventId: extractLastEventId(request)}
 
 	flusher, ok := writeStream.(http.Flusher)
 
@@ -212,19 +284,70 @@ func transmit(writeStream http.ResponseWriter, request *http.Request, arrival, d
 	closedConnectionNotification := writeStream.(http.CloseNotifier).CloseNotify()
 
 	// When this HTTP client departs, remove it from the active client list.
+	// The client list may have already removed a slow client in which case we
+	// leave without waiting for the connection to close.
 	// @see manageClients()
+	finished := make(chan struct{})
+	defer close(finished)
+
 	go func() {
-		<-closedConnectionNotification
+		select {
+		case <-closedConnectionNotification:
+		case <-finished:
+		}
+
 		departure <- myEar
 	}()
 
-	for msg := range myEar {
-		fmt.Fprintf(writeStream, "data: %s\n\n", msg)
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event, isOpen := <-myEar:
+			if !isOpen {
+				// Only relevant when myEar has closed before writeStream.
+				fmt.Fprintf(writeStream, "event: close\ndata: The end\n\n")
+				return
+			}
+
+			writeEvent(writeStream, event)
+		case <-heartbeat.C:
+			fmt.Fprintf(writeStream, ": heartbeat\n\n")
+		}
+
 		flusher.Flush()
 	}
+}
+
+/**
+ * Write a Server sent event.
+ *
+ * Events without an ID, such as "resync", leave the browser's last event ID
+ * untouched.
+ */
+func writeEvent(writeStream io.Writer, event streamEvent) {
+
+	if event.Id > 0 {
+		fmt.Fprintf(writeStream, "id: %d\n", event.Id)
+	}
 
-	// Only relevant when myEar has closed before writeStream.
-	fmt.Fprintf(writeStream, "event: close\ndata: The end\n\n")
+	fmt.Fprintf(writeStream, "event: %s\ndata: %s\n\n", event.Name, event.Data)
+}
+
+/**
+ * ID of the last Server sent event seen by a reconnecting browser.
+ *
+ * Zero for a new browser or a garbled ID.
+ */
+func extractLastEventId(request *http.Request) (lastEventId int64) {
+
+	lastEventId, err := strconv.ParseInt(request.Header.Get("Last-Event-ID"), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return lastEventId
 }
 
 /**
@@ -238,30 +361,95 @@ func transmit(writeStream http.ResponseWriter, request *http.Request, arrival, d
  *   <pre class="line line__1">$autoloader = require_once &#39;autoload.php&#39;;</pre>
  *   ...
  * </div>
+ *
+ * A range of lines can be asked for, e.g. /formatted-file/foo.php?from=100&to=199
+ * Both ends are optional.  The X-Total-Lines header carries the number of
+ * lines in the whole file so that large files can be displayed bit by bit.
+ *
+ * Files that are not in UTF-8 are transcoded to UTF-8.
  */
-func makeFormattedFileHandler(codebase http.Dir) http.HandlerFunc {
+func makeFormattedFileHandler(codebase http.Dir, encodings *codebase.Encodings) http.HandlerFunc {
 
 	return func(writeStream http.ResponseWriter, request *http.Request) {
 
 		filePath := request.URL.Path[len("/formatted-file/"):]
 
-		output, err := file.GrabIt(codebase, filePath)
+		from, err := parseLineNo(request.URL.Query().Get("from"))
+		if err != nil {
+			http.Error(writeStream, "Invalid from line.", http.StatusBadRequest)
+			return
+		}
+
+		to, err := parseLineNo(request.URL.Query().Get("to"))
+		if err != nil {
+			http.Error(writeStream, "Invalid to line.", http.StatusBadRequest)
+			return
+		}
+
+		output, lineCount, err := file.GrabLines(codebase, filePath, from, to, encodings)
 
 		if nil != err {
 			http.Error(writeStream, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
+		// The UIs want to hear when this file changes.
+		watcher.NoteDisplayed(filePath)
+
 		writeStream.Header().Set("Content-Type", "text/html")
+		writeStream.Header().Set("X-Total-Lines", strconv.Itoa(lineCount))
 		io.WriteString(writeStream, output)
 	}
 }
 
 /**
- * Prepare handler for listing break and breakpoints.
+ * Parse an optional line number from a query parameter.
+ *
+ * Zero when missing.
+ */
+func parseLineNo(lineNoParam string) (lineNo int, err error) {
+
+	if lineNoParam == "" {
+		return 0, nil
+	}
+
+	if lineNo, err = strconv.Atoi(lineNoParam); err == nil && lineNo < 0 {
+		err = fmt.Errorf("Negative line number: %d", lineNo)
+	}
+
+	return lineNo, err
+}
+
+/**
+ * Prepare handler for the outline of a file.
+ *
+ * Example: GET /outline/src/User.php
+ */
+func makeOutlineHandler(codebase http.Dir, encodings *codebase.Encodings) http.HandlerFunc {
+
+	return func(writeStream http.ResponseWriter, request *http.Request) {
+
+		filePath := request.URL.Path[len("/outline/"):]
+
+		outline, err := file.Outline(codebase, filePath, encodings)
+		if os.IsNotExist(err) {
+			writeJSONError(writeStream, http.StatusNotFound, "No such file.")
+			return
+		} else if err != nil {
+			writeJSONError(writeStream, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		writeJSON(writeStream, http.StatusOK, outline)
+	}
+}
+
+/**
+ * Prepare handler for listing break, breakpoints, and details of the break.
  *
  * These are useful during UI initialization.  The last state is used by UI
- * clients to display breaks.
+ * clients to display breaks.  The stack trace and variables let a client
+ * joining during a break display them straight away.
  *
  * The breakpoint list saves existing UI clients from the burden of reprocessing
  * the breakpoints every time a new HTTP UI client is added.  Without this
@@ -275,6 +463,7 @@ func makeCurrentStateHandler(codeDir string) http.HandlerFunc {
 		stateMessages := currentstate.Get()
 		for i, msg := range stateMessages {
 			stateMessages[i] = adjustFilepath(msg, codeDir)
+			stateMessages[i].Context.Local = escapeVarValue(msg.Context.Local)
 		}
 
 		if jsonMsg, err := json.Marshal(stateMessages); err == nil {
@@ -288,36 +477,6 @@ func makeCurrentStateHandler(codeDir string) http.HandlerFunc {
 	}
 }
 
-/**
- * Writes a string message to all current client channels.
- */
-func broadcast(msg string, httpClientList map[client]bool) {
-
-	for clientChannel, _ := range httpClientList {
-		clientChannel <- msg
-	}
-}
-
-/**
- * Update the list of HTTP clients currently listening.
- *
- * When an HTTP client first starts listening for Server sent events, we
- * add it as a new client and vice-versa.
- */
-func manageClients(httpClientList map[client]bool, arrival, departure <-chan client) {
-
-	for {
-		select {
-		case clientChannel := <-arrival:
-			httpClientList[clientChannel] = true
-
-		case clientChannel := <-departure:
-			delete(httpClientList, clientChannel)
-			close(clientChannel)
-		}
-	}
-}
-
 /**
  * Find the HTML UI.
  *
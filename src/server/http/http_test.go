@@ -7,9 +7,14 @@
 package http
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http/httptest"
 	"net/url"
+	"server/core/tracker"
+	"server/dbgp/message"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -22,16 +27,38 @@ import (
  * http.CloseNotifier.  This response recorder is useful where the HTTP handler
  * needs to know as soon as the HTTP connection closes.  This is particularly
  * useful for long running HTTP connections as is served by transmit().
+ *
+ * The handler writes from its own goroutine.  So the output is guarded.
  */
 type mockResponseRecorder struct {
 	*httptest.ResponseRecorder
 	closeNotify chan bool
+	lock        *sync.Mutex
 }
 
 func (writer mockResponseRecorder) CloseNotify() <-chan bool { return writer.closeNotify }
 func (writer mockResponseRecorder) Close()                   { writer.closeNotify <- true }
 func (writer mockResponseRecorder) Flush()                   {}
 
+func (writer mockResponseRecorder) Write(data []byte) (int, error) {
+
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	return writer.ResponseRecorder.Write(data)
+}
+
+/**
+ * Everything written so far.
+ */
+func (writer mockResponseRecorder) Output() string {
+
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	return writer.Body.String()
+}
+
 /**
  * Tests for receive().
  *
@@ -46,7 +73,7 @@ func TestReceive(t *testing.T) {
 	request := httptest.NewRequest("POST", "/steering-wheel", formReader)
 	request.Header.Add("Content-Type", "application/x-www-form-urlencoded; param=value")
 	writer := httptest.NewRecorder()
-	commands := make(chan string)
+	commands := make(chan tracker.Request)
 
 	receive(writer, request, commands)
 
@@ -63,10 +90,10 @@ func TestReceive(t *testing.T) {
 	request = httptest.NewRequest("POST", "/steering-wheel", formReader)
 	request.Header.Add("Content-Type", "application/x-www-form-urlencoded; param=value")
 	writer = httptest.NewRecorder()
-	commands = make(chan string)
+	commands = make(chan tracker.Request)
 
 	go receive(writer, request, commands)
-	DBGpCmd := <-commands
+	DBGpCmd := (<-commands).Cmd
 
 	expectedResponse = "Got it."
 	response = writer.Body.String()
@@ -79,6 +106,35 @@ func TestReceive(t *testing.T) {
 	if expectedCmd != DBGpCmd {
 		t.Errorf("receive(status) commanded: %s", DBGpCmd)
 	}
+
+	// Pass case that waits for the response to the "status" command.
+	formValues = url.Values{"cmd": {"status"}, "client": {"foo"}, "wait": {"1"}}
+	formReader = strings.NewReader(formValues.Encode())
+	request = httptest.NewRequest("POST", "/steering-wheel", formReader)
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded; param=value")
+	writer = httptest.NewRecorder()
+	commands = make(chan tracker.Request)
+
+	done := make(chan struct{})
+	go func() {
+		receive(writer, request, commands)
+		close(done)
+	}()
+
+	syncRequest := <-commands
+	if syncRequest.Origin != "http:foo" || !syncRequest.IsSync() {
+		t.Errorf("receive(status) issued unexpected request: %v", syncRequest)
+	}
+
+	statusMsg := message.Message{MessageType: "response", State: "break"}
+	statusMsg.Properties.Command = "status"
+	syncRequest.Answer(statusMsg)
+	<-done
+
+	var reply message.Message
+	if err := json.Unmarshal(writer.Body.Bytes(), &reply); err != nil || reply.State != "break" || reply.Origin != "http:foo" {
+		t.Errorf("receive(status) replied: %s", writer.Body.String())
+	}
 }
 
 /**
@@ -92,20 +148,21 @@ func TestTransmit(t *testing.T) {
 	writer := mockResponseRecorder{
 		ResponseRecorder: httptest.NewRecorder(),
 		closeNotify:      make(chan bool),
+		lock:             &sync.Mutex{},
 	}
 
 	// HTTP client has arrived.
-	arrival := make(chan client)
+	arrival := make(chan clientArrival)
 	departure := make(chan client)
 	go transmit(writer, request, arrival, departure)
 
-	ear0 := <-arrival
-	ear0 <- "Foo bar."
+	ear0 := (<-arrival).queue
+	ear0 <- streamEvent{Id: 7, Name: dbgpEventName, Data: "Foo bar."}
 
 	time.Sleep(time.Millisecond)
-	streamedOutput := writer.Body.String()
+	streamedOutput := writer.Output()
 
-	expected := "data: Foo bar.\n\n"
+	expected := "id: 7\nevent: dbgp\ndata: Foo bar.\n\n"
 	if expected != streamedOutput {
 		t.Errorf("transmit() said: %s", streamedOutput)
 	}
@@ -123,9 +180,9 @@ func TestTransmit(t *testing.T) {
 	// ...and a closing message has been sent.
 	close(ear0)
 	time.Sleep(time.Millisecond)
-	streamedOutput = writer.Body.String()
+	streamedOutput = writer.Output()
 
-	expected = "data: Foo bar.\n\nevent: close\ndata: The end\n\n"
+	expected = "id: 7\nevent: dbgp\ndata: Foo bar.\n\nevent: close\ndata: The end\n\n"
 	if expected != streamedOutput {
 		t.Errorf("At the end, transmit() said: %s", streamedOutput)
 	}
@@ -136,33 +193,163 @@ func TestTransmit(t *testing.T) {
  */
 func TestBroadcast(t *testing.T) {
 
-	httpClientList := make(map[client]bool)
-
-	ear0 := make(chan string)
-	ear1 := make(chan string)
-	ear2 := make(chan string)
+	httpClientList := newClientRegistry()
 
-	httpClientList[ear0] = true
-	httpClientList[ear1] = true
-	httpClientList[ear2] = true
+	ear0 := newClientQueue()
+	ear1 := newClientQueue()
+	ear2 := newClientQueue()
 
-	go broadcast("Foo", httpClientList)
+	httpClientList.add(clientArrival{queue: ear0})
+	httpClientList.add(clientArrival{queue: ear1})
+	httpClientList.add(clientArrival{queue: ear2})
 
-	var msg0, msg1, msg2 string
+	broadcast("Foo", httpClientList)
 
 	// Record what we have just heard.
-	for i := 0; i < 3; i++ {
-		select {
-		case msg0 = <-ear0:
+	msg0, msg1, msg2 := <-ear0, <-ear1, <-ear2
+
+	if "Foo" != msg0.Data || "Foo" != msg1.Data || "Foo" != msg2.Data {
+		t.Errorf("Wrong broadcast: %v, %v, %v", msg0, msg1, msg2)
+	}
+
+	if 1 != msg0.Id || msg0 != msg1 || msg0 != msg2 {
+		t.Errorf("Broadcast carries wrong event ID: %v, %v, %v", msg0, msg1, msg2)
+	}
+}
+
+/**
+ * Tests for replaying missed messages to a reconnecting client.
+ */
+func TestReplay(t *testing.T) {
+
+	httpClientList := newClientRegistry()
+
+	for i := 1; i <= 3; i++ {
+		broadcast(fmt.Sprintf("Foo %d", i), httpClientList)
+	}
+
+	// Reconnecting after the first message.
+	ear := newClientQueue()
+	httpClientList.add(clientArrival{queue: ear, lastEventId: 1})
+
+	if missed := <-ear; 2 != missed.Id || "Foo 2" != missed.Data {
+		t.Errorf("Wrong replay: %v", missed)
+	}
+	if missed := <-ear; 3 != missed.Id || "Foo 3" != missed.Data {
+		t.Errorf("Wrong replay: %v", missed)
+	}
+	if 0 != len(ear) {
+		t.Error("Replayed more than the missed messages.")
+	}
+
+	// New clients hear nothing from the past.
+	newEar := newClientQueue()
+	httpClientList.add(clientArrival{queue: newEar})
+
+	if 0 != len(newEar) {
+		t.Error("Replayed to a new client.")
+	}
 
-		case msg1 = <-ear1:
+	// Missed more than we remember.
+	for i := 0; i < historySize; i++ {
+		broadcast("Bar", httpClientList)
+	}
+
+	lateEar := newClientQueue()
+	httpClientList.add(clientArrival{queue: lateEar, lastEventId: 1})
+
+	if resync := <-lateEar; resyncEventName != resync.Name {
+		t.Errorf("Expected resync event, got: %v", resync)
+	}
+	if historySize != len(lateEar) {
+		t.Errorf("Expected %d replayed messages, got %d.", historySize, len(lateEar))
+	}
+
+	// Footle has restarted since the client's last message.
+	restartedList := newClientRegistry()
+	broadcast("Baz", restartedList)
+
+	staleEar := newClientQueue()
+	restartedList.add(clientArrival{queue: staleEar, lastEventId: 5})
+
+	if resync := <-staleEar; resyncEventName != resync.Name {
+		t.Errorf("Expected resync event after restart, got: %v", resync)
+	}
+	if 0 != len(staleEar) {
+		t.Error("Replayed messages from before the restart.")
+	}
+}
+
+/**
+ * Tests for dealing with a client that has stopped listening.
+ *
+ * Broadcasts should carry on regardless.  The slow client first misses
+ * messages and is then disconnected.
+ */
+func TestBroadcastToSlowClient(t *testing.T) {
+
+	httpClientList := newClientRegistry()
+
+	slowEar := newClientQueue()
+	httpClientList.add(clientArrival{queue: slowEar})
+
+	for i := 0; i < clientQueueSize+maxDroppedMsgs-1; i++ {
+		broadcast("Foo", httpClientList)
+	}
+
+	if 1 != httpClientList.count() {
+		t.Error("Slow client has been disconnected too early.")
+	}
 
-		case msg2 = <-ear2:
-		}
+	broadcast("Foo", httpClientList)
+
+	if 0 != httpClientList.count() {
+		t.Error("Slow client has not been disconnected.")
+	}
+
+	for i := 0; i < clientQueueSize; i++ {
+		<-slowEar
 	}
 
-	if "Foo" != msg0 || "Foo" != msg1 || "Foo" != msg2 {
-		t.Errorf("Wrong broadcast: %s, %s, %s", msg0, msg1, msg2)
+	if _, isOpen := <-slowEar; isOpen {
+		t.Error("Queue of the slow client is still open.")
+	}
+
+	// Departure of an already disconnected client is harmless.
+	httpClientList.remove(slowEar)
+}
+
+/**
+ * A client that has missed messages is asked to resync once it catches up.
+ */
+func TestResyncAfterMissedMsg(t *testing.T) {
+
+	httpClientList := newClientRegistry()
+
+	ear := newClientQueue()
+	httpClientList.add(clientArrival{queue: ear})
+
+	for i := 0; i <= clientQueueSize; i++ {
+		broadcast(fmt.Sprintf("Foo %d", i), httpClientList)
+	}
+
+	for i := 0; i < clientQueueSize; i++ {
+		<-ear
+	}
+
+	broadcast("Bar", httpClientList)
+
+	if resync := <-ear; resyncEventName != resync.Name {
+		t.Errorf("Expected resync event, got: %v", resync)
+	}
+	if msg := <-ear; "Bar" != msg.Data {
+		t.Errorf("Expected the latest message after resync, got: %v", msg)
+	}
+
+	broadcast("Baz", httpClientList)
+
+	if msg := <-ear; "Baz" != msg.Data {
+		t.Errorf("Expected no further resync, got: %v", msg)
 	}
 }
 
@@ -173,41 +360,41 @@ func TestBroadcast(t *testing.T) {
  */
 func TestManageClients(t *testing.T) {
 
-	httpClientList := make(map[client]bool)
-	arrival := make(chan client)
+	httpClientList := newClientRegistry()
+	arrival := make(chan clientArrival)
 	departure := make(chan client)
 
 	go manageClients(httpClientList, arrival, departure)
 
-	ear0 := make(chan string)
-	ear1 := make(chan string)
-	ear2 := make(chan string)
+	ear0 := newClientQueue()
+	ear1 := newClientQueue()
+	ear2 := newClientQueue()
 
-	arrival <- ear0
+	arrival <- clientArrival{queue: ear0}
 	// Sleep() is needed to give the manageClients() goroutine a chance update
 	// the client list.
 	time.Sleep(time.Millisecond)
-	if 1 != len(httpClientList) {
+	if 1 != httpClientList.count() {
 		t.Error("manageClients() failed to record arrival.")
 	}
 
 	departure <- ear0
 	time.Sleep(time.Millisecond)
-	if 0 != len(httpClientList) {
+	if 0 != httpClientList.count() {
 		t.Error("manageClients() failed to record departure.")
 	}
 
-	arrival <- ear1
-	arrival <- ear2
+	arrival <- clientArrival{queue: ear1}
+	arrival <- clientArrival{queue: ear2}
 	time.Sleep(time.Millisecond)
-	if 2 != len(httpClientList) {
+	if 2 != httpClientList.count() {
 		t.Error("manageClients() failed to record two arrivals.")
 	}
 
 	departure <- ear1
 	departure <- ear2
 	time.Sleep(time.Millisecond)
-	if 0 != len(httpClientList) {
+	if 0 != httpClientList.count() {
 		t.Error("manageClients() failed to record two departures.")
 	}
 }
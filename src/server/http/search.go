@@ -0,0 +1,105 @@
+/**
+ * @file
+ * Full-text search over the codebase.
+ *
+ * Results are streamed as Server sent events while the search progresses:
+ *   - A "match" event for each matching line.  Its data is a JSON object with
+ *     path, line, snippet, start, and end keys.
+ *   - A final "done" event with the number of files searched, the number of
+ *     matches, and whether the result limit cut the search short.
+ *
+ * The search stops when the client goes away.
+ *
+ * Query parameters:
+ *   - q: Search text.
+ *   - regex: Treat q as a regular expression when set to 1.
+ *   - case: Match case when set to 1.
+ *   - limit: Maximum number of matches.
+ *
+ * Examples:
+ *   - GET /api/search?q=function+foo
+ *   - GET /api/search?q=^class\s+User&regex=1&case=1
+ *
+ * @see http/search/search.go
+ */
+
+package http
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"server/codebase"
+	"server/http/search"
+)
+
+const defaultSearchLimit = 500
+const maxSearchLimit = 5000
+
+/**
+ * Wrapper for the "search" handler.
+ */
+func makeSearchHandler(codeDir string, excludes []string) http.HandlerFunc {
+
+	return func(writeStream http.ResponseWriter, request *http.Request) {
+
+		if request.Method != http.MethodGet {
+			writeJSONError(writeStream, http.StatusMethodNotAllowed, "Only GET is supported.")
+			return
+		}
+
+		query := request.URL.Query()
+
+		pattern, err := search.Compile(query.Get("q"), query.Get("regex") == "1", query.Get("case") == "1")
+		if err != nil {
+			writeJSONError(writeStream, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		limit, err := parseLimit(query.Get("limit"), defaultSearchLimit, maxSearchLimit)
+		if err != nil {
+			writeJSONError(writeStream, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		flusher, ok := writeStream.(http.Flusher)
+		if !ok {
+			writeJSONError(writeStream, http.StatusInternalServerError, "Unable to Flush.")
+			return
+		}
+
+		writeStream.Header().Set("Content-Type", "text/event-stream")
+		writeStream.Header().Set("Cache-control", "no-cache")
+		flusher.Flush()
+
+		filter := codebase.NewFilter(codeDir, excludes)
+		summary, err := search.Run(codeDir, filter, pattern, limit, request.Context().Done(), func(result search.Result) {
+
+			writeJSONEvent(writeStream, "match", result)
+			flusher.Flush()
+		})
+
+		if err == search.ErrCancelled {
+			return
+		} else if err != nil {
+			log.Println(err)
+		}
+
+		writeJSONEvent(writeStream, "done", summary)
+		flusher.Flush()
+	}
+}
+
+/**
+ * Write a Server sent event carrying JSON data.
+ */
+func writeJSONEvent(writeStream http.ResponseWriter, eventName string, value interface{}) {
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	writeEvent(writeStream, streamEvent{Name: eventName, Data: string(data)})
+}
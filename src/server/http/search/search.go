@@ -0,0 +1,212 @@
+/**
+ * @file
+ * Full-text search over the codebase.
+ *
+ * Each file is searched line by line.  Only the first match in a line is
+ * reported.  Files ignored through .gitignore or the exclude list are skipped.
+ * So are binary files and very large files.
+ */
+
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"server/codebase"
+	"strings"
+	"unicode/utf8"
+)
+
+/**
+ * Files larger than this are skipped.  These are rarely hand written.
+ */
+const maxFileSize = 4 * 1024 * 1024
+
+/**
+ * Lines longer than this end the search of a file.  Think minified code.
+ */
+const maxLineLength = 64 * 1024
+
+/**
+ * Length of snippets in characters.  Longer lines are cut around the match.
+ */
+const maxSnippetLength = 160
+
+/**
+ * A file with a NUL byte among its first few bytes is treated as binary.
+ */
+const binarySniffLength = 8000
+
+/**
+ * A matching line.
+ *
+ * Start and End are character offsets of the match within the snippet.
+ */
+type Result struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Snippet string `json:"snippet"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+}
+
+/**
+ * Statistics for a finished search.
+ */
+type Summary struct {
+	FileCount   int  `json:"files"`
+	MatchCount  int  `json:"matches"`
+	IsTruncated bool `json:"truncated"`
+}
+
+var errLimitReached = errors.New("Search limit reached.")
+var ErrCancelled = errors.New("Search cancelled.")
+
+/**
+ * Turn a search query into a regular expression.
+ *
+ * Queries are literal text unless isRegexp is set.  Case is ignored unless
+ * isCaseSensitive is set.
+ */
+func Compile(query string, isRegexp, isCaseSensitive bool) (pattern *regexp.Regexp, err error) {
+
+	if query == "" {
+		return nil, errors.New("Empty search query.")
+	}
+
+	if !isRegexp {
+		query = regexp.QuoteMeta(query)
+	}
+
+	if !isCaseSensitive {
+		query = "(?i)" + query
+	}
+
+	return regexp.Compile(query)
+}
+
+/**
+ * Search all files in the codebase.
+ *
+ * Each matching line is passed to the "found" callback as soon as it is
+ * found.  The search stops after "limit" matches or when the stop channel is
+ * closed.  ErrCancelled is returned in the latter case.
+ */
+func Run(root string, filter *codebase.Filter, pattern *regexp.Regexp, limit int, stop <-chan struct{}, found func(Result)) (summary Summary, err error) {
+
+	err = codebase.Walk(root, filter, func(relativePath string, fileInfo os.FileInfo) error {
+
+		select {
+		case <-stop:
+			return ErrCancelled
+		default:
+		}
+
+		if fileInfo.Size() > maxFileSize {
+			return nil
+		}
+
+		file, err := os.Open(filepath.Join(root, filepath.FromSlash(relativePath)))
+		if err != nil {
+			return nil
+		}
+		defer file.Close()
+
+		summary.FileCount++
+
+		return searchFile(file, pattern, func(lineNo int, line string, match []int) error {
+
+			if summary.MatchCount >= limit {
+				summary.IsTruncated = true
+				return errLimitReached
+			}
+
+			summary.MatchCount++
+
+			result := Result{Path: relativePath, Line: lineNo}
+			result.Snippet, result.Start, result.End = prepareSnippet(line, match[0], match[1])
+			found(result)
+
+			return nil
+		})
+	})
+
+	if err == errLimitReached {
+		err = nil
+	}
+
+	return summary, err
+}
+
+/**
+ * Find matching lines in a file.
+ *
+ * Binary files never match.  The match is passed as a pair of byte offsets.
+ * An error from the callback stops the search and is returned.
+ */
+func searchFile(in io.Reader, pattern *regexp.Regexp, found func(lineNo int, line string, match []int) error) (err error) {
+
+	reader := bufio.NewReaderSize(in, binarySniffLength)
+	if head, _ := reader.Peek(binarySniffLength); bytes.IndexByte(head, 0) >= 0 {
+		return nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineLength)
+
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+
+		if match := pattern.FindStringIndex(line); match != nil {
+			if err = found(lineNo, line, match); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+/**
+ * Cut a line down to a snippet around the match.
+ *
+ * Leading whitespace is dropped.  The match's byte offsets are turned into
+ * character offsets within the snippet.
+ */
+func prepareSnippet(line string, matchStart, matchEnd int) (snippet string, start, end int) {
+
+	indentLength := len(line) - len(strings.TrimLeft(line, " \t"))
+	if indentLength > matchStart {
+		indentLength = matchStart
+	}
+
+	runes := []rune(line[indentLength:])
+	start = utf8.RuneCountInString(line[indentLength:matchStart])
+	end = start + utf8.RuneCountInString(line[matchStart:matchEnd])
+
+	if len(runes) <= maxSnippetLength {
+		return string(runes), start, end
+	}
+
+	from := start - maxSnippetLength/4
+	if from < 0 {
+		from = 0
+	}
+
+	to := from + maxSnippetLength
+	if to > len(runes) {
+		to = len(runes)
+		from = to - maxSnippetLength
+	}
+
+	if end > to {
+		end = to
+	}
+
+	return string(runes[from:to]), start - from, end - from
+}
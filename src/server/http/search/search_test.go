@@ -0,0 +1,93 @@
+package search
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"server/codebase"
+	"strings"
+	"testing"
+)
+
+/**
+ * Tests for Compile().
+ */
+func TestCompile(t *testing.T) {
+
+	if pattern, err := Compile("a.b", false, false); err != nil || !pattern.MatchString("xA.B") || pattern.MatchString("axb") {
+		t.Errorf("Expected a case insensitive literal search: %v", err)
+	}
+
+	if pattern, err := Compile(`^func\s+foo`, true, true); err != nil || !pattern.MatchString("func  foo()") || pattern.MatchString("FUNC foo") {
+		t.Errorf("Expected a case sensitive regular expression: %v", err)
+	}
+
+	if _, err := Compile("(", true, false); err == nil {
+		t.Error("Expected an error for a broken regular expression.")
+	}
+
+	if _, err := Compile("", false, false); err == nil {
+		t.Error("Expected an error for an empty query.")
+	}
+}
+
+/**
+ * Tests for Run().
+ */
+func TestRun(t *testing.T) {
+
+	root, err := ioutil.TempDir("", "footle-search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	os.Mkdir(filepath.Join(root, "vendor"), 0755)
+	ioutil.WriteFile(filepath.Join(root, "index.php"), []byte("<?php\n\nfoo();\nbar();\nfoo(); foo();\n"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "image.png"), []byte("foo\x00"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "vendor", "lib.php"), []byte("<?php foo();"), 0644)
+
+	filter := codebase.NewFilter(root, []string{"vendor/"})
+	pattern, _ := Compile("FOO", false, false)
+
+	results := []Result{}
+	summary, err := Run(root, filter, pattern, 10, nil, func(result Result) {
+		results = append(results, result)
+	})
+
+	if err != nil || summary.FileCount != 2 || summary.MatchCount != 2 || summary.IsTruncated {
+		t.Errorf("Unexpected summary: %v, %v", summary, err)
+	}
+
+	if len(results) != 2 || results[0].Path != "index.php" || results[0].Line != 3 || results[1].Line != 5 {
+		t.Errorf("Unexpected results: %v", results)
+	}
+
+	summary, _ = Run(root, filter, pattern, 1, nil, func(result Result) {})
+	if summary.MatchCount != 1 || !summary.IsTruncated {
+		t.Errorf("Expected a truncated search: %v", summary)
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	if _, err = Run(root, filter, pattern, 10, stop, func(result Result) {}); err != ErrCancelled {
+		t.Errorf("Expected cancellation, got: %v", err)
+	}
+}
+
+/**
+ * Tests for prepareSnippet().
+ */
+func TestPrepareSnippet(t *testing.T) {
+
+	snippet, start, end := prepareSnippet("\t\t$café = foo();", 11, 14)
+	if snippet != "$café = foo();" || start != 8 || end != 11 {
+		t.Errorf("Unexpected snippet: %q, %d, %d", snippet, start, end)
+	}
+
+	line := strings.Repeat("a", 500) + "foo" + strings.Repeat("b", 500)
+	snippet, start, end = prepareSnippet(line, 500, 503)
+	if len(snippet) != maxSnippetLength || snippet[start:end] != "foo" {
+		t.Errorf("Unexpected long snippet: %q, %d, %d", snippet, start, end)
+	}
+}
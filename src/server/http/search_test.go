@@ -0,0 +1,43 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/**
+ * Tests for the search handler.
+ */
+func TestSearchHandler(t *testing.T) {
+
+	codeDir, err := ioutil.TempDir("", "footle-search")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(codeDir)
+
+	ioutil.WriteFile(filepath.Join(codeDir, "index.php"), []byte("<?php\nfunction foo() {}\n"), 0644)
+
+	handler := makeSearchHandler(codeDir, nil)
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("GET", "/api/search?q=function+foo", nil))
+
+	expected := "event: match\ndata: {\"path\":\"index.php\",\"line\":2,\"snippet\":\"function foo() {}\",\"start\":0,\"end\":12}\n\n" +
+		"event: done\ndata: {\"files\":1,\"matches\":1,\"truncated\":false}\n\n"
+	if recorder.Body.String() != expected || recorder.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Unexpected stream: %q", recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("GET", "/api/search?q=(&regex=1", nil))
+
+	if recorder.Code != http.StatusBadRequest || !strings.Contains(recorder.Body.String(), "error") {
+		t.Errorf("Expected a bad request, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,76 @@
+/**
+ * @file
+ * Raw content of the files in the codebase.
+ *
+ * Like a plain file server except that source files which are not in UTF-8
+ * are transcoded to UTF-8.  Source files are PHP files and any file with a
+ * configured encoding.  Everything else, e.g. images, is served untouched.
+ *
+ * Example: GET /files/src/User.php
+ *
+ * @see codebase.Encodings
+ */
+
+package http
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"server/codebase"
+	"server/php"
+)
+
+/**
+ * Wrapper for the source file handler.
+ *
+ * Expects the request path to be relative to the codebase.  Use with
+ * http.StripPrefix().
+ */
+func makeSourceFileHandler(codebase http.Dir, encodings *codebase.Encodings) http.Handler {
+
+	fileServer := http.FileServer(codebase)
+
+	return http.HandlerFunc(func(writeStream http.ResponseWriter, request *http.Request) {
+
+		filePath := request.URL.Path
+		if !php.IsPHPFile(filePath) && !encodings.IsConfigured(filePath) {
+			fileServer.ServeHTTP(writeStream, request)
+			return
+		}
+
+		fileDesc, err := codebase.Open(filePath)
+		if err != nil {
+			// Let the file server report it.
+			fileServer.ServeHTTP(writeStream, request)
+			return
+		}
+		defer fileDesc.Close()
+
+		fileInfo, err := fileDesc.Stat()
+		if err != nil || fileInfo.IsDir() {
+			fileServer.ServeHTTP(writeStream, request)
+			return
+		}
+
+		src, err := ioutil.ReadAll(fileDesc)
+		if err != nil {
+			http.Error(writeStream, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if encodings.Detect(filePath, src) == "utf-8" {
+			fileServer.ServeHTTP(writeStream, request)
+			return
+		}
+
+		decoded, err := encodings.Decode(filePath, src)
+		if err != nil {
+			http.Error(writeStream, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeStream.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		http.ServeContent(writeStream, request, fileInfo.Name(), fileInfo.ModTime(), bytes.NewReader(decoded))
+	})
+}
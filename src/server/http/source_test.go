@@ -0,0 +1,62 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"server/codebase"
+	"testing"
+)
+
+/**
+ * Tests for the source file handler.
+ *
+ * Legacy source files should arrive in UTF-8.  Other files should not be
+ * touched.
+ */
+func TestSourceFileHandler(t *testing.T) {
+
+	codeDir, err := ioutil.TempDir("", "footle-source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(codeDir)
+
+	ioutil.WriteFile(filepath.Join(codeDir, "legacy.php"), []byte("<?php echo 'caf\xe9';"), 0644)
+	ioutil.WriteFile(filepath.Join(codeDir, "modern.php"), []byte("<?php echo 'café';"), 0644)
+	ioutil.WriteFile(filepath.Join(codeDir, "logo.bin"), []byte("\x89PNG\xe9"), 0644)
+
+	encodings, _ := codebase.NewEncodings(nil)
+	handler := http.StripPrefix("/files/", makeSourceFileHandler(http.Dir(codeDir), encodings))
+
+	cases := []struct {
+		path        string
+		body        string
+		contentType string
+	}{
+		{"/files/legacy.php", "<?php echo 'café';", "text/plain; charset=utf-8"},
+		{"/files/modern.php", "<?php echo 'café';", ""},
+		{"/files/logo.bin", "\x89PNG\xe9", ""},
+	}
+
+	for _, c := range cases {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", c.path, nil))
+
+		if recorder.Code != http.StatusOK || recorder.Body.String() != c.body {
+			t.Errorf("Unexpected response for %s: %d, %q", c.path, recorder.Code, recorder.Body.String())
+		}
+
+		if contentType := recorder.Header().Get("Content-Type"); c.contentType != "" && contentType != c.contentType {
+			t.Errorf("Unexpected content type for %s: %s", c.path, contentType)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/files/missing.php", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing file, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,56 @@
+/**
+ * @file
+ * PHP declarations in the codebase.
+ *
+ * Either looks up the declarations of a name, i.e. "go to definition", or
+ * suggests declarations whose name starts with the given text.
+ *
+ * Examples:
+ *   - GET /api/symbols?name=save
+ *   - GET /api/symbols?name=App\Model\User::save
+ *   - GET /api/symbols?prefix=sav&limit=10
+ *
+ * @see codebase.SymbolIndex.Lookup()
+ */
+
+package http
+
+import (
+	"net/http"
+	"server/codebase"
+)
+
+const defaultSymbolLimit = 20
+const maxSymbolLimit = 200
+
+/**
+ * Wrapper for the "symbols" handler.
+ */
+func makeSymbolsHandler(index func() *codebase.SymbolIndex) http.HandlerFunc {
+
+	return func(writeStream http.ResponseWriter, request *http.Request) {
+
+		if request.Method != http.MethodGet {
+			writeJSONError(writeStream, http.StatusMethodNotAllowed, "Only GET is supported.")
+			return
+		}
+
+		query := request.URL.Query()
+
+		if name := query.Get("name"); name != "" {
+			writeJSON(writeStream, http.StatusOK, index().Lookup(name))
+			return
+		} else if query.Get("prefix") == "" {
+			writeJSONError(writeStream, http.StatusBadRequest, "Either name or prefix is needed.")
+			return
+		}
+
+		limit, err := parseLimit(query.Get("limit"), defaultSymbolLimit, maxSymbolLimit)
+		if err != nil {
+			writeJSONError(writeStream, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		writeJSON(writeStream, http.StatusOK, index().Complete(query.Get("prefix"), limit))
+	}
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"server/codebase"
+	"testing"
+)
+
+/**
+ * Tests for the symbols handler.
+ */
+func TestSymbolsHandler(t *testing.T) {
+
+	codeDir, err := ioutil.TempDir("", "footle-symbols")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(codeDir)
+
+	ioutil.WriteFile(filepath.Join(codeDir, "index.php"), []byte("<?php\nfunction foo() {}\nfunction foobar() {}\n"), 0644)
+
+	index := codebase.NewSymbolIndex(codeDir, nil)
+	index.Build()
+	handler := makeSymbolsHandler(func() *codebase.SymbolIndex { return index })
+
+	cases := map[string]int{
+		"/api/symbols?name=foo":           1,
+		"/api/symbols?prefix=foo":         2,
+		"/api/symbols?prefix=foo&limit=1": 1,
+	}
+
+	for url, expected := range cases {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest("GET", url, nil))
+
+		var definitions []codebase.Definition
+		if err := json.NewDecoder(recorder.Body).Decode(&definitions); err != nil || len(definitions) != expected || definitions[0].Line != 2 {
+			t.Errorf("%s: Unexpected response: %v, %v", url, definitions, err)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("GET", "/api/symbols", nil))
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected a bad request, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,76 @@
+/**
+ * @file
+ * JSON file tree of the codebase.
+ *
+ * Directories are listed one at a time so that the UI can load them lazily as
+ * they are expanded.  Files ignored through .gitignore or the exclude list are
+ * left out unless asked for.
+ *
+ * Examples:
+ *   - GET /api/tree
+ *   - GET /api/tree?path=src/Controller
+ *   - GET /api/tree?path=vendor&ignored=1
+ */
+
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"server/codebase"
+)
+
+/**
+ * Wrapper for the "tree" handler.
+ */
+func makeTreeHandler(codeDir string, excludes []string) http.HandlerFunc {
+
+	return func(writeStream http.ResponseWriter, request *http.Request) {
+
+		if request.Method != http.MethodGet {
+			writeJSONError(writeStream, http.StatusMethodNotAllowed, "Only GET is supported.")
+			return
+		}
+
+		query := request.URL.Query()
+		filter := codebase.NewFilter(codeDir, excludes)
+		withIgnored := query.Get("ignored") == "1"
+
+		dir, err := codebase.ListDir(codeDir, query.Get("path"), filter, withIgnored)
+		if err == codebase.ErrOutsideCodebase {
+			writeJSONError(writeStream, http.StatusForbidden, err.Error())
+			return
+		} else if os.IsNotExist(err) {
+			writeJSONError(writeStream, http.StatusNotFound, "No such directory.")
+			return
+		} else if err != nil {
+			writeJSONError(writeStream, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		writeJSON(writeStream, http.StatusOK, dir)
+	}
+}
+
+/**
+ * Write any value as JSON.
+ */
+func writeJSON(writeStream http.ResponseWriter, status int, value interface{}) {
+
+	writeStream.Header().Set("Content-Type", "application/json")
+	writeStream.Header().Set("Cache-control", "no-cache")
+	writeStream.WriteHeader(status)
+
+	json.NewEncoder(writeStream).Encode(value)
+}
+
+/**
+ * Write an error message as JSON.
+ *
+ * Example: {"error": "No such directory."}
+ */
+func writeJSONError(writeStream http.ResponseWriter, status int, errorMsg string) {
+
+	writeJSON(writeStream, status, map[string]string{"error": errorMsg})
+}
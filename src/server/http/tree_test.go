@@ -0,0 +1,56 @@
+package http
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"server/codebase"
+	"testing"
+)
+
+/**
+ * Tests for the file tree handler.
+ */
+func TestTreeHandler(t *testing.T) {
+
+	codeDir, err := ioutil.TempDir("", "footle-tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(codeDir)
+
+	os.Mkdir(filepath.Join(codeDir, "vendor"), 0755)
+	ioutil.WriteFile(filepath.Join(codeDir, "index.php"), []byte("<?php"), 0644)
+
+	handler := makeTreeHandler(codeDir, []string{"vendor/"})
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("GET", "/api/tree", nil))
+
+	var dir codebase.Entry
+	if err := json.NewDecoder(recorder.Body).Decode(&dir); err != nil || recorder.Code != http.StatusOK {
+		t.Fatalf("Unexpected response: %d, %v", recorder.Code, err)
+	}
+
+	if len(dir.Children) != 1 || dir.Children[0].Name != "index.php" || dir.Children[0].Type != codebase.FileType {
+		t.Errorf("Unexpected listing: %v", dir)
+	}
+
+	statuses := map[string]int{
+		"/api/tree?path=nowhere":   http.StatusNotFound,
+		"/api/tree?path=index.php": http.StatusBadRequest,
+		"/api/tree?path=../etc":    http.StatusForbidden,
+	}
+
+	for url, expected := range statuses {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest("GET", url, nil))
+
+		if expected != recorder.Code {
+			t.Errorf("Expected %d for %s, got %d", expected, url, recorder.Code)
+		}
+	}
+}
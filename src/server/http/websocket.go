@@ -0,0 +1,219 @@
+/**
+ * @file
+ * WebSocket transport for the HTTP UI.
+ *
+ * A single WebSocket carries both commands from the browser and messages for
+ * the browser.  This is an alternative to the "/steering-wheel" and
+ * "/message-stream" pair which remain as a fallback.
+ *
+ * All frames are JSON objects with a Type:
+ *   - command: From the browser.  Example: {"Type": "command", "Id": "7", "Cmd": "step_over"}
+ *   - ack: The command with the given Id has been accepted.
+ *   - error: The command with the given Id has been rejected.  See Error.
+ *   - response: The response to the command with the given Id.  See Message.
+ *   - message: A message broadcast to all UIs.  See Message.
+ *   - resync: Messages have been missed.  The browser should start afresh from
+ *     the current state.
+ */
+
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"server/core/tracker"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+/**
+ * Identifies commands issued over WebSockets.
+ *
+ * Each WebSocket connection appends its own ID.  Example: "ws:3"
+ */
+const WebSocketOrigin = "ws"
+
+/**
+ * Largest frame we are willing to read from a browser.
+ */
+const maxWebSocketFrameSize = 64 * 1024
+
+/**
+ * How long to wait for a browser to accept a frame.
+ */
+const webSocketWriteTimeout = 10 * time.Second
+
+type wsFrame struct {
+	Type    string
+	Id      string          `json:",omitempty"`
+	Cmd     string          `json:",omitempty"`
+	Error   string          `json:",omitempty"`
+	Message json.RawMessage `json:",omitempty"`
+}
+
+/**
+ * Number of WebSocket connections so far.  Used for connection IDs.
+ */
+var lastWebSocketId int64
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+}
+
+/**
+ * Wrapper over serveWebSocket().
+ */
+func makeWebSocketHandler(out chan tracker.Request, arrival chan clientArrival, departure chan client, codeDir string) http.HandlerFunc {
+
+	return func(writeStream http.ResponseWriter, request *http.Request) {
+
+		serveWebSocket(writeStream, request, out, arrival, departure, codeDir)
+	}
+}
+
+/**
+ * Serves the "/websocket" path.
+ *
+ * Like transmit(), each WebSocket joins the HTTP client list to receive
+ * broadcast messages.  Additionally, commands arriving over the WebSocket are
+ * acknowledged and later answered with their responses.
+ */
+func serveWebSocket(writeStream http.ResponseWriter, request *http.Request, debugger chan tracker.Request, arrival chan clientArrival, departure chan client, codeDir string) {
+
+	conn, err := upgrader.Upgrade(writeStream, request, nil)
+	if err != nil {
+		// The upgrader has already replied with an HTTP error.
+		log.Println(err)
+		return
+	}
+	defer conn.Close()
+
+	conn.SetReadLimit(maxWebSocketFrameSize)
+
+	myEar := newClientQueue()
+	outbox := make(chan wsFrame)
+	writerDone := make(chan struct{})
+
+	arrival <- clientArrival{queue: myEar}
+	go writeWebSocketFrames(conn, myEar, outbox, writerDone)
+
+	origin := fmt.Sprintf("%s:%d", WebSocketOrigin, atomic.AddInt64(&lastWebSocketId, 1))
+	readWebSocketFrames(conn, debugger, outbox, writerDone, origin, codeDir)
+
+	// Leave the client list.  The client list closes myEar which stops the
+	// writer, if it has not stopped already.
+	// @see manageClients()
+	departure <- myEar
+	<-writerDone
+}
+
+/**
+ * Process commands arriving over a WebSocket until it closes.
+ */
+func readWebSocketFrames(conn *websocket.Conn, debugger chan tracker.Request, outbox chan<- wsFrame, writerDone <-chan struct{}, origin, codeDir string) {
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure, websocket.CloseAbnormalClosure) {
+			log.Println(err)
+			return
+		} else if err != nil {
+			return
+		}
+
+		var frame wsFrame
+		var reply wsFrame
+
+		if err := json.Unmarshal(data, &frame); err != nil {
+			reply = wsFrame{Type: "error", Error: err.Error()}
+		} else if frame.Type != "command" {
+			reply = wsFrame{Type: "error", Id: frame.Id, Error: fmt.Sprintf("Unknown frame type: %s", frame.Type)}
+		} else if err := validateCmd(frame.Cmd); err != nil {
+			reply = wsFrame{Type: "error", Id: frame.Id, Error: err.Error()}
+		} else {
+			reply = wsFrame{Type: "ack", Id: frame.Id}
+		}
+
+		select {
+		case outbox <- reply:
+		case <-writerDone:
+			return
+		}
+
+		if reply.Type != "ack" {
+			continue
+		}
+
+		request := tracker.NewSync(frame.Cmd, origin)
+		debugger <- request
+
+		go awaitResponse(request, frame.Id, outbox, writerDone, codeDir)
+	}
+}
+
+/**
+ * Pass on the response for a command once it arrives.
+ *
+ * Commands such as "run" may not receive a response before the WebSocket
+ * closes.  In that case, we give up.
+ */
+func awaitResponse(request tracker.Request, frameId string, outbox chan<- wsFrame, writerDone <-chan struct{}, codeDir string) {
+
+	var reply wsFrame
+
+	select {
+	case msg := <-request.Reply:
+		jsonMsg, err := prepareMsgForBrowsers(msg, codeDir)
+		if err != nil {
+			reply = wsFrame{Type: "error", Id: frameId, Error: err.Error()}
+		} else {
+			reply = wsFrame{Type: "response", Id: frameId, Message: jsonMsg}
+		}
+	case <-writerDone:
+		return
+	}
+
+	select {
+	case outbox <- reply:
+	case <-writerDone:
+	}
+}
+
+/**
+ * Write broadcast messages and command replies to a WebSocket.
+ *
+ * This is the only goroutine writing to the WebSocket as concurrent writes are
+ * not allowed.  It stops when myEar is closed, which happens at departure or
+ * when the client registry gives up on a slow client.  In the latter case, the
+ * WebSocket is closed so that the browser reconnects.
+ */
+func writeWebSocketFrames(conn *websocket.Conn, myEar <-chan streamEvent, outbox <-chan wsFrame, writerDone chan<- struct{}) {
+
+	defer close(writerDone)
+
+	for {
+		var frame wsFrame
+
+		select {
+		case event, isOpen := <-myEar:
+			if !isOpen {
+				conn.Close()
+				return
+			}
+
+			frame = wsFrame{Type: "message", Message: json.RawMessage(event.Data)}
+			if event.Name == resyncEventName {
+				frame = wsFrame{Type: "resync"}
+			}
+		case frame = <-outbox:
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+		conn.WriteJSON(frame)
+	}
+}
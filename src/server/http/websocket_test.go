@@ -0,0 +1,94 @@
+/**
+ * @file
+ * Tests for the WebSocket transport.
+ */
+
+package http
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"server/core/tracker"
+	"server/dbgp/message"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+/**
+ * Tests for serveWebSocket().
+ *
+ * Sends a valid and an invalid command and then listens for a broadcast.
+ */
+func TestServeWebSocket(t *testing.T) {
+
+	commands := make(chan tracker.Request)
+	arrival := make(chan clientArrival)
+	departure := make(chan client)
+
+	server := httptest.NewServer(makeWebSocketHandler(commands, arrival, departure, "/foo"))
+	defer server.Close()
+
+	wsUrl := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(wsUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	ear := (<-arrival).queue
+
+	// Invalid command.
+	conn.WriteJSON(wsFrame{Type: "command", Id: "1", Cmd: "foo"})
+
+	var frame wsFrame
+	if err := conn.ReadJSON(&frame); err != nil || frame.Type != "error" || frame.Id != "1" {
+		t.Errorf("Expected error frame for an invalid command, got: %v, %v", frame, err)
+	}
+
+	// Valid command.
+	conn.WriteJSON(wsFrame{Type: "command", Id: "2", Cmd: "status"})
+
+	if err := conn.ReadJSON(&frame); err != nil || frame.Type != "ack" || frame.Id != "2" {
+		t.Errorf("Expected ack frame, got: %v, %v", frame, err)
+	}
+
+	request := <-commands
+	if request.Cmd != "status" || !strings.HasPrefix(request.Origin, WebSocketOrigin+":") {
+		t.Errorf("Unexpected request: %v", request)
+	}
+
+	statusMsg := message.Message{MessageType: "response", State: "break"}
+	statusMsg.Properties.Command = "status"
+	request.Answer(statusMsg)
+
+	var reply message.Message
+	if err := conn.ReadJSON(&frame); err != nil || frame.Type != "response" || frame.Id != "2" {
+		t.Errorf("Expected response frame, got: %v, %v", frame, err)
+	} else if err := json.Unmarshal(frame.Message, &reply); err != nil || reply.State != "break" {
+		t.Errorf("Unexpected response: %s", frame.Message)
+	}
+
+	// Broadcast.
+	ear <- streamEvent{Id: 1, Name: dbgpEventName, Data: `{"State": "stopped"}`}
+
+	if err := conn.ReadJSON(&frame); err != nil || frame.Type != "message" || !strings.Contains(string(frame.Message), "stopped") {
+		t.Errorf("Expected message frame, got: %v, %v", frame, err)
+	}
+
+	// Departure.
+	conn.Close()
+
+	select {
+	case departedEar := <-departure:
+		if departedEar != ear {
+			t.Error("A different client has departed.")
+		}
+		close(ear)
+	case <-time.After(time.Second):
+		t.Error("WebSocket has not departed after closing.")
+	}
+}
@@ -0,0 +1,231 @@
+/**
+ * @file
+ * Executable line analysis.
+ *
+ * Works out which lines of a PHP file can carry a breakpoint.  A line can carry
+ * a breakpoint when a statement starts on it.  Blank lines, comments, inline
+ * HTML, and declarations (e.g. class, use, property) cannot.  Neither can the
+ * second and later lines of a statement that spans several lines, e.g. the
+ * elements of a multi-line array.  The closing brace of a function can, as
+ * that is where the function returns.
+ *
+ * This is an approximation of what Xdebug considers executable.  It errs on the
+ * side of allowing breakpoints.
+ */
+
+package php
+
+import "strings"
+
+/**
+ * Statements starting with these keywords do not execute anything.
+ */
+var declarationKeywords = toSet("abstract", "class", "const", "declare",
+	"do", "else", "enddeclare", "endfor", "endforeach", "endif", "endswitch",
+	"endwhile", "enum", "final", "finally", "function", "interface",
+	"namespace", "private", "protected", "public", "readonly", "static",
+	"trait", "try", "use", "var")
+
+/**
+ * Statements starting with these keywords end at a colon when using the
+ * alternative syntax.
+ *
+ * Example: if ($foo): ... endif;
+ */
+var colonKeywords = toSet("case", "default", "else", "elseif", "for",
+	"foreach", "if", "switch", "while")
+
+/**
+ * A block enclosed in curly braces.
+ */
+type block struct {
+	outerDepth int  // Bracket depth outside the block.
+	isFunction bool // Function or method body.
+}
+
+type lineAnalyser struct {
+	executableLines  map[int]bool
+	isInPHP          bool
+	isStatementStart bool
+	firstWord        string // Lowercase first word of the current statement.
+	hasFunction      bool   // Has the current statement declared a function?
+	depth            int    // Nesting depth of round and square brackets.
+	attributeDepth   int    // Bracket depth of the current attribute, if any.
+	blocks           []block
+}
+
+/**
+ * Lines where a statement starts.
+ *
+ * Line numbers start at 1.
+ */
+func ExecutableLines(tokens []Token) (executableLines map[int]bool) {
+
+	a := lineAnalyser{executableLines: make(map[int]bool)}
+
+	for _, token := range tokens {
+		switch token.Type {
+		case Tag:
+			a.processTag(token)
+		case Plain:
+			if a.isInPHP {
+				a.processPunctuation(token)
+			}
+		case Comment:
+			// Nothing to execute.
+		default:
+			a.processWord(token)
+		}
+	}
+
+	return a.executableLines
+}
+
+/**
+ * Next line, at or after the given one, that can carry a breakpoint.
+ *
+ * Returns the given line when there is no such line.
+ */
+func NextExecutableLine(executableLines map[int]bool, lineNo int) int {
+
+	nextLineNo := 0
+
+	for candidate := range executableLines {
+		if candidate >= lineNo && (nextLineNo == 0 || candidate < nextLineNo) {
+			nextLineNo = candidate
+		}
+	}
+
+	if nextLineNo == 0 {
+		return lineNo
+	}
+
+	return nextLineNo
+}
+
+/**
+ * PHP open and close tags end any statement.
+ *
+ * The short echo tag (<?=) is an echo statement in itself.
+ */
+func (a *lineAnalyser) processTag(token Token) {
+
+	a.isInPHP = (token.Text != "?>")
+	a.startStatement()
+
+	if token.Text == "<?=" {
+		a.executableLines[token.Line] = true
+		a.isStatementStart = false
+		a.firstWord = "echo"
+	}
+}
+
+/**
+ * Variables, names, strings, numbers, etc.
+ */
+func (a *lineAnalyser) processWord(token Token) {
+
+	if token.Type == Keyword && strings.ToLower(token.Text) == "function" {
+		a.hasFunction = true
+	}
+
+	if !a.isStatementStart || a.attributeDepth > 0 {
+		return
+	}
+
+	a.isStatementStart = false
+	a.firstWord = strings.ToLower(token.Text)
+
+	if token.Type != Keyword || !declarationKeywords[a.firstWord] {
+		a.executableLines[token.Line] = true
+	}
+}
+
+/**
+ * Whitespace, brackets, operators, etc.
+ */
+func (a *lineAnalyser) processPunctuation(token Token) {
+
+	text := strings.TrimSpace(token.Text)
+
+	switch text {
+	case "":
+		// Whitespace.
+	case "#[":
+		// Attributes do not start statements.  Example: #[Pure]
+		a.depth++
+		if a.isStatementStart {
+			a.attributeDepth = a.depth
+		}
+	case "(", "[":
+		a.processWord(token)
+		a.depth++
+	case ")", "]":
+		a.closeBracket()
+	case "{":
+		a.blocks = append(a.blocks, block{outerDepth: a.depth, isFunction: a.hasFunction})
+		a.depth = 0
+		a.startStatement()
+	case "}":
+		a.closeBlock(token.Line)
+	case ";":
+		if a.depth == 0 {
+			a.startStatement()
+		}
+	case ":":
+		if a.depth == 0 && colonKeywords[a.firstWord] {
+			a.startStatement()
+		}
+	default:
+		// Example: ++$i;
+		a.processWord(token)
+	}
+}
+
+/**
+ * Round or square bracket closure.
+ */
+func (a *lineAnalyser) closeBracket() {
+
+	if a.depth > 0 {
+		a.depth--
+	}
+
+	if a.attributeDepth > 0 && a.depth < a.attributeDepth {
+		a.attributeDepth = 0
+		a.isStatementStart = true
+	}
+}
+
+/**
+ * Curly brace closure.
+ *
+ * Blocks inside an expression, e.g. closures, leave the enclosing statement
+ * unfinished.
+ */
+func (a *lineAnalyser) closeBlock(lineNo int) {
+
+	if len(a.blocks) == 0 {
+		a.depth = 0
+		a.startStatement()
+		return
+	}
+
+	closedBlock := a.blocks[len(a.blocks)-1]
+	a.blocks = a.blocks[:len(a.blocks)-1]
+
+	if closedBlock.isFunction {
+		a.executableLines[lineNo] = true
+	}
+
+	a.depth = closedBlock.outerDepth
+	a.startStatement()
+	a.isStatementStart = (a.depth == 0)
+}
+
+func (a *lineAnalyser) startStatement() {
+
+	a.isStatementStart = true
+	a.firstWord = ""
+	a.hasFunction = false
+}
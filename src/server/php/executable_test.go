@@ -0,0 +1,70 @@
+package php
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+/**
+ * Tests for ExecutableLines().
+ */
+func TestExecutableLines(t *testing.T) {
+
+	src := `<?php
+namespace Foo;
+
+use Bar\Baz;
+
+/**
+ * A class.
+ */
+#[Pure]
+class Qux {
+  public $list = [
+    1,
+  ];
+
+  public function quux($x) {
+    $y = [
+      $x,
+      2,
+    ];
+
+    if ($x):
+      return array_map(function ($z) {
+        return $z;
+      }, $y);
+    endif;
+  }
+}
+?>
+<h1><?= $title ?></h1>
+`
+
+	lineNos := []int{}
+	for lineNo := range ExecutableLines(Tokenize(src)) {
+		lineNos = append(lineNos, lineNo)
+	}
+	sort.Ints(lineNos)
+
+	expected := []int{16, 21, 22, 23, 24, 26, 29}
+	if !reflect.DeepEqual(expected, lineNos) {
+		t.Errorf("Expected executable lines %v, got %v", expected, lineNos)
+	}
+}
+
+/**
+ * Tests for NextExecutableLine().
+ */
+func TestNextExecutableLine(t *testing.T) {
+
+	executableLines := map[int]bool{3: true, 7: true, 5: true}
+
+	cases := map[int]int{1: 3, 3: 3, 4: 5, 6: 7, 8: 8}
+	for lineNo, expected := range cases {
+		if nextLineNo := NextExecutableLine(executableLines, lineNo); expected != nextLineNo {
+			t.Errorf("Expected %d for line %d, got %d", expected, lineNo, nextLineNo)
+		}
+	}
+}
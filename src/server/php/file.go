@@ -0,0 +1,33 @@
+/**
+ * @file
+ * PHP files.
+ */
+
+package php
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+/**
+ * Extensions of PHP files.
+ */
+var phpExtensions = map[string]bool{
+	".php":     true,
+	".phtml":   true,
+	".inc":     true,
+	".module":  true,
+	".install": true,
+	".theme":   true,
+	".profile": true,
+	".engine":  true,
+}
+
+/**
+ * Is this a PHP file?  Judged by its extension.
+ */
+func IsPHPFile(path string) bool {
+
+	return phpExtensions[strings.ToLower(filepath.Ext(path))]
+}
@@ -0,0 +1,66 @@
+/**
+ * @file
+ * Outline of a PHP file.
+ *
+ * Lists classes with their members, and functions and constants outside
+ * classes.  Namespaces are left out.
+ *
+ * Each function and method comes with the first line of its body that can
+ * carry a breakpoint.  This is where execution stops when a breakpoint is set
+ * at the function's entry.
+ */
+
+package php
+
+import "strings"
+
+type OutlineItem struct {
+	Symbol
+	EntryLine int           `json:"entryLine,omitempty"` // Functions and methods with a body only.
+	Members   []OutlineItem `json:"members,omitempty"`   // Classes only.
+}
+
+/**
+ * Outline of a PHP file, in order of appearance.
+ */
+func Outline(tokens []Token) (outline []OutlineItem) {
+
+	executableLines := ExecutableLines(tokens)
+	outline = []OutlineItem{}
+
+	for _, symbol := range Symbols(tokens) {
+		item := OutlineItem{Symbol: symbol}
+
+		if symbol.Kind == FunctionKind || symbol.Kind == MethodKind {
+			item.EntryLine = findEntryLine(executableLines, symbol)
+		}
+
+		last := len(outline) - 1
+		isMember := strings.Contains(symbol.FullName, "::")
+
+		switch {
+		case symbol.Kind == NamespaceKind:
+		case isMember && last >= 0 && outline[last].EndLine >= symbol.Line:
+			outline[last].Members = append(outline[last].Members, item)
+		case !isMember:
+			outline = append(outline, item)
+		}
+	}
+
+	return outline
+}
+
+/**
+ * First line of a function's body that can carry a breakpoint.
+ *
+ * Zero for functions without a body, e.g. abstract methods.
+ */
+func findEntryLine(executableLines map[int]bool, symbol Symbol) int {
+
+	entryLine := NextExecutableLine(executableLines, symbol.Line)
+	if !executableLines[entryLine] || entryLine > symbol.EndLine {
+		return 0
+	}
+
+	return entryLine
+}
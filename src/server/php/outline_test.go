@@ -0,0 +1,40 @@
+package php
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+/**
+ * Tests for Outline().
+ */
+func TestOutline(t *testing.T) {
+
+	src := `<?php
+namespace App;
+
+abstract class User {
+  const ROLE = 'user';
+
+  abstract protected function role();
+
+  public function save() {
+
+    $this->store();
+  }
+}
+
+function helper() {}
+`
+
+	expected := `[{"name":"User","fullName":"App\\User","kind":"class","line":4,"endLine":13,"members":[` +
+		`{"name":"ROLE","fullName":"App\\User::ROLE","kind":"constant","line":5,"visibility":"public"},` +
+		`{"name":"role","fullName":"App\\User::role","kind":"method","line":7,"endLine":7,"visibility":"protected"},` +
+		`{"name":"save","fullName":"App\\User::save","kind":"method","line":9,"endLine":12,"visibility":"public","entryLine":11}]},` +
+		`{"name":"helper","fullName":"App\\helper","kind":"function","line":15,"endLine":15,"entryLine":15}]`
+
+	outline, _ := json.Marshal(Outline(Tokenize(src)))
+	if string(outline) != expected {
+		t.Errorf("Expected %s, got %s", expected, outline)
+	}
+}
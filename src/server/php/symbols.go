@@ -0,0 +1,347 @@
+/**
+ * @file
+ * Symbol extraction.
+ *
+ * Finds the declarations in a PHP file: namespaces, classes, interfaces,
+ * traits, enums, functions, methods, and constants.  Constants are declared
+ * with "const" or define().
+ *
+ * Like the tokenizer, this never fails.  Broken code yields whatever
+ * declarations can be recognised.
+ */
+
+package php
+
+import "strings"
+
+type SymbolKind string
+
+const (
+	NamespaceKind SymbolKind = "namespace"
+	ClassKind     SymbolKind = "class"
+	InterfaceKind SymbolKind = "interface"
+	TraitKind     SymbolKind = "trait"
+	EnumKind      SymbolKind = "enum"
+	FunctionKind  SymbolKind = "function"
+	MethodKind    SymbolKind = "method"
+	ConstantKind  SymbolKind = "constant"
+)
+
+/**
+ * A declaration.
+ *
+ * FullName is fully qualified, without the leading backslash.  Methods and
+ * class constants are qualified with their class.  Examples:
+ *   - App\Model\User
+ *   - App\Model\User::save
+ *   - App\helper
+ *
+ * EndLine is where the body of a class or function ends.  Bodies left open
+ * in broken code end with the file.
+ */
+type Symbol struct {
+	Name       string     `json:"name"`
+	FullName   string     `json:"fullName"`
+	Kind       SymbolKind `json:"kind"`
+	Line       int        `json:"line"`
+	EndLine    int        `json:"endLine,omitempty"`
+	Visibility string     `json:"visibility,omitempty"` // Class members only.
+}
+
+/**
+ * Keywords introducing class-like declarations.
+ */
+var classKeywords = map[string]SymbolKind{
+	"class":     ClassKind,
+	"interface": InterfaceKind,
+	"trait":     TraitKind,
+	"enum":      EnumKind,
+}
+
+/**
+ * Keywords that may come before a class member's declaration.
+ */
+var modifierKeywords = toSet("abstract", "final", "private", "protected",
+	"public", "readonly", "static", "var")
+
+/**
+ * Body of a class-like declaration or function.
+ */
+type body struct {
+	name   string // Class name.  Empty for anonymous classes and functions.
+	symbol int    // Index of the declaration in symbols.  -1 when there is none.
+	depth  int    // Brace depth inside the body.
+}
+
+type symbolExtractor struct {
+	tokens    []Token // Only the significant ones.
+	symbols   []Symbol
+	namespace string
+	depth     int // Nesting depth of curly braces.
+	classes   []body
+	functions []body // Named functions and methods only.
+
+	isClassPending bool // Class declared, body yet to start.
+	pendingClass   body
+
+	isFunctionPending bool // Function declared, body yet to start.
+	pendingFunction   body
+}
+
+/**
+ * Declarations in a PHP file, in order of appearance.
+ */
+func Symbols(tokens []Token) (symbols []Symbol) {
+
+	e := symbolExtractor{}
+
+	for _, token := range tokens {
+		if token.Type != Comment && strings.TrimSpace(token.Text) != "" {
+			e.tokens = append(e.tokens, token)
+		}
+	}
+
+	for i := 0; i < len(e.tokens); i++ {
+		i = e.process(i)
+	}
+
+	// Bodies left open by broken code.
+	openBodies := append(e.classes, e.functions...)
+	if e.isFunctionPending {
+		openBodies = append(openBodies, e.pendingFunction)
+	}
+
+	for _, openBody := range openBodies {
+		if openBody.symbol >= 0 {
+			e.symbols[openBody.symbol].EndLine = e.tokens[len(e.tokens)-1].Line
+		}
+	}
+
+	return e.symbols
+}
+
+/**
+ * Process the token at the given position.
+ *
+ * Returns the position of the last token consumed.
+ */
+func (e *symbolExtractor) process(i int) int {
+
+	token := e.tokens[i]
+	word := strings.ToLower(token.Text)
+
+	switch {
+	case token.Text == "{":
+		e.depth++
+		if e.isClassPending {
+			e.pendingClass.depth = e.depth
+			e.classes = append(e.classes, e.pendingClass)
+			e.isClassPending = false
+		} else if e.isFunctionPending {
+			e.pendingFunction.depth = e.depth
+			e.functions = append(e.functions, e.pendingFunction)
+			e.isFunctionPending = false
+		}
+	case token.Text == "}":
+		e.functions = e.closeBody(e.functions, token.Line)
+		e.classes = e.closeBody(e.classes, token.Line)
+		e.depth--
+	case token.Text == ";" && e.isFunctionPending:
+		// Abstract or interface method.
+		e.symbols[e.pendingFunction.symbol].EndLine = token.Line
+		e.isFunctionPending = false
+	case token.Type == Keyword && word == "namespace" && e.peek(i+1).Text != `\`:
+		return e.processNamespace(i)
+	case token.Type == Keyword && classKeywords[word] != "":
+		e.isClassPending, e.pendingClass = true, body{symbol: -1}
+
+		// Anonymous classes have no name.
+		if name := e.peek(i + 1); name.Type == Identifier {
+			e.pendingClass = body{name: e.qualify(name.Text), symbol: len(e.symbols)}
+			e.add(Symbol{Name: name.Text, FullName: e.pendingClass.name, Kind: classKeywords[word], Line: token.Line})
+
+			return i + 1
+		}
+	case token.Type == Keyword && word == "function":
+		next := i + 1
+		if e.peek(next).Text == "&" {
+			next++
+		}
+
+		// Closures have no name.
+		if name := e.peek(next); isWord(name) && e.peek(next+1).Text == "(" {
+			if class, isInClass := e.currentClass(); !isInClass {
+				e.isFunctionPending, e.pendingFunction = true, body{symbol: len(e.symbols)}
+				e.add(Symbol{Name: name.Text, FullName: e.qualify(name.Text), Kind: FunctionKind, Line: token.Line})
+			} else if class.name != "" {
+				e.isFunctionPending, e.pendingFunction = true, body{symbol: len(e.symbols)}
+				e.add(Symbol{Name: name.Text, FullName: class.name + "::" + name.Text, Kind: MethodKind, Line: token.Line, Visibility: e.visibility(i)})
+			}
+
+			return next
+		}
+	case token.Type == Keyword && word == "const":
+		return e.processConst(i)
+	case token.Type == Identifier && word == "define" && e.peek(i+1).Text == "(" && e.peek(i+2).Type == String:
+		if name := strings.Trim(e.peek(i+2).Text, `'"`); name != "" {
+			e.add(Symbol{Name: name, FullName: strings.TrimPrefix(name, `\`), Kind: ConstantKind, Line: token.Line})
+		}
+
+		return i + 2
+	}
+
+	return i
+}
+
+/**
+ * Namespace declaration.
+ *
+ * Example: namespace App\Model;
+ */
+func (e *symbolExtractor) processNamespace(i int) int {
+
+	name := ""
+
+	for i+1 < len(e.tokens) && (isWord(e.tokens[i+1]) || e.tokens[i+1].Text == `\`) {
+		i++
+		name += e.tokens[i].Text
+	}
+
+	e.namespace = strings.Trim(name, `\`)
+	if e.namespace != "" {
+		e.add(Symbol{Name: e.namespace, FullName: e.namespace, Kind: NamespaceKind, Line: e.tokens[i].Line})
+	}
+
+	return i
+}
+
+/**
+ * Constant declaration.
+ *
+ * The name is the word right before each "=".  Examples:
+ *   - const FOO = 1, BAR = 2;
+ *   - public const int LIMIT = 10;
+ */
+func (e *symbolExtractor) processConst(i int) int {
+
+	bracketDepth := 0
+	visibility := e.visibility(i)
+
+	for i++; i < len(e.tokens); i++ {
+		token := e.tokens[i]
+
+		switch token.Text {
+		case "(", "[", "{":
+			bracketDepth++
+		case ")", "]", "}":
+			bracketDepth--
+		case "=":
+			if name := e.peek(i - 1); bracketDepth == 0 && isWord(name) {
+				if class, isInClass := e.currentClass(); !isInClass {
+					e.add(Symbol{Name: name.Text, FullName: e.qualify(name.Text), Kind: ConstantKind, Line: name.Line})
+				} else if class.name != "" {
+					e.add(Symbol{Name: name.Text, FullName: class.name + "::" + name.Text, Kind: ConstantKind, Line: name.Line, Visibility: visibility})
+				}
+			}
+		case ";":
+			return i
+		}
+
+		if bracketDepth < 0 {
+			// Broken code.  Leave the bracket for process().
+			return i - 1
+		}
+	}
+
+	return i
+}
+
+func (e *symbolExtractor) add(symbol Symbol) {
+
+	e.symbols = append(e.symbols, symbol)
+}
+
+/**
+ * Note where a body ends when its closing brace arrives.
+ *
+ * Returns the bodies that remain open.
+ */
+func (e *symbolExtractor) closeBody(bodies []body, lineNo int) []body {
+
+	last := len(bodies) - 1
+	if last < 0 || bodies[last].depth != e.depth {
+		return bodies
+	}
+
+	if bodies[last].symbol >= 0 {
+		e.symbols[bodies[last].symbol].EndLine = lineNo
+	}
+
+	return bodies[:last]
+}
+
+/**
+ * Visibility of the class member declared at the given position.
+ *
+ * Judged by the modifiers in front of the declaration.  Public by default.
+ */
+func (e *symbolExtractor) visibility(i int) string {
+
+	for i--; i >= 0 && e.tokens[i].Type == Keyword && modifierKeywords[strings.ToLower(e.tokens[i].Text)]; i-- {
+		switch modifier := strings.ToLower(e.tokens[i].Text); modifier {
+		case "private", "protected", "public":
+			return modifier
+		}
+	}
+
+	return "public"
+}
+
+/**
+ * The class whose body we are directly in, if any.
+ *
+ * Members of anonymous classes are left out as they cannot be referred to.
+ */
+func (e *symbolExtractor) currentClass() (class body, isInClass bool) {
+
+	if last := len(e.classes) - 1; last >= 0 && e.classes[last].depth == e.depth {
+		return e.classes[last], true
+	}
+
+	return class, false
+}
+
+/**
+ * Prefix a name with the current namespace.
+ */
+func (e *symbolExtractor) qualify(name string) string {
+
+	if e.namespace == "" {
+		return name
+	}
+
+	return e.namespace + `\` + name
+}
+
+/**
+ * Token at the given position, if any.
+ */
+func (e *symbolExtractor) peek(i int) Token {
+
+	if i < 0 || i >= len(e.tokens) {
+		return Token{}
+	}
+
+	return e.tokens[i]
+}
+
+/**
+ * Can this token be a name?
+ *
+ * Keywords can be method names, e.g. $list->list().  They can also be part of
+ * namespace names, e.g. App\Enum.
+ */
+func isWord(token Token) bool {
+
+	return (token.Type == Identifier || token.Type == Keyword || token.Type == Constant) && isIdentStart(token.Text[0])
+}
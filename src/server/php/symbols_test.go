@@ -0,0 +1,72 @@
+package php
+
+import (
+	"reflect"
+	"testing"
+)
+
+/**
+ * Tests for Symbols().
+ */
+func TestSymbols(t *testing.T) {
+
+	src := `<?php
+namespace App\Enum;
+
+use function Lib\helper;
+use const Lib\LIMIT;
+
+define('VERSION', '1.0');
+const DEBUG = false, LEVEL = 2;
+
+/**
+ * class Fake {}
+ */
+final class Status implements \JsonSerializable {
+  public const int OPEN = 1;
+  const LIST = [1, 2], CLOSED = 0;
+
+  public static function &list(array $options = []): array {
+    $callback = function ($x) { return $x; };
+    $object = new class { function inner() {} };
+    return [static::class, Status::class];
+  }
+
+  private function save() {
+  }
+}
+
+interface Shape { function area(): float; }
+trait Named {}
+enum Suit: string { case Hearts = 'H'; }
+
+function helper() {}
+
+function broken() {
+  if (
+`
+
+	expected := []Symbol{
+		{"App\\Enum", "App\\Enum", NamespaceKind, 2, 0, ""},
+		{"VERSION", "VERSION", ConstantKind, 7, 0, ""},
+		{"DEBUG", "App\\Enum\\DEBUG", ConstantKind, 8, 0, ""},
+		{"LEVEL", "App\\Enum\\LEVEL", ConstantKind, 8, 0, ""},
+		{"Status", "App\\Enum\\Status", ClassKind, 13, 25, ""},
+		{"OPEN", "App\\Enum\\Status::OPEN", ConstantKind, 14, 0, "public"},
+		{"LIST", "App\\Enum\\Status::LIST", ConstantKind, 15, 0, "public"},
+		{"CLOSED", "App\\Enum\\Status::CLOSED", ConstantKind, 15, 0, "public"},
+		{"list", "App\\Enum\\Status::list", MethodKind, 17, 21, "public"},
+		{"save", "App\\Enum\\Status::save", MethodKind, 23, 24, "private"},
+		{"Shape", "App\\Enum\\Shape", InterfaceKind, 27, 27, ""},
+		{"area", "App\\Enum\\Shape::area", MethodKind, 27, 27, "public"},
+		{"Named", "App\\Enum\\Named", TraitKind, 28, 28, ""},
+		{"Suit", "App\\Enum\\Suit", EnumKind, 29, 29, ""},
+		{"helper", "App\\Enum\\helper", FunctionKind, 31, 31, ""},
+		{"broken", "App\\Enum\\broken", FunctionKind, 33, 34, ""},
+	}
+
+	symbols := Symbols(Tokenize(src))
+	if !reflect.DeepEqual(expected, symbols) {
+		t.Errorf("Expected %v, got %v", expected, symbols)
+	}
+}
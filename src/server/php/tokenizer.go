@@ -0,0 +1,470 @@
+/**
+ * @file
+ * PHP tokenizer.
+ *
+ * Breaks PHP source code into tokens that are good enough for syntax
+ * highlighting and simple code analysis.  Unlike PHP's own tokenizer, it never
+ * fails.  Broken code is tokenized as well as possible.
+ *
+ * Tokens may span several lines, e.g. comments, strings, and heredocs.  Use
+ * SplitLines() to get the tokens of each line.
+ */
+
+package php
+
+import "strings"
+
+type TokenType string
+
+const (
+	Plain      TokenType = "" // Whitespace, operators, inline HTML, etc.
+	Tag        TokenType = "tag"
+	Keyword    TokenType = "keyword"
+	Constant   TokenType = "constant"
+	Identifier TokenType = "identifier"
+	Variable   TokenType = "variable"
+	String     TokenType = "string"
+	Comment    TokenType = "comment"
+	Number     TokenType = "number"
+)
+
+type Token struct {
+	Type TokenType
+	Text string
+	Line int // Where the token starts.  The first line is 1.
+}
+
+var keywords = toSet("abstract", "and", "array", "as", "break", "callable",
+	"case", "catch", "class", "clone", "const", "continue", "declare",
+	"default", "die", "do", "echo", "else", "elseif", "empty", "enddeclare",
+	"endfor", "endforeach", "endif", "endswitch", "endwhile", "enum", "eval",
+	"exit", "extends", "final", "finally", "fn", "for", "foreach", "function",
+	"global", "goto", "if", "implements", "include", "include_once",
+	"instanceof", "insteadof", "interface", "isset", "list", "match",
+	"namespace", "new", "or", "parent", "print", "private", "protected",
+	"public", "readonly", "require", "require_once", "return", "self",
+	"static", "switch", "throw", "trait", "try", "unset", "use", "var",
+	"while", "xor", "yield")
+
+var constants = toSet("true", "false", "null", "__class__", "__dir__",
+	"__file__", "__function__", "__line__", "__method__", "__namespace__",
+	"__trait__")
+
+/**
+ * Operators after which a keyword is only a member name.
+ *
+ * Example: $list->list()
+ */
+var memberOperators = toSet("->", "?->", "::")
+
+type tokenizer struct {
+	src             string
+	pos             int
+	line            int
+	tokens          []Token
+	lastSignificant Token // Last token that is not whitespace.
+}
+
+/**
+ * Tokenize PHP source code.
+ */
+func Tokenize(src string) (tokens []Token) {
+
+	t := tokenizer{src: src, line: 1}
+
+	for t.pos < len(t.src) {
+		t.scanInlineHTML()
+		t.scanPHP()
+	}
+
+	return t.tokens
+}
+
+/**
+ * Split tokens into lines.
+ *
+ * Tokens spanning several lines are split into one token per line.  Newlines
+ * are dropped.
+ */
+func SplitLines(tokens []Token) (lines [][]Token) {
+
+	lines = [][]Token{nil}
+
+	for _, token := range tokens {
+		for i, part := range strings.Split(token.Text, "\n") {
+			if i > 0 {
+				lines = append(lines, nil)
+			}
+
+			if part != "" {
+				lineNo := len(lines)
+				lines[lineNo-1] = append(lines[lineNo-1], Token{Type: token.Type, Text: part, Line: lineNo})
+			}
+		}
+	}
+
+	return lines
+}
+
+/**
+ * Everything up to the next PHP open tag.
+ */
+func (t *tokenizer) scanInlineHTML() {
+
+	openTagPos := strings.Index(t.src[t.pos:], "<?")
+	if openTagPos < 0 {
+		t.emit(Plain, len(t.src))
+		return
+	}
+
+	t.emit(Plain, t.pos+openTagPos)
+
+	tagEnd := t.pos + len("<?php")
+	if tagEnd <= len(t.src) && strings.EqualFold(t.src[t.pos:tagEnd], "<?php") {
+		t.emit(Tag, t.pos+len("<?php"))
+	} else if strings.HasPrefix(t.src[t.pos:], "<?=") {
+		t.emit(Tag, t.pos+len("<?="))
+	} else {
+		t.emit(Tag, t.pos+len("<?"))
+	}
+}
+
+/**
+ * Everything up to the next PHP close tag.
+ */
+func (t *tokenizer) scanPHP() {
+
+	for t.pos < len(t.src) {
+		rest := t.src[t.pos:]
+		c := rest[0]
+
+		switch {
+		case strings.HasPrefix(rest, "?>"):
+			t.emit(Tag, t.pos+2)
+			return
+		case isSpace(c):
+			t.emit(Plain, t.scanWhile(t.pos, isSpace))
+		case strings.HasPrefix(rest, "#["):
+			// Attribute.
+			t.emit(Plain, t.pos+2)
+		case c == '#' || strings.HasPrefix(rest, "//"):
+			t.emit(Comment, t.findLineCommentEnd())
+		case strings.HasPrefix(rest, "/*"):
+			t.emit(Comment, t.findAfter("*/", t.pos+2))
+		case c == '\'':
+			t.emit(String, t.findQuotedEnd(c))
+		case c == '"' || c == '`':
+			t.scanInterpolated(t.findQuotedEnd(c))
+		case strings.HasPrefix(rest, "<<<"):
+			t.scanHeredoc()
+		case c == '$' && len(rest) > 1 && isIdentStart(rest[1]):
+			t.emit(Variable, t.scanWhile(t.pos+1, isIdentChar))
+		case isDecimal(c) || (c == '.' && len(rest) > 1 && isDecimal(rest[1])):
+			t.emit(Number, t.findNumberEnd())
+		case isIdentStart(c):
+			end := t.scanWhile(t.pos, isIdentChar)
+			t.emit(t.classifyWord(t.src[t.pos:end]), end)
+		case strings.HasPrefix(rest, "?->"):
+			t.emit(Plain, t.pos+3)
+		case strings.HasPrefix(rest, "->") || strings.HasPrefix(rest, "::"):
+			t.emit(Plain, t.pos+2)
+		default:
+			t.emit(Plain, t.pos+1)
+		}
+	}
+}
+
+/**
+ * Heredoc or nowdoc.
+ *
+ * Example:
+ *   <<<EOT
+ *     Hello $name
+ *     EOT;
+ *
+ * The closing identifier may be indented.
+ */
+func (t *tokenizer) scanHeredoc() {
+
+	i := t.scanWhile(t.pos+3, isBlank)
+
+	quote := byte(0)
+	if i < len(t.src) && (t.src[i] == '\'' || t.src[i] == '"') {
+		quote = t.src[i]
+		i++
+	}
+
+	labelEnd := t.scanWhile(i, isIdentChar)
+	label := t.src[i:labelEnd]
+
+	if label == "" || !isIdentStart(label[0]) {
+		// Not a heredoc after all.
+		t.emit(Plain, t.pos+3)
+		return
+	}
+
+	headerEnd := strings.IndexByte(t.src[labelEnd:], '\n')
+	if headerEnd < 0 {
+		t.emit(String, len(t.src))
+		return
+	}
+
+	bodyStart := labelEnd + headerEnd + 1
+	t.emit(String, bodyStart)
+
+	bodyEnd, closingEnd := t.findHeredocEnd(bodyStart, label)
+
+	if quote == '\'' {
+		t.emit(String, bodyEnd)
+	} else {
+		t.scanInterpolated(bodyEnd)
+	}
+
+	t.emit(String, closingEnd)
+}
+
+/**
+ * Find the closing identifier of a heredoc.
+ *
+ * Returns where the body ends and where the closing identifier ends.
+ */
+func (t *tokenizer) findHeredocEnd(bodyStart int, label string) (bodyEnd, closingEnd int) {
+
+	for lineStart := bodyStart; lineStart < len(t.src); {
+		labelStart := t.scanWhile(lineStart, isBlank)
+		closingEnd = labelStart + len(label)
+
+		isClosing := strings.HasPrefix(t.src[labelStart:], label) && (closingEnd == len(t.src) || !isIdentChar(t.src[closingEnd]))
+		if isClosing {
+			return lineStart, closingEnd
+		}
+
+		nextLine := strings.IndexByte(t.src[lineStart:], '\n')
+		if nextLine < 0 {
+			break
+		}
+
+		lineStart += nextLine + 1
+	}
+
+	return len(t.src), len(t.src)
+}
+
+/**
+ * String with variables in it, up to the given position.
+ *
+ * Example: "Hello $name"
+ */
+func (t *tokenizer) scanInterpolated(end int) {
+
+	for i := t.pos; i < end; {
+		if t.src[i] == '\\' {
+			i += 2
+			continue
+		}
+
+		if t.src[i] == '$' && i+1 < end && isIdentStart(t.src[i+1]) {
+			t.emit(String, i)
+
+			varEnd := t.scanWhile(i+1, isIdentChar)
+			if varEnd > end {
+				varEnd = end
+			}
+
+			t.emit(Variable, varEnd)
+			i = varEnd
+			continue
+		}
+
+		i++
+	}
+
+	t.emit(String, end)
+}
+
+/**
+ * End of a quoted string, after the closing quote.
+ */
+func (t *tokenizer) findQuotedEnd(quote byte) int {
+
+	for i := t.pos + 1; i < len(t.src); i++ {
+		if t.src[i] == '\\' {
+			i++
+		} else if t.src[i] == quote {
+			return i + 1
+		}
+	}
+
+	return len(t.src)
+}
+
+/**
+ * End of a single line comment.
+ *
+ * These end at the end of the line or at a PHP close tag.
+ */
+func (t *tokenizer) findLineCommentEnd() int {
+
+	end := len(t.src)
+
+	if newlinePos := strings.IndexByte(t.src[t.pos:], '\n'); newlinePos >= 0 {
+		end = t.pos + newlinePos
+	}
+
+	if closeTagPos := strings.Index(t.src[t.pos:end], "?>"); closeTagPos >= 0 {
+		end = t.pos + closeTagPos
+	}
+
+	return end
+}
+
+/**
+ * Position just after the next occurrence of the given text.
+ */
+func (t *tokenizer) findAfter(text string, from int) int {
+
+	if from > len(t.src) {
+		return len(t.src)
+	}
+
+	if textPos := strings.Index(t.src[from:], text); textPos >= 0 {
+		return from + textPos + len(text)
+	}
+
+	return len(t.src)
+}
+
+/**
+ * End of a number.
+ *
+ * Examples: 42, 0x1A, 0b11, 1_000, 3.14, .5, 1e-3
+ */
+func (t *tokenizer) findNumberEnd() int {
+
+	rest := t.src[t.pos:]
+
+	if len(rest) > 1 && rest[0] == '0' && strings.IndexByte("xXbBoO", rest[1]) >= 0 {
+		return t.scanWhile(t.pos+2, isHexDigit)
+	}
+
+	i := t.scanWhile(t.pos, isDigit)
+
+	if i < len(t.src) && t.src[i] == '.' {
+		i = t.scanWhile(i+1, isDigit)
+	}
+
+	if i+1 < len(t.src) && (t.src[i] == 'e' || t.src[i] == 'E') {
+		exponentStart := i + 1
+		if t.src[exponentStart] == '+' || t.src[exponentStart] == '-' {
+			exponentStart++
+		}
+
+		if exponentStart < len(t.src) && isDecimal(t.src[exponentStart]) {
+			i = t.scanWhile(exponentStart, isDigit)
+		}
+	}
+
+	return i
+}
+
+/**
+ * Keyword, constant, or some other name?
+ */
+func (t *tokenizer) classifyWord(word string) TokenType {
+
+	lowercaseWord := strings.ToLower(word)
+
+	if memberOperators[t.lastSignificant.Text] {
+		return Identifier
+	} else if keywords[lowercaseWord] {
+		return Keyword
+	} else if constants[lowercaseWord] {
+		return Constant
+	}
+
+	return Identifier
+}
+
+/**
+ * Add a token for the source code up to the given position.
+ */
+func (t *tokenizer) emit(tokenType TokenType, end int) {
+
+	if end <= t.pos {
+		return
+	}
+
+	token := Token{Type: tokenType, Text: t.src[t.pos:end], Line: t.line}
+	t.tokens = append(t.tokens, token)
+
+	t.line += strings.Count(token.Text, "\n")
+	t.pos = end
+
+	if strings.TrimSpace(token.Text) != "" {
+		t.lastSignificant = token
+	}
+}
+
+/**
+ * Position of the first character that does not match.
+ */
+func (t *tokenizer) scanWhile(from int, matches func(byte) bool) int {
+
+	i := from
+	for i < len(t.src) && matches(t.src[i]) {
+		i++
+	}
+
+	return i
+}
+
+func isSpace(c byte) bool {
+
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isBlank(c byte) bool {
+
+	return c == ' ' || c == '\t'
+}
+
+func isDecimal(c byte) bool {
+
+	return '0' <= c && c <= '9'
+}
+
+/**
+ * Digits may be separated by underscores.  Example: 1_000
+ */
+func isDigit(c byte) bool {
+
+	return isDecimal(c) || c == '_'
+}
+
+func isHexDigit(c byte) bool {
+
+	return isDigit(c) || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+/**
+ * Non-ASCII bytes are allowed in PHP names.
+ */
+func isIdentStart(c byte) bool {
+
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c == '_' || c >= 0x80
+}
+
+func isIdentChar(c byte) bool {
+
+	return isIdentStart(c) || isDecimal(c)
+}
+
+func toSet(words ...string) map[string]bool {
+
+	set := make(map[string]bool)
+	for _, word := range words {
+		set[word] = true
+	}
+
+	return set
+}
@@ -0,0 +1,133 @@
+package php
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+/**
+ * Tests for Tokenize().
+ */
+func TestTokenize(t *testing.T) {
+
+	src := `<h1><?php echo $title; ?></h1>
+<?php
+// Greet.
+$list->list(0x1F, 1_000, 3.14e-2, TRUE);
+$greeting = "Hello $name";
+/* Multi
+   line */
+$text = <<<EOT
+  Dear $name,
+  EOT;
+$raw = <<<'EOT'
+$notAVariable
+EOT;
+`
+
+	tokens := Tokenize(src)
+
+	expected := []Token{
+		{Plain, "<h1>", 1},
+		{Tag, "<?php", 1},
+		{Keyword, "echo", 1},
+		{Variable, "$title", 1},
+		{Tag, "?>", 1},
+		{Plain, "</h1>\n", 1},
+		{Tag, "<?php", 2},
+		{Comment, "// Greet.", 3},
+		{Variable, "$list", 4},
+		{Identifier, "list", 4},
+		{Number, "0x1F", 4},
+		{Number, "1_000", 4},
+		{Number, "3.14e-2", 4},
+		{Constant, "TRUE", 4},
+		{Variable, "$greeting", 5},
+		{String, `"Hello `, 5},
+		{Variable, "$name", 5},
+		{String, `"`, 5},
+		{Comment, "/* Multi\n   line */", 6},
+		{Variable, "$text", 8},
+		{String, "<<<EOT\n", 8},
+		{String, "  Dear ", 9},
+		{Variable, "$name", 9},
+		{String, ",\n", 9},
+		{String, "  EOT", 10},
+		{Variable, "$raw", 11},
+		{String, "<<<'EOT'\n", 11},
+		{String, "$notAVariable\n", 12},
+		{String, "EOT", 13},
+	}
+
+	significantTokens := []Token{}
+	for _, token := range tokens {
+		if token.Type != Plain || strings.Trim(token.Text, " \n;=(),->") != "" {
+			significantTokens = append(significantTokens, token)
+		}
+	}
+
+	if len(expected) != len(significantTokens) {
+		t.Fatalf("Expected %d tokens, got %d: %v", len(expected), len(significantTokens), significantTokens)
+	}
+
+	for i := range expected {
+		if expected[i] != significantTokens[i] {
+			t.Errorf("Expected %v, got %v", expected[i], significantTokens[i])
+		}
+	}
+
+	// Nothing is lost.
+	var rebuilt bytes.Buffer
+	for _, token := range tokens {
+		rebuilt.WriteString(token.Text)
+	}
+
+	if src != rebuilt.String() {
+		t.Error("Tokens do not add up to the source code.")
+	}
+}
+
+/**
+ * Tests for SplitLines().
+ */
+func TestSplitLines(t *testing.T) {
+
+	lines := SplitLines(Tokenize("<?php\n/* Foo\nbar */ $baz;"))
+
+	if 3 != len(lines) {
+		t.Fatalf("Expected three lines, got: %v", lines)
+	}
+
+	expected := Token{Comment, "bar */", 3}
+	if 4 != len(lines[2]) || expected != lines[2][0] {
+		t.Errorf("Unexpected third line: %v", lines[2])
+	}
+}
+
+/**
+ * Tests for Tokenize() with a large file.
+ *
+ * Tokenizing should take time in proportion to the size of the file.  Each
+ * number and open tag used to cost as much as the rest of the file.
+ */
+func TestTokenizeLargeFile(t *testing.T) {
+
+	var src bytes.Buffer
+	for i := 0; i < 50000; i++ {
+		src.WriteString("<?php $total = 0x1F + 42 * 3.5; ?>\n")
+	}
+
+	start := time.Now()
+	tokens := Tokenize(src.String())
+	elapsed := time.Since(start)
+
+	if len(tokens) == 0 || tokens[len(tokens)-1].Line != 50000 {
+		t.Errorf("Unexpected tokens at the end: %v", tokens[len(tokens)-3:])
+	}
+
+	if elapsed > 10*time.Second {
+		t.Errorf("Tokenizing %d bytes took %s", src.Len(), elapsed)
+	}
+}
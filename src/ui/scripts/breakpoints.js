@@ -34,10 +34,14 @@ var existingBreakpointList = new Map()
  * the *parent* of the ".line__number" element.  The breakpoint Id is also
  * stored as a data attribute of this parent using an attribute name of
  * "breakpoint-id".
+ *
+ * Lines that cannot carry a breakpoint have the "line--not-executable" class.
+ * Clicking these does not create a breakpoint.  Any existing breakpoint on such
+ * a line can still be removed.
  */
 function setupTrigger () {
   jQuery('.tab').on('click', '.tab-content', function (event) {
-    var hasClickedLineNoWOBreakpoint = event.target.classList.contains('line__number') && !jQuery(event.target).parent('.line.breakpoint').length
+    var hasClickedLineNoWOBreakpoint = event.target.classList.contains('line__number') && !jQuery(event.target).parent('.line.breakpoint, .line--not-executable').length
     var hasClickedLineNoWBreakpoint = event.target.classList.contains('line__number') && jQuery(event.target).parent('.line.breakpoint').length
     var hasNothingToDoWBreakpoint = !(hasClickedLineNoWOBreakpoint || hasClickedLineNoWBreakpoint)
 
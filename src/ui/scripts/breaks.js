@@ -78,6 +78,19 @@ function redrawCurrent () {
   displayNew(filenameOfLastBreak, lineNoOfLastBreak)
 }
 
+/**
+ * Redraw the break if it is in the given file.
+ *
+ * Needed after the file's content has been replaced.
+ *
+ * @param string filename
+ */
+function redrawIn (filename) {
+  if (filename === filenameOfLastBreak) {
+    redrawCurrent()
+  }
+}
+
 /**
  * Remove break from display.
  *
@@ -179,4 +192,4 @@ function isInViewport (element) {
   return isInViewport
 }
 
-export { update, removePrevious }
+export { update, redrawIn, removePrevious, scrollLineIntoView }
@@ -10,7 +10,7 @@ import * as server from './server-commands.js'
  * Prepare handlers for continuation buttons.
  *
  * Setup click handlers for continuation buttons of the following
- * commands: step_over, step_into, step_out, and run.
+ * commands: step_over, step_into, step_out, run, and break.
  */
 function setupContinuationControls () {
   var commandsNSelectors = {
@@ -18,6 +18,7 @@ function setupContinuationControls () {
     step_into: '[name="button--step-in"]',
     step_out: '[name="button--step-out"]',
     run: '[name="button--run"]',
+    break: '[name="button--pause"]',
     continue: '[name="button--continue"]',
     stop: '[name="button--stop"]',
     on: '[name="button--on"]',
@@ -82,10 +83,23 @@ function disable () {
 }
 
 /**
- * Enable all buttons.
+ * Enable all buttons except the pause button.
+ *
+ * There is nothing to pause when execution has already stopped.
  */
 function enable () {
   jQuery('.button--control').attr('disabled', false)
+  jQuery('[name="button--pause"]').attr('disabled', true)
+}
+
+/**
+ * Only allow pausing while the script is running.
+ *
+ * No other command is answered by the debugger engine until execution stops.
+ */
+function enablePauseOnly () {
+  disable()
+  jQuery('[name="button--pause"]').attr('disabled', false)
 }
 
-export { setupContinuationControls, setupStateControl, toggleOnOffbuttons, disable, enable }
+export { setupContinuationControls, setupStateControl, toggleOnOffbuttons, disable, enable, enablePauseOnly }
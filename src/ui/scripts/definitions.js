@@ -0,0 +1,72 @@
+/**
+ * @file
+ * Go to definition.
+ *
+ * Function names in the stack trace lead to their declarations.  So do names
+ * in source code when clicked while holding Ctrl (Cmd on Mac).  Declarations
+ * come from /api/symbols.
+ */
+
+import * as feedback from './feedback.js'
+import * as navigation from './navigation.js'
+
+/**
+ * Make names clickable.
+ */
+function setup () {
+  jQuery('.stacktrace > .traces').on('click', '.call-detail__where', function (event) {
+    // Xdebug uses "->" for instance methods.  Declarations always use "::".
+    follow(jQuery(this).text().replace('->', '::'))
+
+    return false
+  })
+
+  jQuery('#tab-content-wrapper').on('click', '.token--identifier', function (event) {
+    if (!event.ctrlKey && !event.metaKey) {
+      return
+    }
+
+    follow(jQuery(this).text())
+
+    return false
+  })
+}
+
+/**
+ * Open the declaration of the given name.
+ *
+ * When there are several, the first one is opened and the rest are listed.
+ *
+ * @param string name
+ *    Function, method, class, or constant name.  Example: App\User::save
+ */
+function follow (name) {
+  jQuery.getJSON('/api/symbols', { name }).done(function (definitions) {
+    if (definitions.length === 0) {
+      feedback.show(`No declaration found for ${escape(name)}.`)
+      return
+    }
+
+    navigation.openAt(definitions[0].path, definitions[0].line)
+
+    if (definitions.length > 1) {
+      const others = definitions.slice(1).map(definition => `${definition.path}:${definition.line}`).join(', ')
+      feedback.show(`${escape(name)} is also declared in ${escape(others)}.`)
+    }
+  }).fail(function (jqXHR, textStatus, errorThrown) {
+    feedback.show(`Failed to look up ${escape(name)}.  More in console log.`)
+    console.log(jqXHR)
+  })
+}
+
+/**
+ * HTML-escape text for feedback messages.
+ *
+ * @param string text
+ * @return string
+ */
+function escape (text) {
+  return jQuery('<span></span>').text(text).html()
+}
+
+export { setup }
@@ -0,0 +1,82 @@
+/**
+ * @file
+ * Deal with files the DBGp engine runs a different copy of.
+ *
+ * The Footle server warns when the local copy of a file differs from the
+ * engine's copy.  The file's tab then gets a banner that lets us switch
+ * between the two copies.  Breaks make more sense in the engine's copy.
+ */
+
+import * as breakpoint from './breakpoints.js'
+import * as breaks from './breaks.js'
+import * as feedback from './feedback.js'
+import * as source from './source.js'
+import * as tab from './tabs.js'
+
+/**
+ * Warn about a file that differs from the engine's copy.
+ *
+ * The file is opened if needed.
+ *
+ * @param string filepath
+ *    Relative filepath.
+ * @param string notice
+ */
+function warn (filepath, notice) {
+  tab.add(filepath, function () {
+    const tabContent = tab.getContentElementForFile(filepath)
+
+    jQuery('.divergence', tabContent).remove()
+    jQuery('.file-content', tabContent).before(`<div class="divergence uk-alert uk-alert-warning"><p>${notice} <button class="divergence__toggle uk-button uk-button-mini" type="button"></button></p></div>`)
+  })
+}
+
+/**
+ * Switch between the two copies when the banner's button is clicked.
+ */
+function setup () {
+  jQuery('#tab-content-wrapper').on('click', '.divergence__toggle', function () {
+    const tabContent = jQuery(this).closest('.tab-content')
+    const filepath = tabContent.data('filepath')
+    const isShowingEngineCopy = tabContent.hasClass('tab-content--engine-copy')
+
+    display(filepath, !isShowingEngineCopy)
+  })
+}
+
+/**
+ * Drop all banners and return to the local copies.
+ *
+ * The engine's copies are gone once the debugging session ends.
+ */
+function forget () {
+  jQuery('.tab-content--engine-copy').each(function () {
+    source.update(jQuery(this).data('filepath'))
+  })
+
+  jQuery('.divergence').remove()
+}
+
+/**
+ * Display either copy of a file in its tab.
+ *
+ * @param string filepath
+ * @param bool isEngineCopy
+ */
+function display (filepath, isEngineCopy) {
+  const tabContent = tab.getContentElementForFile(filepath)
+  const url = (isEngineCopy ? '/engine-file/' : '/formatted-file/') + filepath
+
+  jQuery.get(url, function (data) {
+    jQuery('.file-content', tabContent).html(data)
+    tabContent.toggleClass('tab-content--engine-copy', isEngineCopy)
+
+    breakpoint.highlightFile(filepath)
+    breaks.redrawIn(filepath)
+  }).fail(function (jqXHR) {
+    feedback.show(`Failed to fetch the ${isEngineCopy ? "DBGp engine's" : 'local'} copy of ${filepath}.  More in console log.`)
+    console.log(jqXHR)
+  })
+}
+
+export { forget, setup, warn }
@@ -1,25 +1,39 @@
 
 /**
  * @file
- * Make the raw file list more usable.
+ * File browser and recently opened files.
  *
- * Prepend breadcrumb and link to parent directory to file list.  Also apply
- * styling to improve the appearance of the file list.
+ * The file browser is a tree of the codebase fetched from /api/tree.  Files
+ * hidden through .gitignore or Footle's -exclude option are not listed.
  */
 
 import * as breakpoint from './breakpoints.js'
+import * as feedback from './feedback.js'
 import * as tab from './tabs.js'
 import RecentFiles from './recent-files.js'
 
 /**
  * Setup file browser.
  *
- * @param object ignoredEvent
- *    Optional, it is okay to call a Javascript function without its arguments.
+ * The codebase is listed as a tree.  Directories are fetched from the server
+ * as they are expanded.
  */
-function setup (ignoredEvent) {
-  setupFileLinks()
-  improveFileListUX()
+function setup () {
+  const browser = jQuery('.file-browser')
+
+  browser.on('click', '.file-tree__link--dir', function (event) {
+    toggleDir(jQuery(this).parent('.file-tree__item'))
+
+    return false
+  })
+
+  browser.on('click', '.file-tree__link--file', function (event) {
+    tab.add(jQuery(this).parent('.file-tree__item').data('path'), postFileOpenTasks)
+
+    return false
+  })
+
+  loadDir('', browser)
 
   const recentFiles = new RecentFiles(localStorage).get()
   displayRecentFiles(recentFiles)
@@ -41,127 +55,80 @@ function setupRecent () {
 }
 
 /**
- * Setup click handler on all *file* links.
- *
- * These file links are found as part of the file browser and recent file list.
+ * Expand or collapse a directory.
  *
- * When a directory name has been clicked, load its file list.  When a filename
- * has been clicked, open that file in a new tab.
+ * A directory's content is fetched the first time it is expanded.
  *
- * Notice that directory names end in a slash, filenames do not.  Only exception
- * is the parent directory link.
- */
-function setupFileLinks () {
-  jQuery('pre', window.file_browser.document).off('click', 'a:not([href$="/"])').on('click', 'a:not([href$="/"])', function (event) {
-    const relativeFilepath = this.pathname.replace('/files/', '')
-    tab.add(relativeFilepath, postFileOpenTasks)
-
-    return false
-  })
-}
-
-/**
- * Things we want to do once a file is opened in a tab.
- *
- * At the moment, we are:
- * - Updating the recently used file list.
- * - Redrawing any existing breakpoints.
- */
-function postFileOpenTasks (filename, filepath) {
-  updateRecent(filepath)
-  breakpoint.highlightFile(filepath)
-}
-
-/**
- * Add current dir name and parent dir link for better UX.
+ * @param object dirItem
+ *    jQuery object for the directory's list item.
  */
-function improveFileListUX () {
-  const hasFinishedSetup = window.file_browser.document.querySelector('body > ul.breadcrumb--file-path:first-child')
-  if (hasFinishedSetup) {
-    return
-  }
-  // Hide file list until stylesheet is ready and all modification is complete.
-  jQuery('body', window.file_browser.document).hide()
-
-  // Load stylesheet for file links which are inside the file browser iframe.
-  // Show the file list once the stylesheet has been loaded.
-  jQuery('head', window.file_browser.document).append(jQuery('<link rel="stylesheet" href="/style/css/ui.css" />').on('load', function () {
-    jQuery('body', window.file_browser.document).addClass('file-list')
-    jQuery('body', window.file_browser.document).show()
-  }))
-
-  // Add breadcrumb...
-  var crumbs = prepareCrumbs(document.URL, window.file_browser.document.URL)
-  if (crumbs.length <= 1) {
-    // No breadcrumb when we are listing files at the docroot.  Note that
-    // the docroot is always the first crumb.
+function toggleDir (dirItem) {
+  if (dirItem.hasClass('file-tree__item--expanded')) {
+    dirItem.removeClass('file-tree__item--expanded')
     return
   }
 
-  var breadcrumb = prepareBreadcrumbMarkup(crumbs)
-  jQuery('pre', window.file_browser.document).before(breadcrumb)
+  dirItem.addClass('file-tree__item--expanded')
 
-  // ... and then a file link pointing to the parent directory.
-  var parentDirURL = crumbs[crumbs.length - 2].url
-  var parentDirLink = '<a class="link--parent-dir" href="' + parentDirURL + '/">..</a>&#10;'
-  jQuery('pre', window.file_browser.document).prepend(parentDirLink)
+  if (!dirItem.data('is-loaded')) {
+    dirItem.data('is-loaded', true)
+    loadDir(dirItem.data('path'), dirItem)
+  }
 }
 
 /**
- * Prepare items for breadcrumb for the current path.
- *
- * @param string siteURL
- *    Example: https://example.net/
- * @param string currentDirURL
- *    Example: https://example.net/foo/bar/
+ * Fetch a directory listing and append it to the given element.
  *
- * @return Array
- *    Array of objects.  Each object has two keys: dir, url.
+ * @param string dirPath
+ *    Relative path of the directory.  Empty for the codebase itself.
+ * @param object container
+ *    jQuery object.
  */
-function prepareCrumbs (siteURL, currentDirURL) {
-  var relativePathParts = currentDirURL.replace(siteURL, '').split('/').filter(dir => dir !== '')
-  var crumbs = []
-
-  var pathPartsCumulative = []
-  for (const pathPart of relativePathParts) {
-    pathPartsCumulative.push(pathPart)
-    crumbs.push({ dir: pathPart, url: siteURL + pathPartsCumulative.join('/') })
-  }
-
-  return crumbs
+function loadDir (dirPath, container) {
+  jQuery.getJSON('/api/tree', { path: dirPath }).done(function (dir) {
+    container.append(prepareTreeMarkup(dir.children || []))
+  }).fail(function (jqXHR, textStatus, errorThrown) {
+    container.removeData('is-loaded').removeClass('file-tree__item--expanded')
+    feedback.show(`Failed to list ${dirPath || 'the codebase'}.  More in console log.`)
+    console.log(jqXHR)
+  })
 }
 
 /**
- * Prepare HTML markup for breadcrumb from given crumbs.
+ * Prepare HTML markup for the content of a directory.
  *
- * @param Array crumbList
- *    Array of objects.  Each object has two keys: dir, url that represents a
- *    directory path.
+ * @param Array entries
+ *    Objects with name, path, and type keys as returned by /api/tree.
  *
- * @return string
- *    HTML markup for breadcrumb.
+ * @return object
+ *    jQuery object for the list.
  */
-function prepareBreadcrumbMarkup (crumbs) {
-  if (crumbs.length <= 0) {
-    return ''
-  }
+function prepareTreeMarkup (entries) {
+  const list = jQuery('<ul class="file-tree"></ul>')
 
-  // Clone crumbs as we do not want side effects.
-  var crumbList = JSON.parse(JSON.stringify(crumbs))
+  for (const entry of entries) {
+    const item = jQuery(`<li class="file-tree__item file-tree__item--${entry.type}"><a href="#" class="file-tree__link file-tree__link--${entry.type}"></a></li>`)
 
-  // Rename the first crumb from "files" to a more meaningful "Docroot".
-  crumbList[0].dir = 'Docroot'
+    // Filenames are set as text so that they are never mistaken for markup.
+    item.children('a').text(entry.type === 'dir' ? entry.name + '/' : entry.name)
+    item.data('path', entry.path)
 
-  var breadcrumb = '<ul class="breadcrumb--file-path uk-breadcrumb">'
-  var lastCrumb = crumbList.pop()
-
-  for (const crumb of crumbList) {
-    breadcrumb += '<li class="link--dir"><a href="' + crumb.url + '">' + crumb.dir + '</a></li>'
+    list.append(item)
   }
 
-  breadcrumb += '<li class="label--dir uk-active"><span>' + lastCrumb.dir + '</span></li></ul>'
+  return list
+}
 
-  return breadcrumb
+/**
+ * Things we want to do once a file is opened in a tab.
+ *
+ * At the moment, we are:
+ * - Updating the recently used file list.
+ * - Redrawing any existing breakpoints.
+ */
+function postFileOpenTasks (filename, filepath) {
+  updateRecent(filepath)
+  breakpoint.highlightFile(filepath)
 }
 
 /**
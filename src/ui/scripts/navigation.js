@@ -0,0 +1,55 @@
+/**
+ * @file
+ * Open files at a given line.
+ *
+ * Used by search results and "go to definition".
+ */
+
+import * as breakpoint from './breakpoints.js'
+import * as breaks from './breaks.js'
+import * as tab from './tabs.js'
+
+/**
+ * Open a file in its tab, bring a line into view, and flash it.
+ *
+ * @param string filepath
+ *    Relative filepath.
+ * @param int lineNo
+ */
+function openAt (filepath, lineNo) {
+  tab.add(filepath, function () {
+    showLine(filepath, lineNo)
+  })
+}
+
+/**
+ * Bring a line of an open file into view and flash it.
+ *
+ * @param string filepath
+ * @param int lineNo
+ */
+function showLine (filepath, lineNo) {
+  const tabNavElement = tab.hasFileMapping(filepath)
+  if (!tabNavElement) {
+    return
+  }
+
+  if (!tabNavElement.hasClass('uk-active')) {
+    tabNavElement.click()
+  }
+
+  breakpoint.highlightFile(filepath)
+
+  const tabContent = tab.getContentElementForFile(filepath)
+  const lineElement = jQuery('.line__' + lineNo, tabContent)
+  if (lineElement.length === 0) {
+    return
+  }
+
+  breaks.scrollLineIntoView(lineElement)
+
+  lineElement.addClass('line--found')
+  setTimeout(() => lineElement.removeClass('line--found'), 2000)
+}
+
+export { openAt }
@@ -0,0 +1,123 @@
+/**
+ * @file
+ * Outline of PHP files.
+ *
+ * Each file tab has a sidebar listing the file's classes, methods, functions,
+ * and constants as fetched from /outline/PATH.  Clicking a name brings its
+ * declaration into view.  Functions and methods also offer a button to set a
+ * breakpoint at their entry, i.e. the first line of their body that can carry
+ * a breakpoint.
+ */
+
+import * as server from './server-commands.js'
+
+/**
+ * Visibility markers as in UML.
+ */
+const visibilityMarkers = { public: '+', protected: '#', private: '-' }
+
+/**
+ * Setup clicks on outline items.
+ */
+function setup () {
+  const tabContentWrapper = jQuery('#tab-content-wrapper')
+
+  tabContentWrapper.on('click', '.outline__link', function (event) {
+    const tabContent = jQuery(this).closest('.tab-content')
+    showLine(tabContent, jQuery(this).parent('.outline__item').data('line'))
+
+    return false
+  })
+
+  tabContentWrapper.on('click', '.outline__breakpoint', function (event) {
+    const filepath = jQuery(this).closest('.tab-content').data('filepath')
+    server.sendCommand('breakpoint_set', [filepath, jQuery(this).parent('.outline__item').data('entry-line')])
+
+    return false
+  })
+}
+
+/**
+ * Fetch and display the outline of a file shown in a tab.
+ *
+ * @param string filepath
+ *    Relative filepath.
+ */
+function load (filepath) {
+  const outlineElement = jQuery(document.getElementById('body-of-' + filepath)).children('.outline')
+  if (outlineElement.length === 0) {
+    return
+  }
+
+  jQuery.getJSON('/outline/' + filepath).done(function (outline) {
+    outlineElement.empty()
+
+    if (outline.length) {
+      outlineElement.append(prepareOutlineMarkup(outline))
+    }
+  }).fail(function (jqXHR, textStatus, errorThrown) {
+    // The file itself is still usable.  So no fuss.
+    console.log(jqXHR)
+  })
+}
+
+/**
+ * Prepare HTML markup for a list of outline items.
+ *
+ * Names are set as text so that they are never mistaken for markup.
+ *
+ * @param Array items
+ *    Objects with name, kind, line, visibility, entryLine, and members keys as
+ *    returned by /outline/PATH.
+ * @return object
+ *    jQuery object for the list.
+ */
+function prepareOutlineMarkup (items) {
+  const list = jQuery('<ul class="outline__list"></ul>')
+
+  for (const item of items) {
+    const element = jQuery(`<li class="outline__item outline__item--${item.kind}"><a href="#" class="outline__link"></a></li>`)
+    const link = element.children('.outline__link')
+
+    if (item.visibility) {
+      link.append(jQuery('<span class="outline__visibility"></span>').text(visibilityMarkers[item.visibility] || ''))
+    }
+    link.append(document.createTextNode(item.name))
+    link.attr('title', `${item.kind} ${item.fullName}, line ${item.line}`)
+    element.data('line', item.line)
+
+    if (item.entryLine) {
+      element.append('<button type="button" class="outline__breakpoint" title="Set breakpoint at entry"></button>')
+      element.data('entry-line', item.entryLine)
+    }
+
+    if (item.members) {
+      element.append(prepareOutlineMarkup(item.members))
+    }
+
+    list.append(element)
+  }
+
+  return list
+}
+
+/**
+ * Bring a line of a tab into view and flash it.
+ *
+ * @param object tabContent
+ *    jQuery object for the tab's content.
+ * @param int lineNo
+ */
+function showLine (tabContent, lineNo) {
+  const lineElement = jQuery('.line__' + lineNo, tabContent)
+  if (lineElement.length === 0) {
+    return
+  }
+
+  lineElement.get(0).scrollIntoView({ block: 'center' })
+
+  lineElement.addClass('line--found')
+  setTimeout(() => lineElement.removeClass('line--found'), 2000)
+}
+
+export { load, setup }
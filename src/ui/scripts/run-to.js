@@ -0,0 +1,69 @@
+/**
+ * @file
+ * Run-to-cursor context menu for lines of source code.
+ *
+ * Right clicking any line of a file displayed in a tab brings up a context
+ * menu.  Its "Run to here" action carries on with execution until that line.
+ */
+
+import * as server from './server-commands.js'
+
+/**
+ * Setup the context menu for source code lines.
+ *
+ * The chosen filepath and line number are saved as data attributes of the
+ * menu element until an action is picked.
+ */
+function setupContextMenu () {
+  const menu = jQuery('.context-menu--line')
+
+  jQuery('.tab').on('contextmenu', '.tab-content .line', function (event) {
+    const filepath = jQuery(this).closest('.tab-content').data('filepath')
+    const lineNo = jQuery('.line__number', this).text()
+
+    if (!filepath || !lineNo) {
+      return
+    }
+
+    event.preventDefault()
+
+    // "Run to here" only makes sense when execution has stopped at a break.
+    const isAtBreak = !jQuery('[name="button--run"]').prop('disabled')
+    jQuery('.context-menu__item[data-action="run_to"]', menu).parent().toggleClass('uk-disabled', !isAtBreak)
+
+    menu.data({ filepath: filepath, lineNo: lineNo })
+      .css({ top: event.pageY, left: event.pageX })
+      .removeClass('uk-hidden')
+  })
+
+  menu.on('click', '.context-menu__item', function (event) {
+    event.preventDefault()
+
+    const isDisabled = jQuery(this).parent().hasClass('uk-disabled')
+    if (!isDisabled) {
+      server.sendCommand(jQuery(this).data('action'), [menu.data('filepath'), menu.data('lineNo')])
+    }
+
+    hide()
+  })
+
+  // Any click elsewhere or the Escape key closes the menu.
+  jQuery(document).on('click', function (event) {
+    if (!jQuery(event.target).closest('.context-menu--line').length) {
+      hide()
+    }
+  }).on('keydown', function (event) {
+    if (event.key === 'Escape') {
+      hide()
+    }
+  })
+}
+
+/**
+ * Hide the context menu.
+ */
+function hide () {
+  jQuery('.context-menu--line').addClass('uk-hidden')
+}
+
+export { setupContextMenu }
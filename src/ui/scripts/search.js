@@ -0,0 +1,119 @@
+/**
+ * @file
+ * Full-text search over the codebase.
+ *
+ * Results arrive one by one from /api/search as Server sent events.  Clicking
+ * a result opens the file at the matching line.
+ */
+
+import * as navigation from './navigation.js'
+
+/**
+ * The ongoing search, if any.
+ */
+let searchStream = null
+
+/**
+ * Setup the search form and its results.
+ */
+function setup () {
+  jQuery('.code-search__form').on('submit', function (event) {
+    event.preventDefault()
+
+    start(this.elements.q.value, this.elements.regex.checked, this.elements.case.checked)
+  })
+
+  jQuery('.code-search__results').on('click', '.code-search__link', function (event) {
+    const result = jQuery(this).parent('.code-search__result')
+    navigation.openAt(result.data('path'), result.data('line'))
+
+    return false
+  })
+}
+
+/**
+ * Start a new search.
+ *
+ * Any ongoing search is abandoned.
+ *
+ * @param string query
+ * @param bool isRegexp
+ * @param bool isCaseSensitive
+ */
+function start (query, isRegexp, isCaseSensitive) {
+  if (searchStream) {
+    searchStream.close()
+  }
+
+  const results = jQuery('.code-search__results').empty()
+  const status = jQuery('.code-search__status')
+
+  if (!query) {
+    status.text('')
+    return
+  }
+
+  status.text('Searching…')
+
+  const params = { q: query }
+  if (isRegexp) {
+    params.regex = 1
+  }
+  if (isCaseSensitive) {
+    params.case = 1
+  }
+
+  const stream = new EventSource('/api/search?' + jQuery.param(params))
+  searchStream = stream
+
+  jQuery(stream).on('match', function (event) {
+    results.append(prepareResultMarkup(JSON.parse(event.originalEvent.data)))
+  })
+
+  jQuery(stream).on('done', function (event) {
+    const summary = JSON.parse(event.originalEvent.data)
+    const moreResults = summary.truncated ? '  There may be more.' : ''
+
+    status.text(`${summary.matches} matching lines in ${summary.files} files.${moreResults}`)
+    stream.close()
+  })
+
+  // The server refuses invalid queries such as broken regular expressions
+  // before streaming anything.  EventSource would otherwise keep retrying.
+  jQuery(stream).on('error', function (event) {
+    stream.close()
+    status.text('Search failed.  Check the search text.')
+  })
+}
+
+/**
+ * Prepare HTML markup for a search result.
+ *
+ * Snippets are set as text so that they are never mistaken for markup.
+ *
+ * @param object result
+ *    Object with path, line, snippet, start, and end keys as returned by
+ *    /api/search.
+ * @return object
+ *    jQuery object for the list item.
+ */
+function prepareResultMarkup (result) {
+  const item = jQuery('<li class="code-search__result"><a href="#" class="code-search__link"><span class="code-search__location"></span><code class="code-search__snippet"></code></a></li>')
+
+  const snippet = Array.from(result.snippet)
+  const before = snippet.slice(0, result.start).join('')
+  const match = snippet.slice(result.start, result.end).join('')
+  const after = snippet.slice(result.end).join('')
+
+  item.find('.code-search__location').text(`${result.path}:${result.line}`)
+  item.find('.code-search__snippet').append(
+    document.createTextNode(before),
+    jQuery('<mark class="code-search__match"></mark>').text(match),
+    document.createTextNode(after)
+  )
+  item.data({ path: result.path, line: result.line })
+
+  return item
+}
+
+export { setup }
@@ -5,6 +5,14 @@
  */
 
 import * as feedback from './feedback.js'
+import * as websocket from './websocket.js'
+
+/**
+ * Identifies this browser tab to the Footle server.
+ *
+ * Responses to our commands come back tagged with this ID.
+ */
+const clientId = Math.random().toString(36).substring(2, 10)
 
 /**
  * Send command to the Footle server.
@@ -16,15 +24,22 @@ import * as feedback from './feedback.js'
  *
  * Example *Footle* command: breakpoint_set index.php 16
  *
- * All commands have a fixed response when successful: "Got it."
+ * Commands go over the WebSocket when it is open.  Otherwise they are POSTed.
+ * POSTed commands have a fixed response when successful: "Got it."
  */
 function sendCommand (command, args) {
   args = args || []
 
   var footleCommand = [command].concat(args).join(' ')
 
+  if (websocket.isOpen()) {
+    websocket.send(footleCommand)
+    return
+  }
+
   jQuery.post('steering-wheel', {
-    cmd: footleCommand
+    cmd: footleCommand,
+    client: clientId
   }).done(function (data, textStatus, jqXHR) {
     const cmdHasSucceeded = (data !== 'Got it.')
     if (cmdHasSucceeded) {
@@ -38,4 +53,14 @@ function sendCommand (command, args) {
     })
 }
 
-export { sendCommand }
+/**
+ * Is this a response to one of our own commands?
+ *
+ * @param object msg
+ * @return bool
+ */
+function isOwnResponse (msg) {
+  return msg.Origin === `http:${clientId}`
+}
+
+export { isOwnResponse, sendCommand }
@@ -5,6 +5,7 @@
  */
 
 import * as breakpoint from './breakpoints.js'
+import * as outline from './outline.js'
 import * as tab from './tabs.js'
 import * as util from './common.js'
 
@@ -25,6 +26,9 @@ function update (filepath) {
   var fileContentElement = jQuery('.file-content', fileTabElement)
 
   jQuery(fileContentElement).load(formattedFilepath, function () {
+    // Back to the local copy.  @see divergence.js
+    jQuery(fileTabElement).removeClass('tab-content--engine-copy')
+
     var fileTabLinkElementSelector = '#' + util.escapeSelector(filepath)
     jQuery(fileTabLinkElementSelector).addClass('uk-animation-slide-top')
 
@@ -35,6 +39,8 @@ function update (filepath) {
 
     breakpoint.highlightFile(filepath)
   })
+
+  outline.load(filepath)
 }
 
 export { update }
@@ -1,6 +1,8 @@
 /**
  * @file
  * Stack trace display related functions.
+ *
+ * @see definitions.js
  */
 
 /**
@@ -20,8 +22,11 @@ function display (callStack) {
     var filename = callStack[stackIndex].Filename
     var lineNo = callStack[stackIndex].LineNo
 
+    // Names can be followed to their declarations.  {main} has none.
+    var whereMarkup = (where === '{main}') ? where : '<a href="#" class="call-detail__where" title="Go to definition">' + where + '</a>'
+
     var traceMarkup = '<tr class="call-detail">' +
-                        '<td>' + where + '</td>' +
+                        '<td>' + whereMarkup + '</td>' +
                         '<td class="filename">' + filename + '</td>' +
                         '<td>' + lineNo + '</td>' +
                       '</tr>'
@@ -9,6 +9,7 @@
 
 import * as server from './server-commands.js'
 import * as feedback from './feedback.js'
+import * as outline from './outline.js'
 
 /**
  * List of files and their corresponding tab elements.
@@ -51,9 +52,10 @@ function add (filepath, postTabOpenAction) {
     // use the object later.
     jQuery('#tab-selector-wrapper').append(tabLink)
 
-    /* Tab content. */
-    const tabContent = `<li id="body-of-${filepath}" class="tab-content" data-filepath="${filepath}"><div class="file-content">${data}</div></li>`
+    /* Tab content.  The outline arrives separately. */
+    const tabContent = `<li id="body-of-${filepath}" class="tab-content" data-filepath="${filepath}"><aside class="outline"></aside><div class="file-content">${data}</div></li>`
     jQuery('#tab-content-wrapper').append(tabContent)
+    outline.load(filepath)
 
     /* Record the presence of a tab for this file. */
     addFileMapping(filepath, tabLink)
@@ -14,39 +14,47 @@ import * as filelist from './file-list.js'
 import * as breakpoint from './breakpoints.js'
 import * as breaks from './breaks.js'
 import * as control from './controls.js'
+import * as definitions from './definitions.js'
+import * as divergence from './divergence.js'
 import * as feedback from './feedback.js'
+import * as outline from './outline.js'
+import * as runTo from './run-to.js'
+import * as search from './search.js'
+import * as server from './server-commands.js'
 import * as source from './source.js'
 import * as stacktrace from './stacktrace.js'
 import * as tab from './tabs.js'
 import * as variable from './variables.js'
+import * as websocket from './websocket.js'
 
 /**
  * Onload event handler.
  *
  * Does the following:
- *   - Sets up click handlers on file links in the file browser.
+ *   - Lists the codebase in the file browser.
  *   - Sets up click handlers on tab close links.
  *   - Adds buttons for Run and Step commands.
  *   - Sets up new breakpoint trigger.
- *   - Creates a Server-sent-event handler to listen to the data stream from the
- *     Footle server.
+ *   - Sets up the context menu for source code lines.
+ *   - Sets up switching to the DBGp engine's copy of differing files.
+ *   - Connects to the Footle server over a WebSocket or, failing that, creates
+ *     a Server-sent-event handler to listen to the data stream from the Footle
+ *     server.
  */
 jQuery(function () {
-  /* We may have missed the very first "load" event for the iframe.  So we
-     need to explicitely call setupFileList() for setting up the click
-     handlers on the file links inside the file browser. */
   filelist.setup()
-
-  // iframe is loaded again when a directory is opened.
-  jQuery('iframe').on('load', filelist.setup)
-
   filelist.setupRecent()
+  search.setup()
+  definitions.setup()
+  divergence.setup()
+  outline.setup()
   tab.setupRefresher()
   tab.setupCloser()
   tab.setupScrollRestoration()
   control.setupContinuationControls()
   control.setupStateControl()
   breakpoint.setupTrigger()
+  runTo.setupContextMenu()
   variable.setupInteraction()
   control.disable()
   feedback.init()
@@ -56,12 +64,31 @@ jQuery(function () {
 
 /**
  * Process responses from the Footle server.
+ *
+ * Prefers a WebSocket.  Falls back to Server-sent events.
  */
 function initServerMessageProcessing () {
+  websocket.open(processMsg, applyInitialState, reportServerGone, initSSEProcessing)
+}
+
+/**
+ * Process responses from the Footle server sent as Server-sent events.
+ *
+ * On reconnection, the browser tells the server the ID of the last event it
+ * has seen and the server replays whatever we have missed.  When we have missed
+ * too much, the server asks us to "resync" from the current state instead.
+ */
+function initSSEProcessing () {
   const sse = new EventSource('/message-stream')
   let hasAttemptedReconnection = false
 
-  jQuery(sse).on('message', function (event) {
+  jQuery(sse).on('open', function () {
+    hasAttemptedReconnection = false
+  })
+
+  jQuery(sse).on('resync', applyInitialState)
+
+  jQuery(sse).on('dbgp', function (event) {
     try {
       var msg = JSON.parse(event.originalEvent.data)
     } catch (e) {
@@ -82,36 +109,54 @@ function initServerMessageProcessing () {
     console.log(event)
 
     if (event.target.readyState === EventSource.CLOSED) {
-      feedback.show('Footle server has gone away. Try reloading this page.')
-
-      updateExecutionState('asleep')
+      reportServerGone()
       hasAttemptedReconnection = false
     } else if (event.target.readyState === EventSource.CONNECTING && hasAttemptedReconnection) {
       sse.close()
-      feedback.show('Footle server has gone away. Try reloading this page.')
-
+      reportServerGone()
       hasAttemptedReconnection = false
-      updateExecutionState('asleep')
     } else if (event.target.readyState === EventSource.CONNECTING && !hasAttemptedReconnection) {
       hasAttemptedReconnection = true
     }
   })
 }
 
+/**
+ * Tell the user that the Footle server is unreachable.
+ */
+function reportServerGone () {
+  feedback.show('Footle server has gone away. Try reloading this page.')
+
+  updateExecutionState('asleep')
+}
+
 /**
  * Update UI based on debugger response.
  *
  * @param object msg
  */
 function processMsg (msg) {
+  if (msg.Properties && msg.Properties.ErrorCode && server.isOwnResponse(msg)) {
+    feedback.show(`The "${msg.Properties.Command}" command failed: ${msg.Properties.ErrorMessage}`)
+  }
+
+  if (msg.Properties && msg.Properties.Notice && server.isOwnResponse(msg)) {
+    feedback.show(msg.Properties.Notice)
+  }
+
   if (msg.MessageType === 'response' && msg.State === 'break' && msg.Properties.Filename) {
     breaks.update(msg.Properties.Filename, msg.Properties.LineNumber)
     control.enable()
+  } else if (msg.MessageType === 'response' && msg.State === 'running') {
+    control.enablePauseOnly()
   } else if (msg.MessageType === 'response' && msg.Properties.Command === 'breakpoint_list') {
     breakpoint.refresh(msg.Breakpoints)
-  } else if (msg.MessageType === 'response' && msg.State === 'stopped') {
+  } else if (msg.MessageType === 'response' && (msg.State === 'stopped' || msg.State === 'detached')) {
     breaks.removePrevious()
+    divergence.forget()
     control.disable()
+  } else if (msg.MessageType === 'response' && msg.Properties.Command === 'diverged') {
+    divergence.warn(msg.Properties.Filename, msg.Properties.Notice)
   } else if (msg.MessageType === 'response' && msg.Properties.Command === 'context_get') {
     variable.updateDisplay(msg.Context)
   } else if (msg.MessageType === 'response' && msg.Properties.Command === 'property_get') {
@@ -0,0 +1,126 @@
+/**
+ * @file
+ * WebSocket connection with the Footle server.
+ *
+ * Carries both commands for the server and messages from the server.  When
+ * WebSockets are unavailable, say due to a proxy, the caller falls back to
+ * Server-sent events and HTTP POST.
+ */
+
+import * as feedback from './feedback.js'
+
+let socket = null
+let lastFrameId = 0
+
+/**
+ * Commands awaiting an answer, keyed by frame ID.
+ */
+const pendingCommands = new Map()
+
+/**
+ * Connect to the Footle server.
+ *
+ * @param function onMessage
+ *   Receives each message broadcast by the server.
+ * @param function onResync
+ *   Called when messages have been missed.
+ * @param function onClose
+ *   Called when an established connection is lost.
+ * @param function onFailure
+ *   Called when a connection cannot be established in the first place.
+ */
+function open (onMessage, onResync, onClose, onFailure) {
+  if (typeof WebSocket === 'undefined') {
+    onFailure()
+    return
+  }
+
+  const protocol = (window.location.protocol === 'https:') ? 'wss:' : 'ws:'
+  const ws = new WebSocket(`${protocol}//${window.location.host}/websocket`)
+  let hasOpened = false
+
+  ws.onopen = function () {
+    hasOpened = true
+    socket = ws
+  }
+
+  ws.onmessage = function (event) {
+    processFrame(event.data, onMessage, onResync)
+  }
+
+  ws.onclose = function (event) {
+    console.log(event)
+
+    socket = null
+    pendingCommands.clear()
+
+    if (hasOpened) {
+      onClose()
+    } else {
+      onFailure()
+    }
+  }
+}
+
+/**
+ * Is the WebSocket ready for commands?
+ *
+ * @return bool
+ */
+function isOpen () {
+  return socket !== null && socket.readyState === WebSocket.OPEN
+}
+
+/**
+ * Send a command over the WebSocket.
+ *
+ * @param string command
+ *   Example: breakpoint_set index.php 16
+ */
+function send (command) {
+  lastFrameId++
+  const frameId = String(lastFrameId)
+
+  pendingCommands.set(frameId, command)
+  socket.send(JSON.stringify({ Type: 'command', Id: frameId, Cmd: command }))
+}
+
+/**
+ * Act on a frame from the server.
+ *
+ * @param string data
+ *   JSON encoded frame.
+ * @param function onMessage
+ * @param function onResync
+ */
+function processFrame (data, onMessage, onResync) {
+  try {
+    var frame = JSON.parse(data)
+  } catch (e) {
+    feedback.show('Trouble parsing JSON formatted response from Footle server.  More in console log.')
+    console.log(e)
+    console.log('Response was: ' + data)
+
+    return
+  }
+
+  const command = pendingCommands.get(frame.Id)
+
+  if (frame.Type === 'message') {
+    console.log(frame.Message)
+    onMessage(frame.Message)
+  } else if (frame.Type === 'resync') {
+    onResync()
+  } else if (frame.Type === 'error') {
+    pendingCommands.delete(frame.Id)
+    feedback.show(`The "${command}" command failed: ${frame.Error}`)
+  } else if (frame.Type === 'response') {
+    pendingCommands.delete(frame.Id)
+
+    if (frame.Message.Properties.ErrorCode) {
+      feedback.show(`The "${command}" command failed: ${frame.Message.Properties.ErrorMessage}`)
+    }
+  }
+}
+
+export { isOpen, open, send }